@@ -0,0 +1,101 @@
+package glam
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// RenderPlan describes what rendering a component involves, as reported by
+// Engine.Analyze.
+type RenderPlan struct {
+	// Components are the names of the components that may be rendered,
+	// including the analyzed component, sorted by name
+	Components []string
+	// Funcs are the names of the template funcs that may be called, sorted
+	// by name. Funcs built into text/template, like printf, are excluded.
+	Funcs []string
+	// Fields are the fields, methods, and variable chains accessed by each
+	// component's template, keyed by component name, e.g. `.User.Name`.
+	// Fields accessed inside of `range` and `with` are relative to their
+	// dot.
+	Fields map[string][]string
+	// Dynamic are the actions that render components chosen at render time,
+	// which can't be analyzed
+	Dynamic []DynamicRender
+}
+
+// DynamicRender is an action that renders a component that's only known at
+// render time, like `{{render .}}`.
+type DynamicRender struct {
+	// Component is the name of the component whose template contains the
+	// action
+	Component string
+	// Action is the command that renders the component, e.g. `render .`
+	Action string
+}
+
+// Analyze reports the components, template funcs, and fields involved in
+// rendering the given component, without rendering it. It walks the compiled
+// templates of the component and every component it renders, so it only
+// includes components referenced by tags. Components rendered using values
+// from data, like `{{render .}}`, are reported as dynamic renders instead.
+//
+// Templates of components registered using RegisterComponentLazy are loaded
+// if they haven't been rendered yet.
+func (e *Engine) Analyze(component any) (*RenderPlan, error) {
+	_, componentType, err := resolveComponent(component)
+	if err != nil {
+		return nil, err
+	}
+
+	name := componentType.Name()
+	if _, ok, err := e.componentTemplate(name); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("No component found for type %s", describeType(componentType))
+	}
+
+	plan := &RenderPlan{Fields: make(map[string][]string)}
+	visited := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		plan.Components = append(plan.Components, name)
+
+		// Function components don't have a template
+		t, ok, err := e.componentTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		analysis := t.Analyze()
+		plan.Funcs = append(plan.Funcs, analysis.Funcs...)
+		if len(analysis.Fields) > 0 {
+			plan.Fields[name] = analysis.Fields
+		}
+		for _, action := range analysis.Dynamic {
+			plan.Dynamic = append(plan.Dynamic, DynamicRender{Component: name, Action: action})
+		}
+
+		for _, component := range analysis.Components {
+			if !visited[component] {
+				visited[component] = true
+				queue = append(queue, component)
+			}
+		}
+	}
+
+	sort.Strings(plan.Components)
+	slices.Sort(plan.Funcs)
+	plan.Funcs = slices.Compact(plan.Funcs)
+	sort.SliceStable(plan.Dynamic, func(i, j int) bool {
+		return plan.Dynamic[i].Component < plan.Dynamic[j].Component
+	})
+
+	return plan, nil
+}
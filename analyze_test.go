@@ -0,0 +1,123 @@
+package glam
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type AnalyzePage struct {
+	User  AnalyzeUser
+	Posts []AnalyzePost
+	Tabs  []any
+}
+
+type AnalyzeUser struct {
+	Name string
+}
+
+type AnalyzePost struct {
+	Title string
+}
+
+type AnalyzeCard struct {
+	Title    string
+	Children template.HTML
+}
+
+type AnalyzeAvatar struct {
+	Name string
+}
+
+func TestAnalyze(t *testing.T) {
+	called := false
+	engine := New(WithFuncs(FuncMap{
+		"upper": func(s string) string {
+			called = true
+			return strings.ToUpper(s)
+		},
+	}))
+	err := engine.RegisterComponent(&AnalyzeAvatar{}, `<img alt="{{.Name}}">`)
+	require.NoError(t, err)
+	err = engine.RegisterFunc("Badge", func(attrs map[string]any, children template.HTML) (template.HTML, error) {
+		return template.HTML(fmt.Sprint(attrs["label"])), nil
+	})
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&AnalyzeCard{}, `<section><h2>{{upper .Title}}</h2>{{.Children}}</section>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&AnalyzePage{}, `<AnalyzeCard title="{{.User.Name}}">`+
+		`<AnalyzeAvatar key="{{.User.Name}}" name="{{.User.Name}}" />`+
+		`{{range .Posts}}<Badge label="{{printf "%s!" .Title}}" />{{end}}`+
+		`</AnalyzeCard>`+
+		`<ul>{{range .Tabs}}{{render .}}{{end}}</ul>`)
+	require.NoError(t, err)
+
+	plan, err := engine.Analyze(&AnalyzePage{})
+	require.NoError(t, err)
+	require.False(t, called)
+
+	require.Equal(t, []string{"AnalyzeAvatar", "AnalyzeCard", "AnalyzePage", "Badge"}, plan.Components)
+	require.Equal(t, []string{"render", "upper"}, plan.Funcs)
+	require.Equal(t, map[string][]string{
+		"AnalyzeAvatar": {".Name"},
+		"AnalyzeCard":   {".Children", ".Title"},
+		"AnalyzePage":   {".Posts", ".Tabs", ".Title", ".User.Name"},
+	}, plan.Fields)
+	require.Equal(t, []DynamicRender{{Component: "AnalyzePage", Action: "render ."}}, plan.Dynamic)
+
+	// Components are only analyzed once, even when they render each other
+	plan, err = engine.Analyze(AnalyzeCard{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"AnalyzeCard"}, plan.Components)
+	require.Empty(t, plan.Dynamic)
+}
+
+func TestAnalyze_Partials(t *testing.T) {
+	engine := New()
+	err := engine.RegisterPartials(`{{define "greeting"}}<AnalyzeAvatar name="{{.Name}}" />{{end}}{{define "unused"}}{{call .Render}}{{end}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&AnalyzeAvatar{}, `<img alt="{{.Name}}">`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&AnalyzeUser{}, `{{template "greeting" .}}`)
+	require.NoError(t, err)
+
+	plan, err := engine.Analyze(&AnalyzeUser{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"AnalyzeAvatar", "AnalyzeUser"}, plan.Components)
+	require.Empty(t, plan.Dynamic)
+}
+
+func TestAnalyze_Lazy(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponentLazy(&AnalyzeAvatar{}, func() (string, error) {
+		return `<img alt="{{.Name}}">`, nil
+	})
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&AnalyzeUser{}, `<AnalyzeAvatar name="{{.Name}}" />`)
+	require.NoError(t, err)
+
+	plan, err := engine.Analyze(&AnalyzeUser{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"AnalyzeAvatar", "AnalyzeUser"}, plan.Components)
+	require.Equal(t, []string{".Name"}, plan.Fields["AnalyzeAvatar"])
+
+	err = engine.RegisterComponentLazy(&AnalyzePost{}, func() (string, error) {
+		return "", fmt.Errorf("not found")
+	})
+	require.NoError(t, err)
+	_, err = engine.Analyze(&AnalyzePost{})
+	require.EqualError(t, err, "could not load template of component AnalyzePost: not found")
+}
+
+func TestAnalyze_Errors(t *testing.T) {
+	engine := New()
+
+	_, err := engine.Analyze(nil)
+	require.EqualError(t, err, "cannot render a nil component")
+
+	_, err = engine.Analyze(&AnalyzePost{})
+	require.ErrorContains(t, err, "No component found for type AnalyzePost")
+}
@@ -0,0 +1,104 @@
+package glam
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"unicode"
+)
+
+// templateExtension is the extension of the template files AutoRegister
+// looks for
+const templateExtension = ".glam.html"
+
+// AutoRegister registers each of the given components with the template file
+// named after it by convention, found anywhere in fsys. The file name is the
+// component's name in snake case followed by ".glam.html", e.g. UserCard is
+// registered with the first file named user_card.glam.html, like
+// users/user_card.glam.html. It returns an error if a component has no
+// template or if more than one file has the same name.
+func (e *Engine) AutoRegister(fsys fs.FS, components ...any) error {
+	plan, err := e.PlanAutoRegister(fsys, components...)
+	if err != nil {
+		return err
+	}
+
+	for _, component := range components {
+		name, _ := componentName(component)
+		filename := plan[name]
+
+		c, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return fmt.Errorf("could not read file: %w", err)
+		}
+
+		err = e.registerComponent(component, string(c), filename)
+		if err != nil {
+			return fmt.Errorf("could not register %s: %w", filename, err)
+		}
+	}
+
+	return nil
+}
+
+// PlanAutoRegister returns the template files AutoRegister would register the
+// given components with, keyed by component name, without registering them.
+// This allows the mapping to be verified, e.g. in tests.
+func (e *Engine) PlanAutoRegister(fsys fs.FS, components ...any) (map[string]string, error) {
+	templates := make(map[string][]string)
+	err := fs.WalkDir(fsys, ".", func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && strings.HasSuffix(d.Name(), templateExtension) {
+			templates[d.Name()] = append(templates[d.Name()], filename)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not find templates: %w", err)
+	}
+
+	plan := make(map[string]string, len(components))
+	for _, component := range components {
+		name, err := componentName(component)
+		if err != nil {
+			return nil, err
+		}
+
+		base := snakeCase(name) + templateExtension
+		switch candidates := templates[base]; len(candidates) {
+		case 0:
+			return nil, fmt.Errorf("no template found for component %s, expected a file named %s", name, base)
+		case 1:
+			plan[name] = candidates[0]
+		default:
+			return nil, fmt.Errorf("component %s matches more than one template: %s", name, strings.Join(candidates, ", "))
+		}
+	}
+
+	return plan, nil
+}
+
+// snakeCase converts a component name to snake case, keeping acronyms
+// together, e.g. UserCard to user_card and HTMLEditor to html_editor.
+func snakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				b.WriteRune('_')
+			}
+		}
+
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package glam
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type AutoUserCard struct {
+	Name string
+}
+
+type AutoHTMLBadge struct{}
+
+type AutoPage struct {
+	Name string
+}
+
+func TestAutoRegister(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/auto_page.glam.html":         {Data: []byte(`<main><AutoUserCard name="{{.Name}}" /><AutoHTMLBadge /></main>`)},
+		"users/auto_user_card.glam.html":    {Data: []byte(`<p>{{.Name}}</p>`)},
+		"auto_html_badge.glam.html":         {Data: []byte(`<b>badge</b>`)},
+		"users/auto_user_card.html":         {Data: []byte(`ignored`)},
+		"partials/unrelated_file.glam.html": {Data: []byte(`ignored`)},
+	}
+
+	engine := New()
+	plan, err := engine.PlanAutoRegister(fsys, &AutoPage{}, &AutoUserCard{}, AutoHTMLBadge{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"AutoPage":      "pages/auto_page.glam.html",
+		"AutoUserCard":  "users/auto_user_card.glam.html",
+		"AutoHTMLBadge": "auto_html_badge.glam.html",
+	}, plan)
+	require.Empty(t, engine.KnownComponents())
+
+	err = engine.AutoRegister(fsys, &AutoPage{}, &AutoUserCard{}, AutoHTMLBadge{})
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &AutoPage{Name: "Fox"})
+	require.NoError(t, err)
+	require.Equal(t, `<main><p>Fox</p><b>badge</b></main>`, b.String())
+}
+
+func TestAutoRegister_Errors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/auto_user_card.glam.html": {Data: []byte(`<p></p>`)},
+		"b/auto_user_card.glam.html": {Data: []byte(`<p></p>`)},
+	}
+
+	engine := New()
+	err := engine.AutoRegister(fsys, &AutoUserCard{})
+	require.EqualError(t, err, "component AutoUserCard matches more than one template: a/auto_user_card.glam.html, b/auto_user_card.glam.html")
+
+	err = engine.AutoRegister(fsys, &AutoPage{})
+	require.EqualError(t, err, "no template found for component AutoPage, expected a file named auto_page.glam.html")
+	require.Empty(t, engine.KnownComponents())
+}
+
+func TestSnakeCase(t *testing.T) {
+	testCases := map[string]string{
+		"UserCard":    "user_card",
+		"HTMLEditor":  "html_editor",
+		"Card2Column": "card2_column",
+		"OAuthButton": "o_auth_button",
+		"Button":      "button",
+		"UserID":      "user_id",
+	}
+
+	for name, expected := range testCases {
+		require.Equal(t, expected, snakeCase(name), name)
+	}
+}
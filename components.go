@@ -0,0 +1,154 @@
+package glam
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/blakewilliams/glam/internal/template"
+)
+
+// ComponentInfo describes a registered component and the attributes it
+// accepts, e.g. to build a style guide or editor hovers.
+type ComponentInfo struct {
+	// Name is the name of the component
+	Name string `json:"name"`
+	// Description describes the component. It's set using
+	// RegisterComponentWithInfo.
+	Description string `json:"description,omitempty"`
+	// Deprecated is the deprecation message of components registered using
+	// RegisterComponentDeprecated
+	Deprecated string `json:"deprecated,omitempty"`
+	// Attributes are the attributes the component accepts, in the order its
+	// fields are declared. Function components that accept a
+	// map[string]any have none.
+	Attributes []AttributeInfo `json:"attributes"`
+}
+
+// AttributeInfo describes an attribute accepted by a component.
+type AttributeInfo struct {
+	// Name is the name of the attribute, e.g. "label"
+	Name string `json:"name"`
+	// Field is the name of the field the attribute populates
+	Field string `json:"field"`
+	// Type is the type of the field, e.g. "string"
+	Type string `json:"type"`
+	// Description describes the attribute, taken from the field's `doc`
+	// tag or the ComponentInfo passed to RegisterComponentWithInfo
+	Description string `json:"description,omitempty"`
+}
+
+// RegisterComponentWithInfo registers a component like RegisterComponent,
+// attaching the description of the component and its attributes from info,
+// which are returned by Components. Attributes are matched by name, ignoring
+// case, and their descriptions replace the descriptions from `doc` tags. The
+// name of the component and the types of its attributes are always taken from
+// value.
+func (e *Engine) RegisterComponentWithInfo(value any, templateString string, info ComponentInfo) error {
+	name, err := componentName(value)
+	if err != nil {
+		return err
+	}
+
+	attributes := componentAttributes(reflect.TypeOf(value))
+	for _, attribute := range info.Attributes {
+		if !hasAttribute(attributes, attribute.Name) {
+			return fmt.Errorf("component %s has no attribute %s to describe", name, attribute.Name)
+		}
+	}
+
+	err = e.registerComponent(value, templateString, "")
+	if err != nil {
+		return err
+	}
+	e.infos[name] = info
+
+	return nil
+}
+
+// Components returns the registered components and the attributes they
+// accept, sorted by name.
+func (e *Engine) Components() []ComponentInfo {
+	names := slices.Clone(e.componentNames)
+	slices.Sort(names)
+
+	components := make([]ComponentInfo, 0, len(names))
+	for _, name := range names {
+		componentType := e.components[name]
+		if componentType == template.FuncComponentType {
+			componentType = e.funcComponents[name].props
+		}
+
+		info := e.infos[name]
+		attributes := componentAttributes(componentType)
+		for attribute, field := range e.mappings[name] {
+			for i := range attributes {
+				if attributes[i].Field == field {
+					attributes[i].Name = attribute
+				}
+			}
+		}
+		for i, attribute := range attributes {
+			for _, described := range info.Attributes {
+				if strings.EqualFold(described.Name, attribute.Name) && described.Description != "" {
+					attributes[i].Description = described.Description
+				}
+			}
+		}
+
+		components = append(components, ComponentInfo{
+			Name:        name,
+			Description: info.Description,
+			Deprecated:  e.deprecations[name],
+			Attributes:  attributes,
+		})
+	}
+
+	return components
+}
+
+// Manifest returns the registered components returned by Components as JSON.
+func (e *Engine) Manifest() ([]byte, error) {
+	return json.MarshalIndent(e.Components(), "", "  ")
+}
+
+// componentAttributes returns the attributes accepted by the given struct
+// type, which has none if it isn't a struct. Children and fields forwarding
+// unmatched attributes aren't attributes.
+func componentAttributes(componentType reflect.Type) []AttributeInfo {
+	for componentType.Kind() == reflect.Ptr {
+		componentType = componentType.Elem()
+	}
+	if componentType.Kind() != reflect.Struct {
+		return []AttributeInfo{}
+	}
+
+	attributes := make([]AttributeInfo, 0, componentType.NumField())
+	for i := 0; i < componentType.NumField(); i++ {
+		field := componentType.Field(i)
+		if !field.IsExported() || field.Name == "Children" || field.Tag.Get("glam") == "attrs" {
+			continue
+		}
+
+		attributes = append(attributes, AttributeInfo{
+			Name:        template.AttributeName(field),
+			Field:       field.Name,
+			Type:        field.Type.String(),
+			Description: field.Tag.Get("doc"),
+		})
+	}
+
+	return attributes
+}
+
+func hasAttribute(attributes []AttributeInfo, name string) bool {
+	for _, attribute := range attributes {
+		if strings.EqualFold(attribute.Name, name) {
+			return true
+		}
+	}
+
+	return false
+}
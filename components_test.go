@@ -0,0 +1,95 @@
+package glam
+
+import (
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type DocumentedButton struct {
+	Label    string `doc:"The text of the button"`
+	Variant  string `attr:"data-variant" doc:"One of primary or secondary"`
+	Disabled bool
+	Attrs    template.HTMLAttr `glam:"attrs"`
+	Children template.HTML
+	internal string
+}
+
+type DocumentedIconProps struct {
+	Name string `doc:"The name of the icon"`
+}
+
+func TestComponents(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponentWithInfo(&DocumentedButton{}, `<button>{{.Label}}</button>`, ComponentInfo{
+		Description: "A clickable button",
+		Attributes: []AttributeInfo{
+			{Name: "disabled", Description: "Disables the button"},
+			{Name: "Label", Description: "The button's text"},
+		},
+	})
+	require.NoError(t, err)
+	err = engine.RegisterFunc("DocumentedIcon", func(props DocumentedIconProps, _ template.HTML) (template.HTML, error) {
+		return "", nil
+	})
+	require.NoError(t, err)
+	err = engine.RegisterFunc("DocumentedBadge", func(attrs map[string]any, _ template.HTML) (template.HTML, error) {
+		return "", nil
+	})
+	require.NoError(t, err)
+	err = engine.RegisterComponentDeprecated(&CellComponent{}, `{{.Value}}`, "Use DocumentedButton instead")
+	require.NoError(t, err)
+
+	require.Equal(t, []ComponentInfo{
+		{
+			Name:       "CellComponent",
+			Deprecated: "Use DocumentedButton instead",
+			Attributes: []AttributeInfo{{Name: "value", Field: "Value", Type: "string"}},
+		},
+		{Name: "DocumentedBadge", Attributes: []AttributeInfo{}},
+		{
+			Name:        "DocumentedButton",
+			Description: "A clickable button",
+			Attributes: []AttributeInfo{
+				{Name: "label", Field: "Label", Type: "string", Description: "The button's text"},
+				{Name: "data-variant", Field: "Variant", Type: "string", Description: "One of primary or secondary"},
+				{Name: "disabled", Field: "Disabled", Type: "bool", Description: "Disables the button"},
+			},
+		},
+		{
+			Name:       "DocumentedIcon",
+			Attributes: []AttributeInfo{{Name: "name", Field: "Name", Type: "string", Description: "The name of the icon"}},
+		},
+	}, engine.Components())
+
+	// Registering the component again removes the attached info
+	err = engine.RegisterComponent(&DocumentedButton{}, `<button>{{.Label}}</button>`)
+	require.NoError(t, err)
+	components := engine.Components()
+	require.Equal(t, "", components[2].Description)
+	require.Equal(t, "The text of the button", components[2].Attributes[0].Description)
+}
+
+func TestRegisterComponentWithInfo_UnknownAttribute(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponentWithInfo(&DocumentedButton{}, `<button></button>`, ComponentInfo{
+		Attributes: []AttributeInfo{{Name: "lable", Description: "Typo"}},
+	})
+	require.EqualError(t, err, "component DocumentedButton has no attribute lable to describe")
+	require.Empty(t, engine.Components())
+}
+
+func TestManifest(t *testing.T) {
+	engine := New()
+	err := engine.RegisterFunc("DocumentedIcon", func(props DocumentedIconProps, _ template.HTML) (template.HTML, error) {
+		return "", nil
+	})
+	require.NoError(t, err)
+
+	manifest, err := engine.Manifest()
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"name": "DocumentedIcon", "attributes": [
+		{"name": "name", "field": "Name", "type": "string", "description": "The name of the icon"}
+	]}]`, string(manifest))
+}
@@ -0,0 +1,92 @@
+// Package markdown provides a glam component that renders markdown using a
+// pluggable converter, so libraries like goldmark or blackfriday can be used
+// without glam depending on them.
+package markdown
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"html/template"
+
+	"github.com/blakewilliams/glam"
+)
+
+type (
+	// Options configures how markdown is converted to HTML.
+	Options struct {
+		// HardWraps renders newlines in paragraphs as line breaks.
+		HardWraps bool
+		// Safe requires the converter to sanitize the generated HTML. It is
+		// true unless the component sets Unsafe.
+		Safe bool
+	}
+
+	// Converter converts markdown source into HTML.
+	Converter interface {
+		Convert(source string, opts Options) (string, error)
+	}
+
+	// ConverterFunc adapts a function into a Converter.
+	ConverterFunc func(source string, opts Options) (string, error)
+
+	// MarkdownComponent renders its Source as HTML using the converter
+	// provided to Register for the engine rendering it, e.g.:
+	//
+	//	<MarkdownComponent source="{{.Body}}" hardwraps="{{true}}" />
+	MarkdownComponent struct {
+		Source    string
+		HardWraps bool `attr:"hardwraps"`
+		// Unsafe disables sanitization of the converted HTML. It must only be
+		// used with trusted markdown.
+		Unsafe bool
+
+		converter Converter
+	}
+)
+
+// Convert calls f(source, opts).
+func (f ConverterFunc) Convert(source string, opts Options) (string, error) {
+	return f(source, opts)
+}
+
+// Register registers MarkdownComponent with the given engine, rendering
+// markdown using the given converter. Each engine uses the converter it was
+// registered with, which is given to the components its templates render.
+func Register(e *glam.Engine, c Converter) error {
+	if c == nil {
+		return fmt.Errorf("markdown converter must not be nil")
+	}
+
+	return e.RegisterComponentFactory(&MarkdownComponent{}, func() any {
+		return &MarkdownComponent{converter: c}
+	}, `{{.HTML}}`)
+}
+
+// Options returns the options used to convert the component's source.
+func (m *MarkdownComponent) Options() Options {
+	return Options{HardWraps: m.HardWraps, Safe: !m.Unsafe}
+}
+
+// HTML converts the component's source into HTML. The result is returned as
+// template.HTML so it's rendered without being escaped, which is why
+// converters must sanitize their output when Options.Safe is true.
+func (m *MarkdownComponent) HTML() (template.HTML, error) {
+	if m.converter == nil {
+		return "", fmt.Errorf("no markdown converter, render MarkdownComponent from the template of an engine it's registered with using markdown.Register")
+	}
+
+	html, err := m.converter.Convert(m.Source, m.Options())
+	if err != nil {
+		return "", fmt.Errorf("could not convert markdown: %w", err)
+	}
+
+	return template.HTML(html), nil
+}
+
+// CacheKey returns a key derived from the component's source and options,
+// suitable for caching the rendered output.
+func (m *MarkdownComponent) CacheKey() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%t:%t:%s", m.HardWraps, m.Unsafe, m.Source)))
+
+	return fmt.Sprintf("markdown:%x", sum)
+}
@@ -0,0 +1,123 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/blakewilliams/glam"
+	"github.com/blakewilliams/glam/glamtest"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConverter converts `# ` headings and escapes HTML in safe mode
+var fakeConverter = ConverterFunc(func(source string, opts Options) (string, error) {
+	if opts.Safe {
+		source = strings.NewReplacer("<", "&lt;", ">", "&gt;").Replace(source)
+	}
+
+	if opts.HardWraps {
+		source = strings.ReplaceAll(source, "\n", "<br>")
+	}
+
+	if heading, ok := strings.CutPrefix(source, "# "); ok {
+		return fmt.Sprintf("<h1>%s</h1>", heading), nil
+	}
+
+	return fmt.Sprintf("<p>%s</p>", source), nil
+})
+
+type Post struct {
+	Body string
+}
+
+func TestMarkdownComponent(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		body     string
+		expected string
+	}{
+		{
+			desc:     "renders converted HTML without escaping it",
+			template: `<article><MarkdownComponent source="{{.Body}}" /></article>`,
+			body:     "# Hello",
+			expected: `<article><h1>Hello</h1></article>`,
+		},
+		{
+			desc:     "sanitizes by default",
+			template: `<MarkdownComponent source="{{.Body}}" />`,
+			body:     "<script>alert(1)</script>",
+			expected: `<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>`,
+		},
+		{
+			desc:     "passes options to the converter",
+			template: `<MarkdownComponent source="{{.Body}}" hardwraps="{{true}}" unsafe="{{true}}" />`,
+			body:     "<b>one</b>\ntwo",
+			expected: `<p><b>one</b><br>two</p>`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := glam.New(nil)
+			err := Register(engine, fakeConverter)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&Post{}, tC.template)
+			require.NoError(t, err)
+
+			require.Equal(t, tC.expected, glamtest.Render(t, engine, &Post{Body: tC.body}))
+		})
+	}
+}
+
+func TestMarkdownComponent_ConverterError(t *testing.T) {
+	engine := glam.New(nil)
+	err := Register(engine, ConverterFunc(func(string, Options) (string, error) {
+		return "", errors.New("boom")
+	}))
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&Post{}, `<MarkdownComponent source="{{.Body}}" />`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &Post{Body: "# Hello"})
+	require.ErrorContains(t, err, "could not convert markdown: boom")
+
+	// Components constructed outside of a template have no converter
+	err = engine.Render(&b, &MarkdownComponent{Source: "# Hello"})
+	require.ErrorContains(t, err, "no markdown converter")
+}
+
+func TestMarkdownComponent_ConverterPerEngine(t *testing.T) {
+	upper := ConverterFunc(func(source string, _ Options) (string, error) {
+		return strings.ToUpper(source), nil
+	})
+
+	engine := glam.New(nil)
+	err := Register(engine, fakeConverter)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&Post{}, `<MarkdownComponent source="{{.Body}}" />`)
+	require.NoError(t, err)
+
+	other := glam.New(nil)
+	err = Register(other, upper)
+	require.NoError(t, err)
+	err = other.RegisterComponent(&Post{}, `<MarkdownComponent source="{{.Body}}" />`)
+	require.NoError(t, err)
+
+	require.Equal(t, `<h1>Hello</h1>`, glamtest.Render(t, engine, &Post{Body: "# Hello"}))
+	require.Equal(t, `# HELLO`, glamtest.Render(t, other, &Post{Body: "# Hello"}))
+}
+
+func TestMarkdownComponent_CacheKey(t *testing.T) {
+	a := &MarkdownComponent{Source: "# Hello"}
+	b := &MarkdownComponent{Source: "# Hello"}
+	c := &MarkdownComponent{Source: "# Hello", HardWraps: true}
+	d := &MarkdownComponent{Source: "# Goodbye"}
+
+	require.Equal(t, a.CacheKey(), b.CacheKey())
+	require.NotEqual(t, a.CacheKey(), c.CacheKey())
+	require.NotEqual(t, a.CacheKey(), d.CacheKey())
+}
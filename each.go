@@ -0,0 +1,147 @@
+package glam
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/blakewilliams/glam/internal/template"
+)
+
+type (
+	// EachOptions configures how RenderEachWithOptions renders a collection
+	// of components.
+	EachOptions struct {
+		// Separator is written between each rendered component
+		Separator string
+		// SkipErrors continues rendering the remaining components when one
+		// fails to render, instead of returning the error immediately. The
+		// output of failed components is discarded, and their errors are
+		// returned together once every component has been rendered.
+		SkipErrors bool
+	}
+
+	// EachError is returned when a component fails to render using
+	// RenderEach. It wraps the underlying error, which can be retrieved using
+	// errors.As.
+	EachError struct {
+		// Index is the position of the component in the rendered collection
+		Index int
+		// Type is the type of the component
+		Type reflect.Type
+		Err  error
+	}
+)
+
+func (e *EachError) Error() string {
+	return fmt.Sprintf("error rendering item %d (%s): %s", e.Index, e.Type, e.Err)
+}
+
+func (e *EachError) Unwrap() error {
+	return e.Err
+}
+
+// eachBuffers are reused across calls to RenderEach, so rendering collections
+// doesn't allocate a buffer per component
+var eachBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// RenderEach renders each component in items, which must be a slice or array
+// of registered components, to the provided writer. Components don't need to
+// be the same type, e.g. a []any containing several types of feed items.
+//
+// It's equivalent to calling Render for each component, but templates are
+// looked up once per type instead of once per component.
+func (e *Engine) RenderEach(w io.Writer, items any) error {
+	return e.RenderEachWithOptions(w, items, EachOptions{})
+}
+
+// RenderEachWithOptions renders each component in items like RenderEach
+// using the given options. See EachOptions for the available options.
+func (e *Engine) RenderEachWithOptions(w io.Writer, items any, opts EachOptions) error {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return fmt.Errorf("RenderEach expects a slice of components, got %T", items)
+	}
+
+	b := eachBuffers.Get().(*bytes.Buffer)
+	defer eachBuffers.Put(b)
+
+	templates := make(map[reflect.Type]*template.Template)
+	errs := make([]error, 0)
+	rendered := 0
+	for i := 0; i < v.Len(); i++ {
+		b.Reset()
+
+		item := v.Index(i)
+		err := e.renderEachItem(b, item.Interface(), templates)
+		if err != nil {
+			// Report the concrete type of items in slices like []any
+			itemType := item.Type()
+			if item.Kind() == reflect.Interface && !item.IsNil() {
+				itemType = item.Elem().Type()
+			}
+
+			err = &EachError{Index: i, Type: itemType, Err: err}
+			if !opts.SkipErrors {
+				return err
+			}
+
+			errs = append(errs, err)
+			continue
+		}
+
+		if rendered > 0 && opts.Separator != "" {
+			_, err = io.WriteString(w, opts.Separator)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = b.WriteTo(w)
+		if err != nil {
+			return err
+		}
+		rendered++
+	}
+
+	return errors.Join(errs...)
+}
+
+// renderEachItem renders a single component of RenderEach, caching the
+// template for each type in templates.
+func (e *Engine) renderEachItem(w io.Writer, renderable any, templates map[reflect.Type]*template.Template) error {
+	component, componentType, err := resolveComponent(renderable)
+	if err != nil {
+		return err
+	}
+
+	if funcComponent, ok := component.(*template.FuncComponent); ok {
+		return e.renderFuncComponent(w, funcComponent)
+	}
+
+	t, ok := templates[componentType]
+	if !ok {
+		t, ok, err = e.componentTemplate(componentType.Name())
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("No component found for type %s", describeType(componentType))
+		}
+
+		templates[componentType] = t
+	}
+
+	err = e.executeTemplate(w, t, component, nil)
+	if err != nil {
+		return fmt.Errorf("error rendering component: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package glam
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type FeedPost struct {
+	Title string
+}
+
+type FeedPhoto struct {
+	URL string
+}
+
+type FeedBroken struct{}
+
+func (FeedBroken) Caption() (string, error) {
+	return "", errors.New("caption unavailable")
+}
+
+func newFeedEngine(t *testing.T) *Engine {
+	engine := New()
+	require.NoError(t, engine.RegisterComponent(&FeedPost{}, `<article>{{.Title}}</article>`))
+	require.NoError(t, engine.RegisterComponent(&FeedPhoto{}, `<img src="{{.URL}}">`))
+	require.NoError(t, engine.RegisterComponent(&FeedBroken{}, `<p>{{.Caption}}</p>`))
+
+	return engine
+}
+
+func TestRenderEach(t *testing.T) {
+	engine := newFeedEngine(t)
+
+	var b strings.Builder
+	err := engine.RenderEach(&b, []FeedPost{{Title: "One"}, {Title: "Two"}})
+	require.NoError(t, err)
+	require.Equal(t, "<article>One</article><article>Two</article>", b.String())
+
+	b.Reset()
+	err = engine.RenderEachWithOptions(&b, []any{
+		&FeedPost{Title: "<One>"},
+		FeedPhoto{URL: "/a.png"},
+		&FeedPost{Title: "Two"},
+	}, EachOptions{Separator: "\n"})
+	require.NoError(t, err)
+	require.Equal(t, "<article>&lt;One&gt;</article>\n<img src=\"/a.png\">\n<article>Two</article>", b.String())
+
+	b.Reset()
+	err = engine.RenderEach(&b, []FeedPost{})
+	require.NoError(t, err)
+	require.Equal(t, "", b.String())
+}
+
+func TestRenderEach_Errors(t *testing.T) {
+	engine := newFeedEngine(t)
+	items := []any{&FeedPost{Title: "One"}, &FeedBroken{}, &FeedPost{Title: "Two"}, nil}
+
+	var b strings.Builder
+	err := engine.RenderEach(&b, items)
+	require.Error(t, err)
+	require.Equal(t, "<article>One</article>", b.String())
+
+	var eachErr *EachError
+	require.ErrorAs(t, err, &eachErr)
+	require.Equal(t, 1, eachErr.Index)
+	require.Equal(t, reflect.TypeOf(&FeedBroken{}), eachErr.Type)
+	require.ErrorContains(t, err, "error rendering item 1 (*glam.FeedBroken): error rendering component:")
+	require.ErrorContains(t, err, "caption unavailable")
+
+	b.Reset()
+	err = engine.RenderEachWithOptions(&b, items, EachOptions{Separator: ", ", SkipErrors: true})
+	require.Error(t, err)
+	require.Equal(t, "<article>One</article>, <article>Two</article>", b.String())
+	require.ErrorContains(t, err, "error rendering item 1 (*glam.FeedBroken)")
+	require.ErrorContains(t, err, "error rendering item 3 (interface {}): cannot render a nil component")
+
+	err = engine.RenderEach(&b, FeedPost{})
+	require.EqualError(t, err, "RenderEach expects a slice of components, got glam.FeedPost")
+}
@@ -1,24 +1,78 @@
 package glam
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	htmltemplate "html/template"
 	"io"
 	"io/fs"
+	"maps"
+	"net/http"
 	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 	"unicode"
 
 	"github.com/blakewilliams/glam/internal/template"
 )
 
+// DefaultTarget is the target that content outside of a `{{target}}` block is
+// written to when rendering with RenderMulti.
+const DefaultTarget = "html"
+
 type (
-	FuncMap = htmltemplate.FuncMap
+	// FuncMap is a map of functions available in templates. It implements
+	// Option so that New(funcs) continues to work, with a nil FuncMap adding
+	// no functions, but WithFuncs should be preferred. Funcs that accept a
+	// FuncMap also accept an html/template FuncMap.
+	FuncMap map[string]any
 
 	// Recoverable is an interface that components can implement that will
-	// rescue the component from panic's. It provides an io.Writer to write
-	// fallback content when the template is `recover`ed.
+	// rescue the component from panic's and render errors. It provides an
+	// io.Writer to write fallback content and the error (or value passed to
+	// panic) that caused the component to fail.
 	Recoverable = template.Recoverable
 
+	// Trusted is a marker interface for components whose templates should be
+	// executed using text/template instead of html/template. Output from
+	// trusted components isn't escaped, so any value they render, including
+	// attributes and child content, is written as-is. Only mark components as
+	// trusted when everything they render has already been sanitized.
+	Trusted = template.Trusted
+
+	// ComponentError is returned when a nested component fails to render. It
+	// wraps the underlying error, which can be retrieved using errors.As.
+	ComponentError = template.ComponentError
+
+	// AssignmentError is returned when the value of an attribute can't be
+	// assigned to the component field it populates, like a struct passed to
+	// an int field. It can be retrieved using errors.As.
+	AssignmentError = template.AssignmentError
+
+	// PanicError is returned when a component panics while rendering. It
+	// includes the name of the component (and the file its template was read
+	// from, if any) along with the value passed to panic.
+	PanicError = template.PanicError
+
+	// UnbalancedError is returned when WithBalancedOutputCheck is set and a
+	// component renders a start tag without its end tag, or an end tag
+	// without its start tag. It can be retrieved using errors.As.
+	UnbalancedError = template.UnbalancedError
+
+	// Policy restricts the funcs and components that the templates of
+	// components registered using RegisterUntrustedComponent can use.
+	// text/template's builtins, like `eq` and `printf`, are always allowed
+	// except for `call`.
+	Policy = template.Policy
+
+	// ParserLimits restricts the size of the templates that can be
+	// registered. Limits that are zero aren't enforced.
+	ParserLimits = template.ParserLimits
+
 	// Engine is a template engine that can be used to render components
 	Engine struct {
 		// components is a map of component names that are available in the template
@@ -26,50 +80,543 @@ type (
 		// to instantiate the component in the generated code
 		components  map[string]reflect.Type
 		templateMap map[string]*template.Template
-		funcs       htmltemplate.FuncMap
+		// componentNames are the names of the registered components in the
+		// order they were first registered, so behaviors that iterate over
+		// components don't depend on map ordering
+		componentNames []string
+		funcs          htmltemplate.FuncMap
 
 		// recompileMap tracks components that were parsed in component templates
 		// but not registered, so were compiled as raw HTML.
 		recompileMap map[string][]*template.Template
+
+		// templateOptions configures how component templates are parsed
+		templateOptions template.Options
+
+		// filenames tracks the file each component's template was read from,
+		// so errors can reference it
+		filenames map[string]string
+
+		// errorHandler writes the response when RenderResponse fails to
+		// render a component
+		errorHandler func(http.ResponseWriter, error)
+
+		// previewChildren is the child content used when previewing
+		// components
+		previewChildren htmltemplate.HTML
+
+		// funcComponents are the components registered using RegisterFunc
+		funcComponents map[string]funcComponent
+
+		// deprecations maps the names of deprecated components to the
+		// message explaining what to use instead
+		deprecations map[string]string
+
+		// deprecationHandler is called when a deprecated component is
+		// rendered from another component's template
+		deprecationHandler func(Deprecation)
+
+		// warnings are the warnings for each component's template, keyed by
+		// component name
+		warnings map[string][]Warning
+
+		// warningHandler is called when a warning is found in a component's
+		// template
+		warningHandler func(Warning)
+
+		// extensions are the components registered using
+		// RegisterComponentExtending, so they can be recompiled when the
+		// component they extend is registered again
+		extensions map[string]extension
+
+		// partialReferences are the capitalized tags in partials that may
+		// refer to components that weren't registered when the partials
+		// were, so the partials can be recompiled once they are
+		partialReferences map[string]bool
+
+		// metrics receives the duration of each render, if set
+		metrics MetricsSink
+
+		// recompiles counts the templates recompiled because a component
+		// they depend on was registered after them
+		recompiles int
+
+		// recompileLimit is the maximum number of recompiles, or 0 if
+		// there's no limit
+		recompileLimit int
+
+		// templateTypes are the types of data the templates registered
+		// using RegisterTemplate expect, keyed by template name
+		templateTypes map[string]reflect.Type
+
+		// factories construct the instances of the components registered
+		// using RegisterComponentFactory, keyed by component name
+		factories map[string]func() any
+
+		// componentFactory constructs the instances of components without a
+		// factory of their own, if set
+		componentFactory func(reflect.Type) any
+
+		// lazyTemplates are the templates of the components registered
+		// using RegisterComponentLazy, keyed by component name
+		lazyTemplates map[string]*lazyTemplate
+
+		// infos are the descriptions attached to the components registered
+		// using RegisterComponentWithInfo, keyed by component name
+		infos map[string]ComponentInfo
+
+		// policies restrict the templates of the components registered
+		// using RegisterUntrustedComponent, keyed by component name
+		policies map[string]Policy
+
+		// mappings map attributes to the fields they populate for the
+		// components registered using RegisterComponentWithMapping, keyed by
+		// component name
+		mappings map[string]map[string]string
+	}
+
+	// extension is a component whose template extends another component's
+	// template, replacing the templates defined by its `{{block}}` actions
+	extension struct {
+		// base is the name of the extended component
+		base   string
+		blocks map[string]string
+	}
+
+	// Deprecation describes a deprecated component being rendered from
+	// another component's template.
+	Deprecation struct {
+		// Component is the name of the deprecated component
+		Component string
+		// Message is the message the component was deprecated with, e.g.
+		// "use Button instead"
+		Message string
+		// ReferencedBy is the name of the component whose template rendered
+		// the deprecated component
+		ReferencedBy string
+	}
+
+	// funcComponent is a function registered as a component using
+	// RegisterFunc
+	funcComponent struct {
+		// props is the type of the function's first argument, which is either
+		// a struct, a pointer to a struct, or map[string]any
+		props reflect.Type
+		fn    reflect.Value
+	}
+
+	// Option configures an Engine when passed to New. Options are applied in
+	// the order they're passed, so later options override earlier ones.
+	Option interface {
+		apply(*Engine)
+	}
+
+	// optionFunc implements Option for functions that configure an Engine
+	optionFunc func(*Engine)
+
+	// RenderOptions configures a single render of a component.
+	RenderOptions struct {
+		// FlushEvery flushes the output every FlushEvery flush points when the
+		// writer implements http.Flusher. Flush points are placed between the
+		// root level nodes of the rendered component's template, so content
+		// like the layout head can be flushed before the components after
+		// it, and after each root level component is rendered, including each
+		// component rendered by a root level `{{range}}`. Nested components
+		// and child content are rendered into buffers, so they can't be
+		// flushed part way through.
+		FlushEvery int
 	}
 )
 
-// New creates a new template engine that can be used to register and render components
-// to be rendered.
-func New(funcs FuncMap) *Engine {
+func (f optionFunc) apply(e *Engine) {
+	f(e)
+}
+
+// apply adds the functions to the engine, allowing a FuncMap to be passed to
+// New directly.
+//
+// Deprecated: pass the FuncMap using WithFuncs instead.
+func (f FuncMap) apply(e *Engine) {
+	WithFuncs(f).apply(e)
+}
+
+// New creates a new template engine that can be used to register and render
+// components, configured using the provided options. nil options are ignored.
+//
+// By default, the engine:
+//   - registers the classes, css, propsJSON, tag, and endTag helpers along
+//     with the internal __glamDict function. They can be replaced using
+//     WithFuncs.
+//   - returns an error when a component shares its name with an HTML tag. See
+//     WithShadowedTags and WithHTMLTagConflicts.
+//   - discards template source that isn't needed for recompilation. See
+//     RetainSource.
+//   - writes a generic 500 response when RenderResponse fails. See
+//     WithErrorHandler.
+func New(opts ...Option) *Engine {
 	e := &Engine{
 		components:   make(map[string]reflect.Type),
 		templateMap:  make(map[string]*template.Template),
 		recompileMap: make(map[string][]*template.Template),
+		filenames:    make(map[string]string),
+		errorHandler: defaultErrorHandler,
+
+		funcComponents: make(map[string]funcComponent),
+		deprecations:   make(map[string]string),
+		extensions:     make(map[string]extension),
+		warnings:       make(map[string][]Warning),
+
+		partialReferences: make(map[string]bool),
+		templateTypes:     make(map[string]reflect.Type),
+		factories:         make(map[string]func() any),
+		lazyTemplates:     make(map[string]*lazyTemplate),
+		infos:             make(map[string]ComponentInfo),
+		policies:          make(map[string]Policy),
+		mappings:          make(map[string]map[string]string),
 	}
 
 	e.funcs = htmltemplate.FuncMap{
 		"__glamDict": Dict,
+		"classes":    Classes,
+		"css":        CSS,
+		"propsJSON":  PropsJSON,
+		"tag":        Tag,
+		"endTag":     EndTag,
 	}
 
-	for k, v := range funcs {
-		e.funcs[k] = v
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+
+		opt.apply(e)
 	}
 
 	return e
 }
 
+// WithFuncs adds the provided functions to the engine so they can be called
+// from templates. Functions with the same name as a built-in helper, or a
+// function added by an earlier option, replace it.
+func WithFuncs(funcs map[string]any) Option {
+	return optionFunc(func(e *Engine) {
+		for k, v := range funcs {
+			e.funcs[k] = v
+			delete(e.templateOptions.PureFuncs, k)
+		}
+	})
+}
+
+// WithPureFuncs adds the provided functions to the engine like WithFuncs, and
+// marks them as pure, meaning they always return the same result for the same
+// arguments. Component attributes that only call pure functions with literal
+// arguments, e.g. `label="{{ pluralize "item" 5 }}"`, are evaluated once when
+// the template is compiled instead of on every render.
+//
+// Functions added using WithFuncs are impure, so they're called on every
+// render. Functions that depend on the request or the current time, like a
+// CSRF token helper, must not be marked as pure since their compile time
+// result would be shared by every render, even when RenderWithFuncs provides
+// a replacement.
+func WithPureFuncs(funcs map[string]any) Option {
+	return optionFunc(func(e *Engine) {
+		if e.templateOptions.PureFuncs == nil {
+			e.templateOptions.PureFuncs = make(map[string]bool, len(funcs))
+		}
+
+		for k, v := range funcs {
+			e.funcs[k] = v
+			e.templateOptions.PureFuncs[k] = true
+		}
+	})
+}
+
+// WithRawFunc registers the `raw` template function, which marks a string as
+// trusted HTML so it's rendered without escaping, e.g. `{{ raw .Body }}`. It's
+// disabled by default since it bypasses html/template's escaping entirely and
+// must only be used with content that has already been sanitized.
+//
+// It also registers `safe`, an older name for `raw` that was previously
+// always available. Templates that call `safe` must enable this option.
+func WithRawFunc() Option {
+	return optionFunc(func(e *Engine) {
+		raw := func(s string) htmltemplate.HTML {
+			return htmltemplate.HTML(s)
+		}
+
+		e.funcs["raw"] = raw
+		e.funcs["safe"] = raw
+	})
+}
+
+// WithErrorHandler sets the function used to write the response when
+// RenderResponse fails to render a component. By default a generic 500
+// Internal Server Error response is written.
+func WithErrorHandler(handler func(w http.ResponseWriter, err error)) Option {
+	return optionFunc(func(e *Engine) {
+		e.errorHandler = handler
+	})
+}
+
+// WithTemplateOption sets options on every template parsed by the engine,
+// using html/template's Option method. For example, "missingkey=error" causes
+// renders to fail when a template references a missing map key.
+func WithTemplateOption(opts ...string) Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.TemplateOptions = append(e.templateOptions.TemplateOptions, opts...)
+	})
+}
+
+// WithShadowedTags allows components with the given names to be registered
+// even though they share their name with an HTML tag, e.g. a `Dialog`
+// component. The capitalized form, `<Dialog>`, renders the component while
+// the lowercase `<dialog>` is left as raw HTML.
+func WithShadowedTags(names ...string) Option {
+	return optionFunc(func(e *Engine) {
+		if e.templateOptions.ShadowedTags == nil {
+			e.templateOptions.ShadowedTags = make(map[string]bool, len(names))
+		}
+
+		for _, name := range names {
+			e.templateOptions.ShadowedTags[name] = true
+		}
+	})
+}
+
+// WithDeprecationHandler sets the function called each time a component
+// registered using RegisterComponentDeprecated is rendered from another
+// component's template, e.g. to log the deprecation so remaining usages can be
+// found. By default, deprecated components are rendered without reporting.
+func WithDeprecationHandler(handler func(Deprecation)) Option {
+	return optionFunc(func(e *Engine) {
+		e.deprecationHandler = handler
+	})
+}
+
+// WithWarningHandler sets the function called with each new warning found
+// when a component's template is registered, like a tag that's probably a
+// typo of a registered component's name, e.g. `<WraperComponent>`. Warnings
+// don't prevent templates from being registered, and can also be retrieved
+// using Warnings.
+func WithWarningHandler(handler func(Warning)) Option {
+	return optionFunc(func(e *Engine) {
+		e.warningHandler = handler
+	})
+}
+
+// WithPreviewChildren sets the child content used by Preview for components
+// with a Children field. By default, Children is left empty.
+func WithPreviewChildren(children htmltemplate.HTML) Option {
+	return optionFunc(func(e *Engine) {
+		e.previewChildren = children
+	})
+}
+
+// WithHTMLTagConflicts allows any component to share its name with an HTML
+// tag, like WithShadowedTags does for specific components.
+func WithHTMLTagConflicts() Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.AllowTagConflicts = true
+	})
+}
+
+// WithTextTemplates executes every template using text/template instead of
+// html/template, like Trusted components, for output that isn't HTML, like
+// plain-text emails. Components are composed the same way, but nothing is
+// escaped, so output rendered by the engine must never be served as HTML.
+func WithTextTemplates() Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.Trusted = true
+	})
+}
+
+// WithIncludeFS resolves `{{include "path"}}` actions in templates against
+// fsys, splicing the content of the included file into the template before
+// it's parsed, so snippets of HTML can be reused without making them
+// components. Included files can render components and include other files.
+// Includes are read again whenever the including template is registered or
+// recompiled.
+func WithIncludeFS(fsys fs.FS) Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.IncludeFS = fsys
+	})
+}
+
+// WithComponentFactory calls factory to construct every component rendered
+// from a template that wasn't registered using RegisterComponentFactory, e.g.
+// to resolve components from a dependency injection container. factory is
+// called with the registered type of the component, and must return a value
+// of that type, or nil to use its zero value.
+func WithComponentFactory(factory func(componentType reflect.Type) any) Option {
+	return optionFunc(func(e *Engine) {
+		e.componentFactory = factory
+	})
+}
+
+// WithComponentTagPrefix requires component tags to start with the given
+// prefix, e.g. "g:" for `<g:Button>` or "x-" for `<x-Button>`, instead of
+// treating every capitalized tag as a component. Capitalized tags without the
+// prefix are left as raw HTML, and since prefixed tags can't be mistaken for
+// HTML tags, components may share their name with an HTML tag. The prefix
+// should start with a letter.
+func WithComponentTagPrefix(prefix string) Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.ComponentTagPrefix = prefix
+	})
+}
+
+// WithStrictBindings validates the attributes passed to components when
+// templates are registered, returning an error if an attribute references a
+// field or method that doesn't exist on the registered component, like
+// `name="{{.Nme}}"`, or if a registered component has no field for an
+// attribute and doesn't forward attributes using `glam:"attrs"`. Bindings
+// where the type of dot isn't known, like inside of `{{range}}`, aren't
+// checked.
+func WithStrictBindings() Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.StrictBindings = true
+	})
+}
+
+// WithAttributeTransformer calls transform with the value of each attribute
+// passed to a component from a template before it's assigned, replacing the
+// value with the result, e.g. to trim whitespace or resolve design tokens.
+// field is the name of the struct field the attribute is assigned to, or the
+// attribute's name when it isn't assigned to a field, like forwarded
+// attributes and attributes of function components. Errors returned by
+// transform fail the render.
+func WithAttributeTransformer(transform func(component string, field string, value any) (any, error)) Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.AttributeTransformer = transform
+	})
+}
+
+// WithLenientAssignment skips attributes whose value can't be assigned to the
+// field they populate, like a struct passed to an int field, instead of
+// failing the render. Skipped attributes are reported to the handler set
+// using WithWarningHandler. It's intended for incremental migrations, where
+// templates may temporarily pass values of the wrong type.
+func WithLenientAssignment() Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.SkipUnassignable = true
+	})
+}
+
+// WithLenientTypedStrings allows strings from expressions, like
+// `Body="{{.Markdown}}"`, to populate fields typed as one of html/template's
+// safe strings, like template.HTML, converting them so they're rendered
+// without being escaped. By default, those fields only accept literal
+// attribute text, which is converted when the template is compiled, and
+// values of the field's type, returning an *AssignmentError for other strings
+// since they may contain untrusted content.
+func WithLenientTypedStrings() Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.LenientTypedStrings = true
+	})
+}
+
+// WithBalancedOutputCheck returns an *UnbalancedError from renders when a
+// component renders a start tag without its end tag, like a conditional that
+// only renders `<div>`, or an end tag without its start tag. Each component is
+// checked separately, along with child content, which is checked as part of
+// the component whose template contains it. Void elements, self-closing SVG
+// and MathML elements, the content of raw text elements like `<script>`, and
+// end tags that HTML allows to be omitted, like `</li>`, are accounted for,
+// using the same rules as glamtest.AssertValidHTML. HTML elements like
+// `<div/>` ignore the / and must still be closed.
+//
+// Output is buffered so it can be checked, so it's intended for development
+// and tests. Renders into multiple targets aren't checked.
+func WithBalancedOutputCheck() Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.CheckBalance = true
+	})
+}
+
+// WithParserLimits returns an error when registering a template that exceeds
+// the given limits, like a template from an untrusted source with deeply
+// nested components or thousands of attributes on a tag, instead of parsing it
+// until it exhausts memory or the stack.
+func WithParserLimits(limits ParserLimits) Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.Limits = limits
+	})
+}
+
+// WithRecompileLimit limits the number of times templates can be recompiled
+// because a component they render was registered after them. Registering a
+// component that would exceed the limit returns an error, which helps catch
+// registration orders that make startup slow for large sets of components.
+// Registering components before the components that render them avoids
+// recompiles entirely.
+func WithRecompileLimit(limit int) Option {
+	return optionFunc(func(e *Engine) {
+		e.recompileLimit = limit
+	})
+}
+
+// RetainSource controls whether the engine keeps the source of every parsed
+// template. By default, the source is discarded to save memory unless it's
+// needed to recompile the template when a referenced component is registered.
+func RetainSource(retain bool) Option {
+	return optionFunc(func(e *Engine) {
+		e.templateOptions.RetainSource = retain
+	})
+}
+
+func defaultErrorHandler(w http.ResponseWriter, _ error) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
 // Render renders the provided toRender value to the provided writer. `renderable` should
 // be a struct or a pointer to a struct that has been registered with the engine.
 func (e *Engine) Render(w io.Writer, renderable any) error {
 	return e.RenderWithFuncs(w, renderable, nil)
 }
 
-func (e *Engine) RenderWithFuncs(w io.Writer, renderable any, funcMap FuncMap) error {
+// RenderWithOptions renders the provided component to the provided writer
+// using the given options. See RenderOptions for the available options.
+func (e *Engine) RenderWithOptions(w io.Writer, renderable any, opts RenderOptions) error {
+	var funcMap FuncMap
+
+	if flusher, ok := w.(http.Flusher); ok && opts.FlushEvery > 0 {
+		flushPoints := 0
+		funcMap = FuncMap{
+			"__glamFlush": func() bool {
+				flushPoints++
+				if flushPoints%opts.FlushEvery == 0 {
+					flusher.Flush()
+				}
+
+				return false
+			},
+		}
+	}
+
+	return e.RenderWithFuncs(w, renderable, funcMap)
+}
+
+func (e *Engine) RenderWithFuncs(w io.Writer, renderable any, funcMap map[string]any) error {
+	component, componentType, err := resolveComponent(renderable)
+	if err != nil {
+		return err
+	}
+
+	// Function components are rendered by calling the registered function
+	// instead of executing a template
+	if funcComponent, ok := component.(*template.FuncComponent); ok {
+		return e.renderFuncComponent(w, funcComponent)
+	}
+
 	// Thought, create a render function that accepts a funcmap to override
 	// after `.cloning` a template. This will enable passing request specific data
-	v := reflect.ValueOf(renderable)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
+	template, ok, err := e.componentTemplate(componentType.Name())
+	if err != nil {
+		return err
 	}
 
-	if template, ok := e.templateMap[v.Type().Name()]; ok {
-		err := template.Execute(w, renderable, funcMap)
+	if ok {
+		err := e.executeTemplate(w, template, component, funcMap)
 		if err != nil {
 			return fmt.Errorf("error rendering component: %w", err)
 		}
@@ -77,116 +624,1370 @@ func (e *Engine) RenderWithFuncs(w io.Writer, renderable any, funcMap FuncMap) e
 		return nil
 	}
 
-	return fmt.Errorf("No component found for type %s", v.Type().Name())
+	return fmt.Errorf("No component found for type %s", describeType(componentType))
 }
 
-// RegisterComponent registers a component with the engine. The provided value must be a struct
-// or a pointer to a struct. The provided template string will be parsed and the component will be
-// rendered using the provided template.
-func (e *Engine) RegisterComponent(value any, templateString string) error {
-	r := reflect.TypeOf(value)
-	if r.Kind() != reflect.Struct && (r.Kind() != reflect.Ptr && r.Elem().Kind() != reflect.Struct) {
-		return fmt.Errorf("provided value must be a struct or a pointer to a struct")
+// resolveComponent unwraps the interfaces and pointers around renderable so
+// components can be rendered using their concrete type, e.g. when rendering
+// an interface field holding one of several components. It returns the
+// component to render, along with its concrete type. Structs are always
+// rendered using a pointer so pointer receiver methods are available,
+// regardless of whether the component was registered or rendered using a
+// pointer or a value.
+func resolveComponent(renderable any) (any, reflect.Type, error) {
+	v := reflect.ValueOf(renderable)
+	if !v.IsValid() {
+		return nil, nil, errors.New("cannot render a nil component")
 	}
 
-	v := reflect.ValueOf(value)
-	if r.Kind() == reflect.Ptr {
-		v = v.Elem()
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil, fmt.Errorf("cannot render a nil %s", reflect.TypeOf(renderable))
+		}
+
+		elem := v.Elem()
+		if v.Kind() == reflect.Ptr && elem.Kind() != reflect.Ptr && elem.Kind() != reflect.Interface {
+			return v.Interface(), elem.Type(), nil
+		}
+
+		v = elem
 	}
 
-	name := v.Type().Name()
-	// We need access to public structs, so disallow private structs
-	if unicode.IsLower([]rune(name)[0]) {
-		return fmt.Errorf("component %s is private, registered components must be public", name)
+	// Struct values aren't addressable, so they're copied into a new value
+	// that is
+	if v.Kind() == reflect.Struct {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+
+		return p.Interface(), v.Type(), nil
 	}
 
-	e.components[name] = reflect.TypeOf(value)
-	err := e.parseTemplate(name, templateString)
-	if err != nil {
-		return fmt.Errorf("could not register template: %w", err)
+	return v.Interface(), v.Type(), nil
+}
+
+// describeType returns the name of the given type along with its package, so
+// errors can distinguish between types with the same name.
+func describeType(t reflect.Type) string {
+	if t.Name() == "" || t.PkgPath() == "" {
+		return t.String()
 	}
 
-	return nil
+	return fmt.Sprintf("%s (%s.%s)", t.Name(), t.PkgPath(), t.Name())
 }
 
-// RegisterComponentFS registers the given component with the engine, reading
-// the file at the given path and using it as the template for the component.
-func (e *Engine) RegisterComponentFS(value any, fs fs.ReadFileFS, filePath string) error {
-	c, err := fs.ReadFile(filePath)
+// RenderByName renders the registered component with the given name using
+// data, instead of looking up the component by data's type. This allows
+// components to be rendered with data of any type, like a map[string]any.
+//
+// Templates registered using RegisterTemplate are rendered the same way, but
+// return an error before rendering if data isn't of the type they expect.
+func (e *Engine) RenderByName(w io.Writer, name string, data any) error {
+	template, ok, err := e.componentTemplate(name)
 	if err != nil {
-		return fmt.Errorf("could not read file: %w", err)
+		return err
 	}
 
-	return e.RegisterComponent(value, string(c))
-}
+	if !ok {
+		return fmt.Errorf("No component found with name %s", name)
+	}
 
-func (e *Engine) RegisterManyFS(fs fs.ReadFileFS, components map[any]string) error {
-	for component, path := range components {
-		err := e.RegisterComponentFS(component, fs, path)
+	if expected, ok := e.templateTypes[name]; ok {
+		err := checkTemplateData(name, expected, data)
 		if err != nil {
 			return err
 		}
 	}
 
+	err = e.executeTemplate(w, template, data, nil)
+	if err != nil {
+		return fmt.Errorf("error rendering component: %w", err)
+	}
+
 	return nil
 }
 
-// KnownComponents returns a map of known component names
-func (e *Engine) KnownComponents() map[string]reflect.Type {
-	return e.components
+// checkTemplateData returns an error if data can't be rendered by a template
+// expecting data of the given type. Pointers to the expected type are allowed,
+// since templates access their fields and methods the same way.
+func checkTemplateData(name string, expected reflect.Type, data any) error {
+	actual := reflect.TypeOf(data)
+	if actual == nil {
+		return fmt.Errorf("template %s expects data of type %s, got nil", name, describeType(expected))
+	}
+
+	if actual.AssignableTo(expected) || (actual.Kind() == reflect.Ptr && actual.Elem().AssignableTo(expected)) {
+		return nil
+	}
+
+	return fmt.Errorf("template %s expects data of type %s, got %s", name, describeType(expected), describeType(actual))
 }
 
-// :nodoc:
-func (e *Engine) FuncMap() FuncMap {
-	return e.funcs
+// RenderMulti renders the provided component into multiple writers, keyed by
+// target name. Content inside a `{{target "name"}}...{{end}}` block is written
+// to the named target's writer, while all other content is written to the
+// "html" target. Nested components inherit the active target, so a component
+// rendered inside a target block writes to that target's writer.
+//
+// This allows a single component to render multiple parts of the same output,
+// like the HTML and plain text bodies of an email. When rendered using
+// Render, target blocks are written to the writer like any other content.
+func (e *Engine) RenderMulti(targets map[string]io.Writer, renderable any) error {
+	if targets[DefaultTarget] == nil {
+		return fmt.Errorf("no writer provided for the %q target", DefaultTarget)
+	}
+
+	return e.Render(template.NewTargetWriter(targets, DefaultTarget), renderable)
 }
 
-func (e *Engine) parseTemplate(name, templateValue string) error {
-	// Recompile any templates that were parsed as raw HTML because this component
-	// wasn't registered yet
-	if templates, ok := e.recompileMap[name]; ok {
-		for _, t := range templates {
-			err := e.parseTemplate(t.Name, t.RawContent())
-			if err != nil {
-				return fmt.Errorf("could not recompile template: %w", err)
-			}
+// AppendRender renders the provided component like Render, appending the
+// output to dst and returning the extended slice. dst is grown ahead of time
+// using the average size of previous renders of the component, so reusing dst
+// across renders avoids most allocations for the output.
+func (e *Engine) AppendRender(dst []byte, renderable any) ([]byte, error) {
+	w := &appendWriter{buf: slices.Grow(dst, e.SizeHint(renderable))}
+	err := e.Render(w, renderable)
+	if err != nil {
+		return dst, err
+	}
+
+	return w.buf, nil
+}
+
+// SizeHint returns the estimated rendered size of the provided component, based
+// on the average size of previous renders. It returns 0 if the component isn't
+// registered or hasn't been rendered yet.
+func (e *Engine) SizeHint(renderable any) int {
+	_, componentType, err := resolveComponent(renderable)
+	if err != nil {
+		return 0
+	}
+
+	if template, ok := e.templateMap[componentType.Name()]; ok {
+		return template.SizeHint()
+	}
+
+	// Lazy templates aren't loaded just to estimate their size
+	if lazy, ok := e.lazyTemplates[componentType.Name()]; ok {
+		if template := lazy.template.Load(); template != nil {
+			return template.SizeHint()
 		}
+	}
 
-		delete(e.recompileMap, name)
+	return 0
+}
+
+// appendWriter is an io.Writer that appends to a byte slice
+type appendWriter struct {
+	buf []byte
+}
+
+func (w *appendWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	return len(p), nil
+}
+
+// RenderResponse renders the provided component as an HTML response. The
+// component is rendered into a buffer first so that a failed render doesn't
+// send a partially written 200 response. On failure, the response is written
+// by the engine's error handler and the error is returned so it can be logged.
+func (e *Engine) RenderResponse(w http.ResponseWriter, renderable any) error {
+	var b bytes.Buffer
+	b.Grow(e.SizeHint(renderable))
+
+	err := e.Render(&b, renderable)
+	if err != nil {
+		e.errorHandler(w, err)
+		return err
 	}
 
-	t, err := template.New(name, e, templateValue)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err = b.WriteTo(w)
+
+	return err
+}
+
+// RenderTemplate parses the provided template string and renders it with the
+// given data. The template can reference registered components, but it is not
+// registered with the engine and is discarded after rendering.
+func (e *Engine) RenderTemplate(w io.Writer, templateString string, data any) error {
+	t, err := template.NewWithOptions("glam__inline", e, templateString, e.templateOptions)
 	if err != nil {
 		return err
 	}
 
-	// Register potentially referenced components with the engine so we can
-	// recompile this template if the referenced component is registered later.
-	for k := range t.ComponentsPotentiallyReferenced() {
-		if _, ok := e.recompileMap[k]; !ok {
-			e.recompileMap[k] = make([]*template.Template, 0)
+	err = t.Execute(w, data, nil)
+	if err != nil {
+		return fmt.Errorf("error rendering template: %w", err)
+	}
+
+	return nil
+}
+
+// Preview renders the registered component with the given name standalone,
+// which is useful for building style guides. The component is instantiated
+// with the provided attributes the same way it would be when rendered from a
+// template and Children is populated using WithPreviewChildren.
+//
+// When no attributes are provided and the component has an `Example()
+// map[string]any` method, the attributes it returns are used instead.
+func (e *Engine) Preview(name string, exampleAttrs map[string]any) (htmltemplate.HTML, error) {
+	componentType, ok := e.components[name]
+	if !ok {
+		return "", fmt.Errorf("No component found with name %s", name)
+	}
+
+	if len(exampleAttrs) == 0 {
+		structType := componentType
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
 		}
 
-		e.recompileMap[k] = append(e.recompileMap[k], t)
+		// Pointers include both pointer and value receiver methods
+		if example, ok := reflect.New(structType).Interface().(interface{ Example() map[string]any }); ok {
+			exampleAttrs = example.Example()
+		}
 	}
 
-	e.templateMap[name] = t
+	var component any
+	var err error
+	if componentType == template.FuncComponentType {
+		component = &template.FuncComponent{Name: name, Attributes: exampleAttrs, Children: e.previewChildren}
+	} else {
+		component, err = template.InstantiateFrom(componentType, e.NewComponent(name, componentType), exampleAttrs, func() (htmltemplate.HTML, error) {
+			return e.previewChildren, nil
+		})
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not preview component %s: %w", name, err)
+	}
 
-	return nil
+	var b bytes.Buffer
+	err = e.Render(&b, component)
+	if err != nil {
+		return "", err
+	}
+
+	return htmltemplate.HTML(b.String()), nil
 }
 
-// Dict is a helper function that can be used to create a map[string]any
-// in a template. It's primarily used to pass attributes to components.
-func Dict(args ...any) map[string]any {
-	if len(args)%2 != 0 {
-		panic("invalid number of arguments passed to __glamDict")
+// RenderWithProps renders the provided component like Render and returns the
+// component's props along with the rendered HTML, which is also written to w.
+// This is useful for hydrating components on the client with the same props
+// used to render on the server.
+//
+// Props are keyed and filtered like PropsJSON, so both APIs produce the same
+// payload. An error is returned for values json.Marshal can't serialize, like
+// cyclic pointers, so the returned map can always be marshaled.
+func (e *Engine) RenderWithProps(w io.Writer, renderable any) (props map[string]any, html string, err error) {
+	var b bytes.Buffer
+	err = e.Render(&b, renderable)
+	if err != nil {
+		return nil, "", err
 	}
 
-	dict := make(map[string]any, len(args)/2)
+	component, _, err := resolveComponent(renderable)
+	if err != nil {
+		return nil, "", err
+	}
 
-	for i := 0; i < len(args); i += 2 {
-		dict[args[i].(string)] = args[i+1]
+	v := reflect.Indirect(reflect.ValueOf(component))
+	fields := propFields(v)
+	props = make(map[string]any, len(fields))
+	for _, prop := range fields {
+		value := prop.value.Interface()
+		if _, err := json.Marshal(value); err != nil {
+			return nil, "", fmt.Errorf("could not serialize field %s of %s: %w", prop.field.Name, v.Type().Name(), err)
+		}
+
+		props[prop.key] = value
 	}
 
-	return dict
+	html = b.String()
+	_, err = io.WriteString(w, html)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return props, html, nil
+}
+
+// RegisterComponent registers a component with the engine. The provided value must be a struct
+// or a pointer to a struct. The provided template string will be parsed and the component will be
+// rendered using the provided template.
+func (e *Engine) RegisterComponent(value any, templateString string) error {
+	return e.registerComponent(value, templateString, "")
+}
+
+func (e *Engine) registerComponent(value any, templateString string, filename string) error {
+	name, err := componentName(value)
+	if err != nil {
+		return err
+	}
+
+	e.setComponent(name, reflect.TypeOf(value))
+	e.resetRegistration(name)
+	if filename != "" {
+		e.filenames[name] = filename
+	}
+
+	err = e.parseTemplate(name, templateString)
+	if err != nil {
+		return fmt.Errorf("could not register template: %w", err)
+	}
+
+	return nil
+}
+
+// resetRegistration removes everything registered for the component with the
+// given name besides its type, template, and warnings, so registering it again
+// doesn't keep behavior from how it was registered before. Warnings are kept
+// so parsing the new template only reports warnings that are new.
+func (e *Engine) resetRegistration(name string) {
+	delete(e.funcComponents, name)
+	delete(e.deprecations, name)
+	delete(e.extensions, name)
+	delete(e.templateTypes, name)
+	delete(e.factories, name)
+	delete(e.lazyTemplates, name)
+	delete(e.filenames, name)
+	delete(e.infos, name)
+	delete(e.policies, name)
+	delete(e.mappings, name)
+}
+
+// RegisterTemplate registers a template that isn't backed by a component,
+// like a page or layout, which is rendered using RenderByName. dataType is a
+// value of the type of data the template expects, like `&PageData{}`, and
+// rendering the template with data of another type returns an error before
+// anything is written. When WithStrictBindings is used, the template's
+// bindings are checked against dataType like a component's.
+func (e *Engine) RegisterTemplate(name string, dataType any, templateString string) error {
+	if _, ok := e.components[name]; ok {
+		return fmt.Errorf("template %s can't have the same name as a registered component", name)
+	}
+
+	if dataType == nil {
+		return fmt.Errorf("template %s must be given a value of the type of data it expects, got nil", name)
+	}
+
+	previous, registered := e.templateTypes[name]
+	e.templateTypes[name] = reflect.TypeOf(dataType)
+	delete(e.filenames, name)
+
+	err := e.parseTemplate(name, templateString)
+	if err != nil {
+		// Keep the type of the template that's still registered, if any
+		if registered {
+			e.templateTypes[name] = previous
+		} else {
+			delete(e.templateTypes, name)
+		}
+
+		return fmt.Errorf("could not register template: %w", err)
+	}
+
+	return nil
+}
+
+// RegisterUntrustedComponent registers a component whose template was written
+// by an untrusted author, like an admin of a CMS, restricting it to the funcs
+// and components allowed by policy. Templates that call other funcs, call
+// glam's internal funcs, or render other components return an error with the
+// position of the violation, including when they're recompiled because a
+// component they reference is registered. Only the allowed funcs are bound to
+// the template, and it isn't given partials.
+func (e *Engine) RegisterUntrustedComponent(value any, templateString string, policy Policy) error {
+	name, err := componentName(value)
+	if err != nil {
+		return err
+	}
+
+	e.setComponent(name, reflect.TypeOf(value))
+	e.resetRegistration(name)
+	e.policies[name] = policy
+
+	err = e.parseTemplate(name, templateString)
+	if err != nil {
+		return fmt.Errorf("could not register template: %w", err)
+	}
+
+	return nil
+}
+
+// RegisterComponentWithMapping registers a component like RegisterComponent,
+// populating its fields from the attributes in mapping, which maps attribute
+// names to field names, e.g. `{"data-id": "ID"}`. It's intended for types
+// whose struct tags can't be changed, like types from other packages.
+// Attributes are matched ignoring case, and attributes that aren't mapped
+// populate fields as usual.
+func (e *Engine) RegisterComponentWithMapping(value any, templateString string, mapping map[string]string) error {
+	name, err := componentName(value)
+	if err != nil {
+		return err
+	}
+
+	componentType := reflect.TypeOf(value)
+	if componentType.Kind() == reflect.Ptr {
+		componentType = componentType.Elem()
+	}
+
+	normalized := make(map[string]string, len(mapping))
+	for attribute, fieldName := range mapping {
+		field, ok := componentType.FieldByName(fieldName)
+		if !ok || !field.IsExported() || len(field.Index) != 1 {
+			return fmt.Errorf("component %s has no field %s to map attribute %s to", name, fieldName, attribute)
+		}
+
+		normalized[strings.ToLower(attribute)] = fieldName
+	}
+
+	e.setComponent(name, reflect.TypeOf(value))
+	e.resetRegistration(name)
+	e.mappings[name] = normalized
+
+	err = e.parseTemplate(name, templateString)
+	if err != nil {
+		return fmt.Errorf("could not register template: %w", err)
+	}
+
+	return nil
+}
+
+// RegisterComponentExtending registers a component whose template extends the
+// template of base, a registered component, replacing the templates defined by
+// the base's `{{block}}` actions with the given overrides, keyed by block name.
+// Blocks that aren't overridden render their default content. When base is
+// registered again, the component's template is rebuilt from the new base.
+func (e *Engine) RegisterComponentExtending(value any, base any, overrides map[string]string) error {
+	name, err := componentName(value)
+	if err != nil {
+		return err
+	}
+
+	baseName, err := componentName(base)
+	if err != nil {
+		return fmt.Errorf("invalid base component: %w", err)
+	}
+
+	if _, ok := e.templateMap[baseName]; !ok {
+		return fmt.Errorf("component %s can't extend %s since it isn't registered with a template", name, baseName)
+	}
+
+	// Extending a component that extends this one would recompile forever
+	for current := baseName; current != ""; current = e.extensions[current].base {
+		if current == name {
+			return fmt.Errorf("component %s can't extend %s since it would extend itself", name, baseName)
+		}
+	}
+
+	e.setComponent(name, reflect.TypeOf(value))
+	e.resetRegistration(name)
+	e.extensions[name] = extension{base: baseName, blocks: overrides}
+
+	err = e.parseTemplate(name, "")
+	if err != nil {
+		delete(e.extensions, name)
+		return fmt.Errorf("could not register template: %w", err)
+	}
+
+	return nil
+}
+
+// componentName validates that the given value can be registered as a
+// component, returning the component's name.
+func componentName(value any) (string, error) {
+	r := reflect.TypeOf(value)
+	if r == nil {
+		return "", fmt.Errorf("provided value must be a struct or a pointer to a struct, got nil")
+	}
+
+	structType := r
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	if structType.Kind() != reflect.Struct {
+		return "", fmt.Errorf("provided value must be a struct or a pointer to a struct, got %s", r)
+	}
+
+	name := structType.Name()
+	if name == "" {
+		return "", fmt.Errorf("provided value must be a named struct, got %s", r)
+	}
+
+	// We need access to public structs, so disallow private structs
+	if unicode.IsLower([]rune(name)[0]) {
+		return "", fmt.Errorf("component %s is private, registered components must be public", name)
+	}
+
+	// Child content is assigned as template.HTML, so any other type would
+	// panic when rendered
+	if field, ok := structType.FieldByName("Children"); ok && field.Type != htmlType {
+		return "", fmt.Errorf("component %s must declare Children as template.HTML, got %s", name, field.Type)
+	}
+
+	// Child components are collected into a slice of props, so they need a
+	// capitalized tag name and a struct to assign attributes to
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tagName, ok := template.ChildComponentsTag(field)
+		if !ok {
+			continue
+		}
+
+		if tagName == "" || !unicode.IsUpper([]rune(tagName)[0]) {
+			return "", fmt.Errorf("component %s must collect capitalized child components in %s, got %q", name, field.Name, tagName)
+		}
+
+		if !template.IsChildComponentsType(field.Type) {
+			return "", fmt.Errorf("component %s must declare %s as a slice of structs to collect %s children, got %s", name, field.Name, tagName, field.Type)
+		}
+	}
+
+	return name, nil
+}
+
+var (
+	htmlType       = reflect.TypeOf(htmltemplate.HTML(""))
+	errorType      = reflect.TypeOf((*error)(nil)).Elem()
+	attributesType = reflect.TypeOf(map[string]any(nil))
+)
+
+// RegisterFunc registers a function as a component with the given name, for
+// small components that don't need a template. fn must have the signature:
+//
+//	func(props P, children template.HTML) (template.HTML, error)
+//
+// where P is either a struct (or pointer to a struct) that attributes are
+// assigned to the same way they are for struct components, or
+// map[string]any to receive every attribute. The function is called with the
+// component's child content and its output is rendered as-is, so it's
+// responsible for escaping any values it renders.
+func (e *Engine) RegisterFunc(name string, fn any) error {
+	if name == "" || !unicode.IsUpper([]rune(name)[0]) {
+		return fmt.Errorf("function component %q must be capitalized", name)
+	}
+
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("function component %s must be a function, got %T", name, fn)
+	}
+
+	fnType := v.Type()
+	if fnType.NumIn() != 2 || fnType.In(1) != htmlType || fnType.IsVariadic() ||
+		fnType.NumOut() != 2 || fnType.Out(0) != htmlType || fnType.Out(1) != errorType {
+		return fmt.Errorf("function component %s must have the signature func(props, template.HTML) (template.HTML, error), got %s", name, fnType)
+	}
+
+	props := fnType.In(0)
+	structType := props
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	if structType.Kind() != reflect.Struct && props != attributesType {
+		return fmt.Errorf("props of function component %s must be a struct, a pointer to a struct, or map[string]any, got %s", name, props)
+	}
+
+	err := e.templateOptions.CheckTagConflict(name)
+	if err != nil {
+		return err
+	}
+
+	e.setComponent(name, template.FuncComponentType)
+	e.resetRegistration(name)
+	delete(e.templateMap, name)
+	delete(e.warnings, name)
+	e.funcComponents[name] = funcComponent{props: props, fn: v}
+
+	err = e.recompileReferences(name)
+	if err != nil {
+		return fmt.Errorf("could not register function component: %w", err)
+	}
+	e.checkPendingTypos(name)
+
+	err = e.recompilePartials(name)
+	if err != nil {
+		return fmt.Errorf("could not register function component: %w", err)
+	}
+
+	return nil
+}
+
+// renderFuncComponent renders a function component by calling the function
+// it was registered with.
+func (e *Engine) renderFuncComponent(w io.Writer, component *template.FuncComponent) (err error) {
+	if e.metrics != nil {
+		start := time.Now()
+		defer func() {
+			e.metrics.ObserveRender(component.Name, time.Since(start), err != nil)
+		}()
+	}
+
+	fc, ok := e.funcComponents[component.Name]
+	if !ok {
+		return fmt.Errorf("No component found with name %s", component.Name)
+	}
+
+	var props reflect.Value
+	if fc.props == attributesType {
+		// Copy the attributes since they may be shared across renders
+		attributes := make(map[string]any, len(component.Attributes))
+		for k, v := range component.Attributes {
+			attributes[k] = v
+		}
+
+		props = reflect.ValueOf(attributes)
+	} else {
+		value, err := template.Instantiate(fc.props, component.Attributes, nil, e.templateOptions.LenientTypedStrings)
+		if err != nil {
+			return fmt.Errorf("error rendering component %s: %w", component.Name, err)
+		}
+
+		props = reflect.ValueOf(value)
+	}
+
+	out := fc.fn.Call([]reflect.Value{props, reflect.ValueOf(component.Children)})
+	if err, _ := out[1].Interface().(error); err != nil {
+		return fmt.Errorf("error rendering component %s: %w", component.Name, err)
+	}
+
+	_, err = io.WriteString(w, string(out[0].Interface().(htmltemplate.HTML)))
+
+	return err
+}
+
+// RegisterComponentDeprecated registers a component like RegisterComponent,
+// marking it as deprecated with a message explaining what to use instead, e.g.
+// "use Button instead". Deprecated components render as usual, but each render
+// from another component's template is reported to the handler set using
+// WithDeprecationHandler.
+func (e *Engine) RegisterComponentDeprecated(value any, templateString string, message string) error {
+	err := e.registerComponent(value, templateString, "")
+	if err != nil {
+		return err
+	}
+
+	componentType := reflect.TypeOf(value)
+	if componentType.Kind() == reflect.Ptr {
+		componentType = componentType.Elem()
+	}
+	e.deprecations[componentType.Name()] = message
+
+	return nil
+}
+
+// ReportRender is called by templates each time they render a nested
+// component, so renders of deprecated components can be reported.
+//
+// :nodoc:
+func (e *Engine) ReportRender(name string, referencedBy string) {
+	if e.deprecationHandler == nil {
+		return
+	}
+
+	if message, ok := e.deprecations[name]; ok {
+		e.deprecationHandler(Deprecation{Component: name, Message: message, ReferencedBy: referencedBy})
+	}
+}
+
+// ReportUnassignable is called by templates when an attribute is skipped
+// since its value can't be assigned to its field, so it can be reported to
+// the warning handler.
+//
+// :nodoc:
+func (e *Engine) ReportUnassignable(err *template.AssignmentError) {
+	if e.warningHandler == nil {
+		return
+	}
+
+	e.warningHandler(Warning{
+		Component: err.Template,
+		Filename:  err.Filename,
+		Message:   err.Error() + ", skipping it",
+	})
+}
+
+// RegisterComponentFactory registers a component like RegisterComponent, but
+// calls factory to construct it when it's rendered from a template instead of
+// using its zero value, e.g. to inject a logger or repository it needs to
+// render. Attributes and child content are assigned to the instance factory
+// returns, which must be of the same type as value.
+func (e *Engine) RegisterComponentFactory(value any, factory func() any, templateString string) error {
+	name, err := componentName(value)
+	if err != nil {
+		return err
+	}
+
+	if instance := factory(); reflect.TypeOf(instance) != reflect.TypeOf(value) {
+		return fmt.Errorf("factory for component %s must return %T, got %T", name, value, instance)
+	}
+
+	err = e.registerComponent(value, templateString, "")
+	if err != nil {
+		return err
+	}
+	e.factories[name] = factory
+
+	return nil
+}
+
+// FuncComponentProps is called by templates to get the props type of the
+// function component with the given name, returning nil if its props aren't
+// a struct.
+//
+// :nodoc:
+func (e *Engine) FuncComponentProps(name string) reflect.Type {
+	fc, ok := e.funcComponents[name]
+	if !ok || fc.props == attributesType {
+		return nil
+	}
+
+	return fc.props
+}
+
+// AttributeMapping is called by templates to get the attributes mapped to
+// fields of the component with the given name, keyed by lowercased attribute
+// name, returning nil if it has no mapping.
+//
+// :nodoc:
+func (e *Engine) AttributeMapping(name string) map[string]string {
+	return e.mappings[name]
+}
+
+// NewComponent is called by templates to construct the instance of a
+// component, returning nil if it has no factory.
+//
+// :nodoc:
+func (e *Engine) NewComponent(name string, componentType reflect.Type) any {
+	if factory, ok := e.factories[name]; ok {
+		return factory()
+	}
+
+	if e.componentFactory != nil {
+		return e.componentFactory(componentType)
+	}
+
+	return nil
+}
+
+// RegisterComponentFS registers the given component with the engine, reading
+// the file at the given path and using it as the template for the component.
+func (e *Engine) RegisterComponentFS(value any, fs fs.ReadFileFS, filePath string) error {
+	c, err := fs.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("could not read file: %w", err)
+	}
+
+	return e.registerComponent(value, string(c), filePath)
+}
+
+// RegisterManyFS registers each of the given components using the file at its
+// path in fs as its template. Components are registered in order of their
+// path, and then their type name, so the same error is returned on every run
+// when more than one registration fails.
+func (e *Engine) RegisterManyFS(fs fs.ReadFileFS, components map[any]string) error {
+	type entry struct {
+		component any
+		path      string
+		typeName  string
+	}
+
+	entries := make([]entry, 0, len(components))
+	for component, path := range components {
+		entries = append(entries, entry{component: component, path: path, typeName: fmt.Sprintf("%T", component)})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].path != entries[j].path {
+			return entries[i].path < entries[j].path
+		}
+
+		return entries[i].typeName < entries[j].typeName
+	})
+
+	for _, entry := range entries {
+		err := e.RegisterComponentFS(entry.component, fs, entry.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterComponentFSGlob registers components using the files in fsys that
+// match the given pattern, using the syntax of fs.Glob. For each file, resolve
+// is called with the file's path to get the component to register with the
+// file's contents. Files are skipped when resolve returns false.
+func (e *Engine) RegisterComponentFSGlob(fsys fs.FS, pattern string, resolve func(filename string) (any, bool)) error {
+	matches, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return fmt.Errorf("could not glob files: %w", err)
+	}
+
+	for _, filename := range matches {
+		component, ok := resolve(filename)
+		if !ok {
+			continue
+		}
+
+		c, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return fmt.Errorf("could not read file: %w", err)
+		}
+
+		err = e.registerComponent(component, string(c), filename)
+		if err != nil {
+			return fmt.Errorf("could not register %s: %w", filename, err)
+		}
+	}
+
+	return nil
+}
+
+// RegisterPartials parses the `{{define}}` blocks in templateText and adds
+// them to the template of every component, including components registered
+// later, so they can be rendered using `{{template "name" .}}`. Partials are
+// compiled like component templates, so they can render components. Partials
+// replace any partials or templates defined with the same name.
+func (e *Engine) RegisterPartials(templateText string) error {
+	references, err := template.ParsePartials(templateText, e, e.templateOptions)
+	if err != nil {
+		return err
+	}
+
+	e.templateOptions.Partials += templateText
+	for name := range references {
+		e.partialReferences[name] = true
+	}
+
+	return e.addPartials(templateText)
+}
+
+// recompilePartials adds the registered partials to every template again if
+// they reference the component with the given name, since they were compiled
+// before it was registered.
+func (e *Engine) recompilePartials(name string) error {
+	if !e.partialReferences[name] {
+		return nil
+	}
+	delete(e.partialReferences, name)
+
+	return e.addPartials(e.templateOptions.Partials)
+}
+
+// addPartials adds the given partials to the template of every registered
+// component.
+func (e *Engine) addPartials(partials string) error {
+	names := make([]string, 0, len(e.templateMap))
+	for name := range e.templateMap {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		err := e.templateMap[name].AddPartials(partials)
+		if err != nil {
+			return fmt.Errorf("could not add partials to %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Clone returns a copy of the engine that components can be registered (or
+// re-registered) on without affecting the original engine. This is useful in
+// tests that need to swap a component's template for a stub.
+//
+// The clone has its own component, template, and recompilation registries,
+// along with its own copies of the engine's options, warnings, and attribute
+// mappings. Its recompile count starts at zero, so it has the full
+// WithRecompileLimit budget. Parsed templates are cloned and bound to the new
+// engine so nested components are resolved using the clone's registrations,
+// while the underlying parse trees and function values are shared with the
+// original.
+//
+// An error is returned if a component's template can't be cloned.
+func (e *Engine) Clone() (*Engine, error) {
+	clone := &Engine{
+		components:   make(map[string]reflect.Type, len(e.components)),
+		templateMap:  make(map[string]*template.Template, len(e.templateMap)),
+		recompileMap: make(map[string][]*template.Template, len(e.recompileMap)),
+		funcs:        make(htmltemplate.FuncMap, len(e.funcs)),
+		filenames:    make(map[string]string, len(e.filenames)),
+
+		funcComponents:  make(map[string]funcComponent, len(e.funcComponents)),
+		deprecations:    make(map[string]string, len(e.deprecations)),
+		extensions:      make(map[string]extension, len(e.extensions)),
+		warnings:        make(map[string][]Warning, len(e.warnings)),
+		templateOptions: e.templateOptions,
+		errorHandler:    e.errorHandler,
+		previewChildren: e.previewChildren,
+
+		deprecationHandler: e.deprecationHandler,
+		warningHandler:     e.warningHandler,
+		partialReferences:  make(map[string]bool, len(e.partialReferences)),
+		metrics:            e.metrics,
+		recompileLimit:     e.recompileLimit,
+		templateTypes:      make(map[string]reflect.Type, len(e.templateTypes)),
+		factories:          make(map[string]func() any, len(e.factories)),
+		componentFactory:   e.componentFactory,
+		lazyTemplates:      make(map[string]*lazyTemplate, len(e.lazyTemplates)),
+		infos:              make(map[string]ComponentInfo, len(e.infos)),
+		policies:           make(map[string]Policy, len(e.policies)),
+		mappings:           make(map[string]map[string]string, len(e.mappings)),
+	}
+
+	// Options are copied by value, so the maps and slices they contain are
+	// copied too
+	clone.templateOptions.TemplateOptions = slices.Clone(e.templateOptions.TemplateOptions)
+	clone.templateOptions.ShadowedTags = maps.Clone(e.templateOptions.ShadowedTags)
+	clone.templateOptions.PureFuncs = maps.Clone(e.templateOptions.PureFuncs)
+
+	for k, v := range e.components {
+		clone.components[k] = v
+	}
+	clone.componentNames = slices.Clone(e.componentNames)
+
+	for k, v := range e.funcs {
+		clone.funcs[k] = v
+	}
+
+	for k, v := range e.filenames {
+		clone.filenames[k] = v
+	}
+
+	for k, v := range e.funcComponents {
+		clone.funcComponents[k] = v
+	}
+
+	for k, v := range e.deprecations {
+		clone.deprecations[k] = v
+	}
+
+	for k, v := range e.extensions {
+		v.blocks = maps.Clone(v.blocks)
+		clone.extensions[k] = v
+	}
+
+	for k, v := range e.warnings {
+		clone.warnings[k] = slices.Clone(v)
+	}
+
+	for k, v := range e.partialReferences {
+		clone.partialReferences[k] = v
+	}
+
+	for k, v := range e.templateTypes {
+		clone.templateTypes[k] = v
+	}
+
+	for k, v := range e.factories {
+		clone.factories[k] = v
+	}
+
+	for k, v := range e.infos {
+		v.Attributes = slices.Clone(v.Attributes)
+		clone.infos[k] = v
+	}
+
+	for k, v := range e.policies {
+		v.Funcs = slices.Clone(v.Funcs)
+		v.Components = slices.Clone(v.Components)
+		clone.policies[k] = v
+	}
+
+	for k, v := range e.mappings {
+		clone.mappings[k] = maps.Clone(v)
+	}
+
+	// Templates render components using the engine that parsed them, so the
+	// clone loads lazy templates again
+	for k, v := range e.lazyTemplates {
+		clone.lazyTemplates[k] = &lazyTemplate{load: v.load}
+	}
+
+	// Track cloned templates so recompileMap references the clone's templates
+	// instead of the original engine's templates.
+	cloned := make(map[*template.Template]*template.Template, len(e.templateMap))
+	cloneTemplate := func(t *template.Template) (*template.Template, error) {
+		if c, ok := cloned[t]; ok {
+			return c, nil
+		}
+
+		c, err := t.Clone(clone)
+		if err != nil {
+			return nil, err
+		}
+		cloned[t] = c
+
+		return c, nil
+	}
+
+	for name, t := range e.templateMap {
+		c, err := cloneTemplate(t)
+		if err != nil {
+			return nil, fmt.Errorf("could not clone engine: %w", err)
+		}
+
+		clone.templateMap[name] = c
+	}
+
+	for name, templates := range e.recompileMap {
+		clonedTemplates := make([]*template.Template, len(templates))
+		for i, t := range templates {
+			c, err := cloneTemplate(t)
+			if err != nil {
+				return nil, fmt.Errorf("could not clone engine: %w", err)
+			}
+
+			clonedTemplates[i] = c
+		}
+
+		clone.recompileMap[name] = clonedTemplates
+	}
+
+	return clone, nil
+}
+
+// recompileReferences recompiles any templates that were parsed as raw HTML
+// because the component with the given name wasn't registered yet.
+func (e *Engine) recompileReferences(name string) error {
+	if templates, ok := e.recompileMap[name]; ok {
+		recompiled := make(map[string]bool, len(templates))
+		for _, t := range templates {
+			// Templates are added each time they're parsed, so skip templates
+			// that have since been replaced, which would otherwise be
+			// recompiled once for every time they were parsed
+			if e.templateMap[t.Name] != t || recompiled[t.Name] {
+				continue
+			}
+			recompiled[t.Name] = true
+
+			err := e.recompiling(t.Name, name)
+			if err != nil {
+				return err
+			}
+
+			err = e.parseTemplate(t.Name, t.RawContent())
+			if err != nil {
+				return fmt.Errorf("could not recompile template: %w", err)
+			}
+		}
+
+		delete(e.recompileMap, name)
+	}
+
+	return e.recompileLazyReferences(name)
+}
+
+// recompiling counts the recompilation of the given component's template
+// since the component it depends on was registered, returning an error if it
+// exceeds the limit set using WithRecompileLimit.
+func (e *Engine) recompiling(name string, registered string) error {
+	e.recompiles++
+
+	if observer, ok := e.metrics.(RecompileObserver); ok {
+		observer.ObserveRecompile(name, registered)
+	}
+
+	if e.recompileLimit > 0 && e.recompiles > e.recompileLimit {
+		return fmt.Errorf("could not recompile %s after registering %s: exceeded the limit of %d recompiles, register components before the components that render them", name, registered, e.recompileLimit)
+	}
+
+	return nil
+}
+
+// Recompiles returns the number of times templates were recompiled because a
+// component they depend on was registered after them.
+func (e *Engine) Recompiles() int {
+	return e.recompiles
+}
+
+// setComponent registers the type of the component with the given name,
+// tracking the order components are first registered in.
+func (e *Engine) setComponent(name string, componentType reflect.Type) {
+	if _, ok := e.components[name]; !ok {
+		e.componentNames = append(e.componentNames, name)
+	}
+	e.components[name] = componentType
+}
+
+// KnownComponents returns a map of known component names
+func (e *Engine) KnownComponents() map[string]reflect.Type {
+	return e.components
+}
+
+// :nodoc:
+func (e *Engine) FuncMap() htmltemplate.FuncMap {
+	return e.funcs
+}
+
+// componentOptions returns the options used to parse the template of the
+// component or template with the given name, including when it's lazily
+// parsed.
+func (e *Engine) componentOptions(name string) template.Options {
+	opts := e.templateOptions
+	opts.Filename = e.filenames[name]
+	opts.Trusted = e.templateOptions.Trusted || isTrusted(e.components[name])
+	opts.DataType = e.templateTypes[name]
+	if policy, ok := e.policies[name]; ok {
+		opts.Policy = &policy
+	}
+
+	return opts
+}
+
+func (e *Engine) parseTemplate(name, templateValue string) error {
+	err := e.recompileReferences(name)
+	if err != nil {
+		return err
+	}
+
+	opts := e.componentOptions(name)
+
+	var t *template.Template
+	if ext, ok := e.extensions[name]; ok {
+		base, ok := e.templateMap[ext.base]
+		if !ok {
+			return fmt.Errorf("component %s extends %s, which isn't registered with a template", name, ext.base)
+		}
+
+		t, err = base.Extend(name, e, ext.blocks, opts)
+	} else {
+		t, err = template.NewWithOptions(name, e, templateValue, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Register potentially referenced components with the engine so we can
+	// recompile this template if the referenced component is registered later.
+	for k := range t.ComponentsPotentiallyReferenced() {
+		if _, ok := e.recompileMap[k]; !ok {
+			e.recompileMap[k] = make([]*template.Template, 0)
+		}
+
+		e.recompileMap[k] = append(e.recompileMap[k], t)
+	}
+
+	e.templateMap[name] = t
+	e.checkTypos(t)
+	e.checkPendingTypos(name)
+
+	err = e.recompilePartials(name)
+	if err != nil {
+		return err
+	}
+
+	return e.recompileExtensions(name)
+}
+
+// recompileExtensions recompiles the templates of components that extend the
+// component with the given name, so they use its current template.
+func (e *Engine) recompileExtensions(name string) error {
+	extending := make([]string, 0)
+	for extName, ext := range e.extensions {
+		if ext.base == name {
+			extending = append(extending, extName)
+		}
+	}
+	slices.Sort(extending)
+
+	for _, extName := range extending {
+		err := e.recompiling(extName, name)
+		if err != nil {
+			return err
+		}
+
+		err = e.parseTemplate(extName, "")
+		if err != nil {
+			return fmt.Errorf("could not recompile %s, which extends %s: %w", extName, name, err)
+		}
+	}
+
+	return nil
+}
+
+var trustedType = reflect.TypeOf((*Trusted)(nil)).Elem()
+
+// isTrusted returns true if the given component type implements Trusted using
+// either a value or pointer receiver.
+func isTrusted(componentType reflect.Type) bool {
+	if componentType == nil {
+		return false
+	}
+
+	if componentType.Kind() == reflect.Ptr {
+		componentType = componentType.Elem()
+	}
+
+	return reflect.PointerTo(componentType).Implements(trustedType)
+}
+
+// Dict is a helper function that can be used to create a map[string]any
+// in a template. It's primarily used to pass attributes to components.
+func Dict(args ...any) map[string]any {
+	if len(args)%2 != 0 {
+		panic("invalid number of arguments passed to __glamDict")
+	}
+
+	dict := make(map[string]any, len(args)/2)
+
+	for i := 0; i < len(args); i += 2 {
+		dict[args[i].(string)] = args[i+1]
+	}
+
+	return dict
+}
+
+// Classes is a helper function that builds a space separated list of CSS
+// classes. It accepts either alternating class name and condition pairs, or a
+// single map of class names to conditions. Classes are only included when
+// their condition is truthy, following the same rules as `if` in templates.
+//
+// It's available in templates as `classes`, e.g.:
+//
+//	class="{{ classes "active" .IsActive "disabled" .IsDisabled }}"
+func Classes(args ...any) (string, error) {
+	var classNames []string
+
+	if len(args) == 1 {
+		v := reflect.ValueOf(args[0])
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return "", fmt.Errorf("classes expects a map with string keys or class and condition pairs, got %T", args[0])
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		for _, key := range keys {
+			if truth, _ := htmltemplate.IsTrue(v.MapIndex(key).Interface()); truth {
+				classNames = append(classNames, key.String())
+			}
+		}
+
+		return strings.Join(classNames, " "), nil
+	}
+
+	if len(args)%2 != 0 {
+		return "", fmt.Errorf("classes expects class and condition pairs, got %d arguments", len(args))
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		className, ok := args[i].(string)
+		if !ok {
+			return "", fmt.Errorf("classes expects class names to be strings, got %T", args[i])
+		}
+
+		if truth, _ := htmltemplate.IsTrue(args[i+1]); truth && className != "" {
+			classNames = append(classNames, className)
+		}
+	}
+
+	return strings.Join(classNames, " "), nil
+}
+
+// propField is a component field that's serialized to hydrate the component
+// on the client
+type propField struct {
+	key   string
+	field reflect.StructField
+	value reflect.Value
+}
+
+// propFields returns the exported fields of the given component struct that
+// are passed to the client as props. Fields are keyed by their `json` tag name
+// when present, otherwise by the attribute name that populates them. Children,
+// fields tagged with `json:"-"`, the field tagged with `glam:"attrs"`, and
+// fields that collect child components are omitted, since they hold rendered
+// content instead of props. Funcs, channels, and unsafe pointers are omitted
+// too, since they can't be represented in JSON.
+func propFields(v reflect.Value) []propField {
+	fields := make([]propField, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() || field.Name == "Children" || field.Tag.Get("glam") == "attrs" {
+			continue
+		}
+		if _, ok := template.ChildComponentsTag(field); ok {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+			continue
+		}
+
+		key := template.AttributeName(field)
+		if tag := field.Tag.Get("json"); tag != "" {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+
+			if tagName != "" {
+				key = tagName
+			}
+		}
+
+		fields = append(fields, propField{key: key, field: field, value: v.Field(i)})
+	}
+
+	return fields
+}
+
+// PropsJSON serializes the exported fields of the given component into a JSON
+// object that can be embedded in a `<script type="application/json">` element
+// to hydrate the component on the client. Children, fields tagged with
+// `json:"-"`, fields holding attributes or child components, and funcs and
+// channels are omitted.
+// The `json` tag name is used as the key when present, otherwise the
+// attribute name that populates the field, matching RenderWithProps.
+//
+// It's available in templates as `propsJSON`, e.g.:
+//
+//	<script type="application/json">{{ propsJSON . }}</script>
+func PropsJSON(component any) (htmltemplate.JS, error) {
+	v := reflect.ValueOf(component)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return "", fmt.Errorf("propsJSON expects a component struct, got %T", component)
+	}
+
+	var b strings.Builder
+	b.WriteString("{")
+
+	for _, prop := range propFields(v) {
+		// Marshal each field individually so errors can name the field that
+		// couldn't be serialized. json.Marshal escapes <, >, and & so values
+		// can't close the surrounding script element.
+		value, err := json.Marshal(prop.value.Interface())
+		if err != nil {
+			return "", fmt.Errorf("could not serialize field %s of %s: %w", prop.field.Name, v.Type().Name(), err)
+		}
+
+		key, err := json.Marshal(prop.key)
+		if err != nil {
+			return "", fmt.Errorf("could not serialize field name %s: %w", prop.key, err)
+		}
+
+		if b.Len() > 1 {
+			b.WriteString(",")
+		}
+
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+
+	b.WriteString("}")
+
+	return htmltemplate.JS(b.String()), nil
 }
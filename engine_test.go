@@ -2,11 +2,22 @@ package glam
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
 	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -137,6 +148,22 @@ func TestTemplateParse_Nested_ReverseRegister(t *testing.T) {
 	require.Regexp(t, regexp.MustCompile(`</b>`), b.String())
 }
 
+type SelfClosingPage struct{}
+
+func TestTemplateParse_SelfClosing_ReverseRegister(t *testing.T) {
+	engine := New()
+
+	err := engine.RegisterComponent(&SelfClosingPage{}, `<p>Hi<NestedComponent /></p>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&NestedComponent{}, `<i>nested</i>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &SelfClosingPage{})
+	require.NoError(t, err)
+	require.Equal(t, `<p>Hi<i>nested</i></p>`, b.String())
+}
+
 type TestFSComponent struct {
 	Value string
 }
@@ -155,6 +182,38 @@ func TestEngineRegisterComponentFS(t *testing.T) {
 	require.Contains(t, b.String(), "Testing, world!")
 }
 
+func TestEngineRegisterManyFS(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"fs.glam.html":       &fstest.MapFile{Data: []byte(`<p>{{.Value}}</p>`)},
+		"streamed.glam.html": &fstest.MapFile{Data: []byte(`<b>{{.Name}}</b>`)},
+	}
+
+	engine := New(nil)
+	err := engine.RegisterManyFS(templateFS, map[any]string{
+		&TestFSComponent{}: "fs.glam.html",
+		&StreamedItem{}:    "streamed.glam.html",
+	})
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TestFSComponent{Value: "Hi"})
+	require.NoError(t, err)
+	require.Equal(t, "<p>Hi</p>", b.String())
+
+	// Components are registered in path order, so the first missing path is
+	// always the one reported
+	for i := 0; i < 20; i++ {
+		err = New(nil).RegisterManyFS(templateFS, map[any]string{
+			&FormComponent{}:   "missing/d.glam.html",
+			&Title{}:           "missing/b.glam.html",
+			&TestFSComponent{}: "fs.glam.html",
+			&Dialog{}:          "missing/c.glam.html",
+			&StreamedItem{}:    "missing/a.glam.html",
+		})
+		require.ErrorContains(t, err, "open missing/a.glam.html")
+	}
+}
+
 type FormComponent struct{}
 
 func TestRenderWithFuncs(t *testing.T) {
@@ -199,6 +258,45 @@ func TestRegistrationFailures(t *testing.T) {
 			component:   Title{},
 			errorString: "component Title conflicts with an existing HTML tag",
 		},
+		{
+			desc:        "ints return an error",
+			component:   1,
+			errorString: "provided value must be a struct or a pointer to a struct, got int",
+		},
+		{
+			desc:        "strings return an error",
+			component:   "Title",
+			errorString: "provided value must be a struct or a pointer to a struct, got string",
+		},
+		{
+			desc:        "pointers to ints return an error",
+			component:   new(int),
+			errorString: "provided value must be a struct or a pointer to a struct, got *int",
+		},
+		{
+			desc:        "maps return an error",
+			component:   map[string]string{},
+			errorString: "provided value must be a struct or a pointer to a struct, got map[string]string",
+		},
+		{
+			desc:        "slices return an error",
+			component:   []PublicComponent{},
+			errorString: "provided value must be a struct or a pointer to a struct, got []glam.PublicComponent",
+		},
+		{
+			desc:        "nil returns an error",
+			component:   nil,
+			errorString: "provided value must be a struct or a pointer to a struct, got nil",
+		},
+		{
+			desc:        "anonymous structs return an error",
+			component:   struct{ Name string }{},
+			errorString: "provided value must be a named struct",
+		},
+		{
+			desc:      "pointers to structs are registered",
+			component: &PublicComponent{},
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -213,3 +311,3605 @@ func TestRegistrationFailures(t *testing.T) {
 		})
 	}
 }
+
+type Dialog struct {
+	Children template.HTML
+}
+
+func TestShadowedTags(t *testing.T) {
+	engine := New(nil, WithShadowedTags("Dialog"))
+
+	// Register the page first so Dialog must be recompiled when registered
+	err := engine.RegisterComponent(&TestFSComponent{}, `<Dialog>Hi</Dialog><dialog open="">Native</dialog>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&Dialog{}, `<div role="dialog">{{.Children}}</div>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TestFSComponent{})
+	require.NoError(t, err)
+	require.Equal(t, `<div role="dialog">Hi</div><dialog open="">Native</dialog>`, b.String())
+
+	err = New(nil, WithShadowedTags("Dialog")).RegisterComponent(Title{}, "")
+	require.ErrorContains(t, err, "component Title conflicts with an existing HTML tag")
+}
+
+func TestRenderTwice(t *testing.T) {
+	engine := New(nil)
+
+	err := engine.RegisterComponent(&GreetingPage{}, greetingTemplate)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&NestedComponent{}, nestedTemplate)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		var b bytes.Buffer
+		err = engine.Render(&b, &GreetingPage{Name: "Fox Mulder"})
+		require.NoError(t, err)
+		require.Contains(t, b.String(), "Name: Fox Mulder")
+		require.Regexp(t, regexp.MustCompile(`<article>\s+Foo`), b.String())
+	}
+}
+
+func TestEngineClone(t *testing.T) {
+	engine := New(nil)
+
+	err := engine.RegisterComponent(&GreetingPage{}, greetingTemplate)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&NestedComponent{}, nestedTemplate)
+	require.NoError(t, err)
+
+	clone, err := engine.Clone()
+	require.NoError(t, err)
+	err = clone.RegisterComponent(&NestedComponent{}, `<p>stubbed</p>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = clone.Render(&b, &GreetingPage{Name: "Fox Mulder"})
+	require.NoError(t, err)
+	require.Contains(t, b.String(), "Name: Fox Mulder")
+	require.Contains(t, b.String(), "<p>stubbed</p>")
+	require.NotContains(t, b.String(), "<article>")
+
+	b.Reset()
+	err = engine.Render(&b, &GreetingPage{Name: "Fox Mulder"})
+	require.NoError(t, err)
+	require.Contains(t, b.String(), "Name: Fox Mulder")
+	require.Regexp(t, regexp.MustCompile(`<article>\s+Foo`), b.String())
+	require.NotContains(t, b.String(), "stubbed")
+}
+
+func TestEngineClone_RecompilesOnlyClone(t *testing.T) {
+	engine := New(nil)
+
+	err := engine.RegisterComponent(&GreetingPage{}, greetingTemplate)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&NestedComponent{}, nestedTemplate)
+	require.NoError(t, err)
+
+	clone, err := engine.Clone()
+	require.NoError(t, err)
+	err = clone.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = clone.Render(&b, &GreetingPage{Name: "Fox Mulder"})
+	require.NoError(t, err)
+	require.Contains(t, b.String(), "Name: Fox Mulder")
+
+	b.Reset()
+	err = engine.Render(&b, &GreetingPage{Name: "Fox Mulder"})
+	require.NoError(t, err)
+	require.NotContains(t, b.String(), "Name: Fox Mulder")
+	require.Contains(t, b.String(), "<WrapperComponent")
+	require.NotContains(t, engine.KnownComponents(), "WrapperComponent")
+}
+
+func TestEngineClone_Independent(t *testing.T) {
+	item := func(_ map[string]any, _ template.HTML) (template.HTML, error) {
+		return "<i></i>", nil
+	}
+
+	engine := New(
+		WithRecompileLimit(1),
+		WithPureFuncs(FuncMap{"pure": strings.ToUpper}),
+		WithShadowedTags("Button"),
+	)
+	err := engine.RegisterComponent(&RecompilePage{}, `<Item0 /><Item1 />`)
+	require.NoError(t, err)
+	err = engine.RegisterFunc("Item0", item)
+	require.NoError(t, err)
+	require.Equal(t, 1, engine.Recompiles())
+	engine.warnings["Notes"] = append(make([]Warning, 0, 2), Warning{Message: "original"})
+
+	clone, err := engine.Clone()
+	require.NoError(t, err)
+
+	// The clone has the full recompile budget
+	require.Equal(t, 0, clone.Recompiles())
+	err = clone.RegisterFunc("Item1", item)
+	require.NoError(t, err)
+	require.Equal(t, 1, engine.Recompiles())
+
+	// Options and warnings are copied
+	clone.templateOptions.PureFuncs["shout"] = true
+	clone.templateOptions.ShadowedTags["Input"] = true
+	clone.warnings["Notes"][0].Message = "clone"
+	require.NotContains(t, engine.templateOptions.PureFuncs, "shout")
+	require.NotContains(t, engine.templateOptions.ShadowedTags, "Input")
+	require.Equal(t, "original", engine.warnings["Notes"][0].Message)
+}
+
+type PropsComponent struct {
+	Name     string `attr:"full-name"`
+	Age      int
+	Nickname string `json:"nick"`
+	OnSave   func()
+	Attrs    template.HTMLAttr `glam:"attrs"`
+	Children template.HTML
+	secret   string
+}
+
+func TestRenderWithProps(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&PropsComponent{}, `<p>{{.Name}} is {{.Age}}</p>`)
+	require.NoError(t, err)
+
+	component := &PropsComponent{
+		Name:     "Fox Mulder",
+		Age:      32,
+		Nickname: "Spooky",
+		OnSave:   func() {},
+		Attrs:    `class="agent"`,
+		secret:   "trust no one",
+	}
+
+	var b bytes.Buffer
+	props, html, err := engine.RenderWithProps(&b, component)
+	require.NoError(t, err)
+
+	require.Equal(t, "<p>Fox Mulder is 32</p>", b.String())
+	require.Equal(t, "<p>Fox Mulder is 32</p>", html)
+	require.Equal(t, map[string]any{"full-name": "Fox Mulder", "age": 32, "nick": "Spooky"}, props)
+
+	encoded, err := json.Marshal(props)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"full-name": "Fox Mulder", "age": 32, "nick": "Spooky"}`, string(encoded))
+
+	// RenderWithProps and propsJSON produce the same payload
+	err = engine.RegisterComponent(&HydratedComponent{}, `<div>{{.Title}}</div>`)
+	require.NoError(t, err)
+
+	hydrated := &HydratedComponent{Title: "Hi", Count: 3, Secret: "hidden"}
+	props, _, err = engine.RenderWithProps(&b, hydrated)
+	require.NoError(t, err)
+	encoded, err = json.Marshal(props)
+	require.NoError(t, err)
+
+	propsJSON, err := PropsJSON(hydrated)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"title": "Hi", "count": 3}`, string(propsJSON))
+	require.JSONEq(t, string(propsJSON), string(encoded))
+}
+
+func TestRenderWithProps_Unserializable(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&CyclicComponent{}, `<p>cycle</p>`)
+	require.NoError(t, err)
+
+	cycle := &CyclicProps{}
+	cycle.Next = cycle
+
+	var b bytes.Buffer
+	_, _, err = engine.RenderWithProps(&b, &CyclicComponent{Node: cycle})
+	require.ErrorContains(t, err, "could not serialize field Node of CyclicComponent")
+	require.Empty(t, b.String())
+}
+
+type recordingFlusher struct {
+	bytes.Buffer
+	flushes []string
+}
+
+func (f *recordingFlusher) Flush() {
+	f.flushes = append(f.flushes, f.String())
+}
+
+type StreamedPage struct {
+	Names []string
+}
+
+type StreamedItem struct {
+	Name     string
+	Children template.HTML
+}
+
+var streamedPageTemplate = `<html><head><title>Streamed</title></head><body>{{range .Names}}<StreamedItem name="{{.}}">!</StreamedItem>{{end}}<p>done</p></body></html>`
+
+func TestRenderWithOptions_FlushEvery(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&StreamedItem{}, `<div>{{.Name}}{{.Children}}</div>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&StreamedPage{}, streamedPageTemplate)
+	require.NoError(t, err)
+
+	var w recordingFlusher
+	err = engine.RenderWithOptions(&w, &StreamedPage{Names: []string{"Fox", "Dana"}}, RenderOptions{FlushEvery: 1})
+	require.NoError(t, err)
+
+	require.Equal(t, `<html><head><title>Streamed</title></head><body><div>Fox!</div><div>Dana!</div><p>done</p></body></html>`, w.String())
+	// Flush points between root level nodes, including after the layout head,
+	// and after each root level component, including one per loop iteration
+	require.Contains(t, w.flushes, `<html><head><title>Streamed</title></head>`)
+	require.Contains(t, w.flushes, `<html><head><title>Streamed</title></head><body><div>Fox!</div>`)
+	require.Contains(t, w.flushes, `<html><head><title>Streamed</title></head><body><div>Fox!</div><div>Dana!</div>`)
+
+	w = recordingFlusher{}
+	err = engine.RenderWithOptions(&w, &StreamedPage{Names: []string{"Fox", "Dana"}}, RenderOptions{FlushEvery: 1})
+	require.NoError(t, err)
+	flushPoints := len(w.flushes)
+
+	w = recordingFlusher{}
+	err = engine.RenderWithOptions(&w, &StreamedPage{Names: []string{"Fox", "Dana"}}, RenderOptions{FlushEvery: 2})
+	require.NoError(t, err)
+	require.Len(t, w.flushes, flushPoints/2)
+
+	w = recordingFlusher{}
+	err = engine.Render(&w, &StreamedPage{Names: []string{"Fox", "Dana"}})
+	require.NoError(t, err)
+	require.Empty(t, w.flushes)
+}
+
+func TestRenderWithOptions_FlushPointsInsideActions(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&StreamedItem{}, `<div>{{.Name}}</div>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&TestFSComponent{}, `<p>{{ if lt 1 2 }}<b>yes</b><StreamedItem name="{{ "a}}" }}" />{{ end }}</p><script>var x = 1;</script>`)
+	require.NoError(t, err)
+
+	var w recordingFlusher
+	err = engine.RenderWithOptions(&w, &TestFSComponent{}, RenderOptions{FlushEvery: 1})
+	require.NoError(t, err)
+	require.Equal(t, `<p><b>yes</b><div>a}}</div></p><script>var x = 1;</script>`, w.String())
+	require.Equal(t, `<p>`, w.flushes[0])
+	require.Contains(t, w.flushes, `<p><b>yes</b><div>a}}</div>`)
+
+	// Templates without components flush between raw nodes
+	err = engine.RegisterComponent(&TestFSComponent{}, `<p>{{ if lt 1 2 }}<b>yes</b>{{ end }}</p>`)
+	require.NoError(t, err)
+	w = recordingFlusher{}
+	err = engine.RenderWithOptions(&w, &TestFSComponent{}, RenderOptions{FlushEvery: 1})
+	require.NoError(t, err)
+	require.Equal(t, `<p><b>yes</b></p>`, w.String())
+	require.Equal(t, `<p>`, w.flushes[0])
+}
+
+type LinkComponent struct {
+	Href     string
+	Children template.HTML
+}
+
+type URLPage struct {
+	Slug string
+}
+
+func (p URLPage) BuildURL() string {
+	return "/posts/" + p.Slug
+}
+
+func (p URLPage) BuildURLFor(slug string) string {
+	return "/posts/" + slug
+}
+
+func TestRenderParentMethodAttributes(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		expected []string
+	}{
+		{
+			desc:     "method call bound to a child component",
+			template: `<LinkComponent href="{{.BuildURL}}">Read</LinkComponent>`,
+			expected: []string{`<a href="/posts/hello">Read</a>`},
+		},
+		{
+			desc:     "method call bound to a self-closing child component",
+			template: `<LinkComponent href="{{.BuildURL}}" />`,
+			expected: []string{`<a href="/posts/hello"></a>`},
+		},
+		{
+			desc:     "method call with arguments bound to a child component",
+			template: `<LinkComponent href="{{.BuildURLFor "world"}}">Read</LinkComponent>`,
+			expected: []string{`<a href="/posts/world">Read</a>`},
+		},
+		{
+			desc:     "method call bound to a component nested in children",
+			template: `<NestedComponent><LinkComponent href="{{.BuildURL}}">Read</LinkComponent></NestedComponent>`,
+			expected: []string{`<article>`, `<a href="/posts/hello">Read</a>`},
+		},
+		{
+			desc:     "method call bound to a component nested two levels deep",
+			template: `<NestedComponent><NestedComponent><LinkComponent href="{{.BuildURL}}">{{.Slug}}</LinkComponent></NestedComponent></NestedComponent>`,
+			expected: []string{`<a href="/posts/hello">hello</a>`},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New(nil)
+			err := engine.RegisterComponent(&NestedComponent{}, nestedTemplate)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&LinkComponent{}, `<a href="{{.Href}}">{{.Children}}</a>`)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(URLPage{}, tC.template)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, URLPage{Slug: "hello"})
+			require.NoError(t, err)
+
+			for _, expected := range tC.expected {
+				require.Contains(t, b.String(), expected)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.RenderTemplate(&b, `<h1>{{.Title}}</h1><WrapperComponent name="{{.Name}}">Hello</WrapperComponent><Unregistered>`, map[string]any{
+		"Title": "Agents",
+		"Name":  "Fox Mulder",
+	})
+	require.NoError(t, err)
+
+	require.Contains(t, b.String(), "<h1>Agents</h1>")
+	require.Contains(t, b.String(), "Name: Fox Mulder")
+	require.Contains(t, b.String(), "Hello")
+	require.Contains(t, b.String(), "<Unregistered>")
+
+	require.Len(t, engine.templateMap, 1)
+	require.Empty(t, engine.recompileMap)
+}
+
+func TestRenderTemplate_ParseError(t *testing.T) {
+	engine := New(nil)
+
+	var b bytes.Buffer
+	err := engine.RenderTemplate(&b, `<h1>{{.Title</h1>`, nil)
+	require.Error(t, err)
+	require.Empty(t, b.String())
+}
+
+type Account struct {
+	First string
+	Last  string
+}
+
+func (a Account) DisplayName() string {
+	return a.First + " " + a.Last
+}
+
+func (a *Account) Greeting(greeting string) string {
+	return greeting + ", " + a.First
+}
+
+type AccountPage struct {
+	User *Account
+}
+
+func (p AccountPage) FormatName() string {
+	return p.User.Last + ", " + p.User.First
+}
+
+func TestRenderParentMethodPipelines(t *testing.T) {
+	expressions := []struct {
+		desc       string
+		expression string
+		expected   string
+	}{
+		{desc: "method", expression: `.FormatName`, expected: "Mulder, Fox"},
+		{desc: "field then method", expression: `.User.DisplayName`, expected: "Fox Mulder"},
+		{desc: "chained pipeline", expression: `.User.DisplayName | upper`, expected: "FOX MULDER"},
+		{desc: "parenthesized call", expression: `(.User.Greeting "Hello")`, expected: "Hello, Fox"},
+		{desc: "parenthesized call in pipeline", expression: `(.User.Greeting "Hello") | upper`, expected: "HELLO, FOX"},
+	}
+
+	wrappers := []struct {
+		desc   string
+		format string
+	}{
+		{desc: "at the root", format: `%s`},
+		{desc: "inside children", format: `<NestedComponent>%s</NestedComponent>`},
+		{desc: "inside grandchildren", format: `<NestedComponent><NestedComponent>%s</NestedComponent></NestedComponent>`},
+	}
+
+	for _, expr := range expressions {
+		for _, wrapper := range wrappers {
+			t.Run(expr.desc+" "+wrapper.desc, func(t *testing.T) {
+				engine := New(WithFuncs(FuncMap{"upper": strings.ToUpper}))
+				err := engine.RegisterComponent(&NestedComponent{}, nestedTemplate)
+				require.NoError(t, err)
+				err = engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+				require.NoError(t, err)
+
+				content := fmt.Sprintf(`<WrapperComponent name="{{%s}}">[{{%s}}]</WrapperComponent>`, expr.expression, expr.expression)
+				err = engine.RegisterComponent(AccountPage{}, fmt.Sprintf(wrapper.format, content))
+				require.NoError(t, err)
+
+				var b bytes.Buffer
+				err = engine.Render(&b, AccountPage{User: &Account{First: "Fox", Last: "Mulder"}})
+				require.NoError(t, err)
+
+				require.Contains(t, b.String(), "Name: "+expr.expected)
+				require.Contains(t, b.String(), "["+expr.expected+"]")
+			})
+		}
+	}
+}
+
+type ClassesComponent struct {
+	IsActive   bool
+	IsDisabled bool
+	Count      int
+	Extra      map[string]bool
+}
+
+func TestClasses(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		template  string
+		component ClassesComponent
+		expected  string
+	}{
+		{
+			desc:      "all conditions true",
+			template:  `<b class="{{ classes "btn" true "active" .IsActive "disabled" .IsDisabled }}"></b>`,
+			component: ClassesComponent{IsActive: true, IsDisabled: true},
+			expected:  `<b class="btn active disabled"></b>`,
+		},
+		{
+			desc:      "some conditions false",
+			template:  `<b class="{{ classes "btn" true "active" .IsActive "disabled" .IsDisabled }}"></b>`,
+			component: ClassesComponent{IsActive: true},
+			expected:  `<b class="btn active"></b>`,
+		},
+		{
+			desc:      "all conditions false",
+			template:  `<b class="{{ classes "active" .IsActive "disabled" .IsDisabled }}"></b>`,
+			component: ClassesComponent{},
+			expected:  `<b class=""></b>`,
+		},
+		{
+			desc:      "non-bool values use template truthiness",
+			template:  `<b class="{{ classes "has-items" .Count "empty" (not .Count) "nil" nil "text" "yes" }}"></b>`,
+			component: ClassesComponent{Count: 2},
+			expected:  `<b class="has-items text"></b>`,
+		},
+		{
+			desc:      "maps are sorted by class name",
+			template:  `<b class="{{ classes .Extra }}"></b>`,
+			component: ClassesComponent{Extra: map[string]bool{"zebra": true, "apple": true, "mango": false}},
+			expected:  `<b class="apple zebra"></b>`,
+		},
+		{
+			desc:      "empty maps produce no classes",
+			template:  `<b class="{{ classes .Extra }}"></b>`,
+			component: ClassesComponent{},
+			expected:  `<b class=""></b>`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New(nil)
+			err := engine.RegisterComponent(ClassesComponent{}, tC.template)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, tC.component)
+			require.NoError(t, err)
+			require.Equal(t, tC.expected, b.String())
+		})
+	}
+}
+
+func TestClasses_Errors(t *testing.T) {
+	_, err := Classes("active")
+	require.ErrorContains(t, err, "classes expects a map with string keys")
+
+	_, err = Classes("active", true, "disabled")
+	require.ErrorContains(t, err, "classes expects class and condition pairs, got 3 arguments")
+
+	_, err = Classes(1, true)
+	require.ErrorContains(t, err, "classes expects class names to be strings, got int")
+}
+
+func TestClasses_Overridable(t *testing.T) {
+	engine := New(WithFuncs(FuncMap{
+		"classes": func(args ...any) string { return "overridden" },
+	}))
+	err := engine.RegisterComponent(ClassesComponent{}, `<b class="{{ classes "active" true }}"></b>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, ClassesComponent{})
+	require.NoError(t, err)
+	require.Equal(t, `<b class="overridden"></b>`, b.String())
+}
+
+type TabData struct {
+	Title string
+}
+
+type TabsComponent struct {
+	Tabs []any
+}
+
+func TestRenderFunc(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(TabData{}, `<li>{{.Title}}</li>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(TabsComponent{}, `<ul>{{range .Tabs}}{{render .}}{{end}}</ul>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, TabsComponent{Tabs: []any{TabData{Title: "One"}, &TabData{Title: "Two"}}})
+	require.NoError(t, err)
+	require.Equal(t, `<ul><li>One</li><li>Two</li></ul>`, b.String())
+}
+
+func TestRenderFunc_Unregistered(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(TabsComponent{}, `<ul>{{range .Tabs}}{{render .}}{{end}}</ul>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, TabsComponent{Tabs: []any{TabData{Title: "One"}}})
+	require.ErrorContains(t, err, "could not render glam.TabData in component TabsComponent")
+	require.ErrorContains(t, err, "No component found for type TabData")
+}
+
+func TestRenderFunc_UserFuncTakesPrecedence(t *testing.T) {
+	engine := New(WithFuncs(FuncMap{
+		"render": func(value any) string { return fmt.Sprintf("custom %v", value) },
+	}))
+	err := engine.RegisterComponent(TabsComponent{}, `<ul>{{range .Tabs}}{{render .}}{{end}}</ul>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, TabsComponent{Tabs: []any{"One"}})
+	require.NoError(t, err)
+	require.Equal(t, `<ul>custom One</ul>`, b.String())
+}
+
+type TabProps struct {
+	Title    string
+	Active   bool
+	Children template.HTML
+}
+
+type TabGroup struct {
+	Tabs     []TabProps `glam:"children-components=Tab"`
+	Children template.HTML
+}
+
+type TabPage struct {
+	Second string
+	Active bool
+}
+
+func TestChildComponentsSlice(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&TabGroup{}, `{{len .Tabs}}:{{range .Tabs}}[{{.Title}} {{.Active}} {{.Children}}]{{end}}{{.Children}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&TabPage{}, `<TabGroup>`+
+		`<Tab title="One" active="{{.Active}}"><b>{{.Second}}</b></Tab>`+
+		`<Tab title="{{.Second}}" />`+
+		`<Tab title="Three"></Tab>`+
+		` after</TabGroup>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TabPage{Second: "Two", Active: true})
+	require.NoError(t, err)
+	require.Equal(t, `3:[One true <b>Two</b>][Two false ][Three false ] after`, b.String())
+}
+
+type PointerTabGroup struct {
+	Tabs []*TabProps `glam:"children-components=Tab"`
+}
+
+func TestChildComponentsSlice_Pointers(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&PointerTabGroup{}, `{{range .Tabs}}[{{.Title}}]{{end}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&TabPage{}, `<PointerTabGroup><Tab title="One" /><Tab title="Two" /></PointerTabGroup>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TabPage{})
+	require.NoError(t, err)
+	require.Equal(t, `[One][Two]`, b.String())
+}
+
+type InvalidTabGroup struct {
+	Tabs []string `glam:"children-components=Tab"`
+}
+
+type LowercaseTabGroup struct {
+	Tabs []TabProps `glam:"children-components=tab"`
+}
+
+func TestChildComponentsSlice_Invalid(t *testing.T) {
+	engine := New(nil)
+
+	err := engine.RegisterComponent(&InvalidTabGroup{}, ``)
+	require.EqualError(t, err, "component InvalidTabGroup must declare Tabs as a slice of structs to collect Tab children, got []string")
+
+	err = engine.RegisterComponent(&LowercaseTabGroup{}, ``)
+	require.EqualError(t, err, `component LowercaseTabGroup must collect capitalized child components in Tabs, got "tab"`)
+}
+
+type BaseCard struct {
+	Title string
+}
+
+type PromoCard struct {
+	Title string
+	Code  string
+}
+
+type PromoBadge struct {
+	Code     string
+	Children template.HTML
+}
+
+func TestRegisterComponentExtending(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&PromoBadge{}, `<b title="{{.Code}}">{{.Children}}</b>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&BaseCard{}, `<div>{{block "header" .}}<h2>{{.Title}}</h2>{{end}}<PromoBadge code="base">{{.Title}}</PromoBadge><footer>{{block "footer" .}}default{{end}}</footer></div>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponentExtending(&PromoCard{}, &BaseCard{}, map[string]string{
+		"footer": `Use <PromoBadge code="{{.Code}}">{{.Code}}</PromoBadge>`,
+	})
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &PromoCard{Title: "Sale", Code: "SAVE10"})
+	require.NoError(t, err)
+	require.Equal(t, `<div><h2>Sale</h2><b title="base">Sale</b><footer>Use <b title="SAVE10">SAVE10</b></footer></div>`, b.String())
+
+	// The base component isn't affected by the override
+	b.Reset()
+	err = engine.Render(&b, &BaseCard{Title: "Plain"})
+	require.NoError(t, err)
+	require.Equal(t, `<div><h2>Plain</h2><b title="base">Plain</b><footer>default</footer></div>`, b.String())
+}
+
+func TestRegisterComponentExtending_BaseReregistered(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&BaseCard{}, `<div>{{block "footer" .}}default{{end}}</div>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponentExtending(&PromoCard{}, &BaseCard{}, map[string]string{
+		"footer": `{{.Code}}`,
+	})
+	require.NoError(t, err)
+
+	err = engine.RegisterComponent(&BaseCard{}, `<section><h2>{{.Title}}</h2>{{block "footer" .}}default{{end}}</section>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &PromoCard{Title: "Sale", Code: "SAVE10"})
+	require.NoError(t, err)
+	require.Equal(t, `<section><h2>Sale</h2>SAVE10</section>`, b.String())
+
+	// Extenders are rebuilt using the new base, so removing a block fails
+	err = engine.RegisterComponent(&BaseCard{}, `<div></div>`)
+	require.ErrorContains(t, err, `could not recompile PromoCard, which extends BaseCard: could not extend template BaseCard: block "footer" isn't defined`)
+}
+
+func TestRegisterComponentExtending_ReplacesRegistration(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&BaseCard{}, `<div>{{block "footer" .}}default{{end}}</div>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponentFactory(&PromoCard{}, func() any { return &PromoCard{Code: "from-factory"} }, `[{{.Code}}]`)
+	require.NoError(t, err)
+	err = engine.RegisterComponentExtending(&PromoCard{}, &BaseCard{}, map[string]string{
+		"footer": `[{{.Code}}]`,
+	})
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&TestFSComponent{}, `<PromoCard code="{{.Value}}"></PromoCard><PromoCard></PromoCard>`)
+	require.NoError(t, err)
+
+	// The factory from the previous registration isn't used
+	var b bytes.Buffer
+	err = engine.Render(&b, &TestFSComponent{Value: "SAVE10"})
+	require.NoError(t, err)
+	require.Equal(t, `<div>[SAVE10]</div><div>[]</div>`, b.String())
+}
+
+func TestRegisterComponentExtending_Errors(t *testing.T) {
+	engine := New(nil)
+
+	err := engine.RegisterComponentExtending(&PromoCard{}, &BaseCard{}, nil)
+	require.EqualError(t, err, "component PromoCard can't extend BaseCard since it isn't registered with a template")
+
+	err = engine.RegisterComponent(&BaseCard{}, `<div>{{block "footer" .}}default{{end}}</div>`)
+	require.NoError(t, err)
+
+	err = engine.RegisterComponentExtending(&PromoCard{}, &BaseCard{}, map[string]string{"header": "Hi"})
+	require.EqualError(t, err, `could not register template: could not extend template BaseCard: block "header" isn't defined`)
+
+	err = engine.RegisterComponentExtending(&PromoCard{}, &BaseCard{}, nil)
+	require.NoError(t, err)
+
+	err = engine.RegisterComponentExtending(&BaseCard{}, &PromoCard{}, nil)
+	require.EqualError(t, err, "component BaseCard can't extend PromoCard since it would extend itself")
+}
+
+type PrefixedPage struct {
+	Name string
+}
+
+type Greeting struct {
+	Name string
+}
+
+func TestWithComponentTagPrefix(t *testing.T) {
+	for _, prefix := range []string{"g:", "x-"} {
+		t.Run(prefix, func(t *testing.T) {
+			engine := New(WithComponentTagPrefix(prefix))
+
+			// Components referenced before they're registered are recompiled
+			err := engine.RegisterComponent(&PrefixedPage{}, fmt.Sprintf(
+				`<%[1]sDialog><%[1]sGreeting name="{{.Name}}"></%[1]sGreeting></%[1]sDialog><Dialog>raw</Dialog>`,
+				prefix,
+			))
+			require.NoError(t, err)
+
+			err = engine.RegisterComponent(&Dialog{}, `<dialog>{{.Children}}</dialog>`)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&Greeting{}, `Hello {{.Name}}`)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, &PrefixedPage{Name: "Fox"})
+			require.NoError(t, err)
+			require.Equal(t, `<dialog>Hello Fox</dialog><Dialog>raw</Dialog>`, b.String())
+		})
+	}
+}
+
+type StrictUser struct {
+	Name string
+}
+
+func (u StrictUser) Initials() string {
+	return u.Name[:1]
+}
+
+type StrictPage struct {
+	User     *StrictUser
+	Items    []string
+	Settings map[string]string
+}
+
+type StrictBadge struct {
+	Label string
+}
+
+type StrictButton struct {
+	Attrs    template.HTMLAttr `glam:"attrs"`
+	Children template.HTML
+}
+
+func TestWithStrictBindings(t *testing.T) {
+	newEngine := func(t *testing.T) *Engine {
+		engine := New(WithStrictBindings())
+		err := engine.RegisterComponent(&StrictBadge{}, `<b>{{.Label}}</b>`)
+		require.NoError(t, err)
+		err = engine.RegisterComponent(&StrictButton{}, `<button {{.Attrs}}>{{.Children}}</button>`)
+		require.NoError(t, err)
+
+		return engine
+	}
+
+	t.Run("valid bindings", func(t *testing.T) {
+		engine := newEngine(t)
+		err := engine.RegisterComponent(&StrictPage{}, `<StrictBadge label="{{.User.Name}}" />`+
+			`<StrictBadge label="{{.User.Initials}}" />`+
+			`<StrictBadge label="{{index .Settings "theme"}}" />`+
+			`<StrictBadge label="{{.Settings.theme}}" />`+
+			`<StrictBadge range="{{.Items}}" label="{{.}}" />`+
+			`{{with .User}}<StrictBadge label="{{.Name}}" />{{end}}`+
+			`<StrictButton data-user="{{.User.Name}}">Save</StrictButton>`)
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		err = engine.Render(&b, &StrictPage{User: &StrictUser{Name: "Fox"}, Items: []string{"a"}, Settings: map[string]string{"theme": "dark"}})
+		require.NoError(t, err)
+		require.Equal(t, `<b>Fox</b><b>F</b><b>dark</b><b>dark</b><b>a</b><b>Fox</b><button data-user="Fox">Save</button>`, b.String())
+	})
+
+	testCases := map[string]string{
+		`<StrictBadge label="{{.Usr.Name}}" />`:                          `attribute of component StrictBadge references .Usr.Name, but glam.StrictPage has no field or method Usr`,
+		`<StrictBadge label="{{.User.Nme}}" />`:                          `attribute of component StrictBadge references .User.Nme, but *glam.StrictUser has no field or method Nme`,
+		`<StrictBadge label="{{printf "%s!" $.User.Nme}}" />`:            `attribute of component StrictBadge references $.User.Nme, but *glam.StrictUser has no field or method Nme`,
+		`<StrictBadge if="{{.User}}" label?="{{.User.Nme}}" />`:          `attribute of component StrictBadge references .User.Nme, but *glam.StrictUser has no field or method Nme`,
+		`<StrictButton><StrictBadge label="{{.Itms}}" /></StrictButton>`: `attribute of component StrictBadge references .Itms, but glam.StrictPage has no field or method Itms`,
+		`<StrictBadge lable="{{.User.Name}}" />`:                         `component StrictBadge has no field for attribute lable`,
+		`<StrictBadge lable="Fox" />`:                                    `component StrictBadge has no field for attribute lable`,
+	}
+
+	for template, expected := range testCases {
+		t.Run(template, func(t *testing.T) {
+			engine := newEngine(t)
+			err := engine.RegisterComponent(StrictPage{}, template)
+			require.ErrorContains(t, err, expected)
+		})
+	}
+
+	t.Run("block overrides", func(t *testing.T) {
+		engine := newEngine(t)
+		err := engine.RegisterComponent(&BaseCard{}, `{{block "footer" .}}{{end}}`)
+		require.NoError(t, err)
+
+		err = engine.RegisterComponentExtending(&PromoCard{}, &BaseCard{}, map[string]string{
+			"footer": `<StrictBadge label="{{.Cde}}" />`,
+		})
+		require.ErrorContains(t, err, `could not parse block "footer" of template PromoCard: attribute of component StrictBadge references .Cde, but *glam.PromoCard has no field or method Cde`)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		engine := New()
+		err := engine.RegisterComponent(&StrictBadge{}, `<b>{{.Label}}</b>`)
+		require.NoError(t, err)
+		err = engine.RegisterComponent(&StrictPage{}, `<StrictBadge label="{{.Usr}}" lable="Fox" />`)
+		require.NoError(t, err)
+	})
+}
+
+type QuotedLabel struct {
+	Label string
+}
+
+type QuotedLabelPage struct {
+	Name string
+}
+
+func TestRawStringAttributes(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&QuotedLabel{}, `<b>{{.Label}}</b>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&QuotedLabelPage{}, "<QuotedLabel label=\"{{ printf `\"%s\"` .Name }}\" />"+
+		"<QuotedLabel label='{{ printf `%s}}` .Name }}' />"+
+		"<QuotedLabel label=\"{{ printf \"{{%s\" .Name }}\" />"+
+		"<a title=\"{{ printf `\"%s\"` .Name }}\">link</a>")
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &QuotedLabelPage{Name: "Fox"})
+	require.NoError(t, err)
+	require.Equal(t, `<b>&#34;Fox&#34;</b><b>Fox}}</b><b>{{Fox</b><a title="&#34;Fox&#34;">link</a>`, b.String())
+}
+
+type ArticleComponent struct {
+	Body string
+}
+
+func TestRawFunc(t *testing.T) {
+	article := ArticleComponent{Body: `<p>Hello <b>world</b></p>`}
+
+	t.Run("body is escaped by default", func(t *testing.T) {
+		engine := New(nil)
+		err := engine.RegisterComponent(ArticleComponent{}, `<article>{{.Body}}</article>`)
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		err = engine.Render(&b, article)
+		require.NoError(t, err)
+		require.Equal(t, `<article>&lt;p&gt;Hello &lt;b&gt;world&lt;/b&gt;&lt;/p&gt;</article>`, b.String())
+	})
+
+	t.Run("raw is not available by default", func(t *testing.T) {
+		engine := New(nil)
+		err := engine.RegisterComponent(ArticleComponent{}, `<article>{{raw .Body}}</article>`)
+		require.ErrorContains(t, err, `function "raw" not defined`)
+	})
+
+	t.Run("safe is not available by default", func(t *testing.T) {
+		engine := New(nil)
+		err := engine.RegisterComponent(ArticleComponent{}, `<article>{{safe .Body}}</article>`)
+		require.ErrorContains(t, err, `function "safe" not defined`)
+	})
+
+	t.Run("safe renders unescaped HTML when raw is enabled", func(t *testing.T) {
+		engine := New(WithRawFunc())
+		err := engine.RegisterComponent(ArticleComponent{}, `<article>{{safe .Body}}</article>`)
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		err = engine.Render(&b, article)
+		require.NoError(t, err)
+		require.Equal(t, `<article><p>Hello <b>world</b></p></article>`, b.String())
+	})
+
+	t.Run("raw renders unescaped HTML when enabled", func(t *testing.T) {
+		engine := New(nil, WithRawFunc())
+		err := engine.RegisterComponent(ArticleComponent{}, `<article>{{raw .Body}}</article>`)
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		err = engine.Render(&b, article)
+		require.NoError(t, err)
+		require.Equal(t, `<article><p>Hello <b>world</b></p></article>`, b.String())
+	})
+
+	t.Run("raw HTML has its tags stripped in attribute values", func(t *testing.T) {
+		engine := New(nil, WithRawFunc())
+		err := engine.RegisterComponent(ArticleComponent{}, `<article title="{{raw .Body}}"></article>`)
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		err = engine.Render(&b, article)
+		require.NoError(t, err)
+		require.Equal(t, `<article title="Hello world"></article>`, b.String())
+	})
+}
+
+type HydratedComponent struct {
+	Title    string `json:"title"`
+	Count    int
+	Secret   string `json:"-"`
+	Children template.HTML
+}
+
+type CyclicProps struct {
+	Next *CyclicProps
+}
+
+type CallbackComponent struct {
+	Title    string
+	OnChange func()
+	Updates  chan string
+}
+
+type CyclicComponent struct {
+	Node *CyclicProps
+}
+
+func TestPropsJSON(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&HydratedComponent{}, `<div>{{.Children}}</div><script type="application/json">{{ propsJSON . }}</script>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&TestFSComponent{}, `<HydratedComponent title="{{.Value}}" count="{{3}}" secret="hidden">Hi</HydratedComponent>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TestFSComponent{Value: "</script><script>alert(1)</script>"})
+	require.NoError(t, err)
+
+	require.Equal(
+		t,
+		`<div>Hi</div><script type="application/json">{"title":"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e","count":3}</script>`,
+		b.String(),
+	)
+}
+
+func TestPropsJSON_SkipsFuncsAndChannels(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(CallbackComponent{}, `<script type="application/json">{{ propsJSON . }}</script>`)
+	require.NoError(t, err)
+
+	component := CallbackComponent{Title: "Hi", OnChange: func() {}, Updates: make(chan string)}
+
+	propsJSON, err := PropsJSON(component)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"title": "Hi"}`, string(propsJSON))
+
+	var b bytes.Buffer
+	props, html, err := engine.RenderWithProps(&b, component)
+	require.NoError(t, err)
+	require.Equal(t, `<script type="application/json">{"title":"Hi"}</script>`, html)
+	require.Equal(t, map[string]any{"title": "Hi"}, props)
+
+	encoded, err := json.Marshal(props)
+	require.NoError(t, err)
+	require.JSONEq(t, string(propsJSON), string(encoded))
+}
+
+func TestPropsJSON_Errors(t *testing.T) {
+	cycle := &CyclicProps{}
+	cycle.Next = cycle
+	_, err := PropsJSON(CyclicComponent{Node: cycle})
+	require.ErrorContains(t, err, "could not serialize field Node of CyclicComponent")
+
+	_, err = PropsJSON("hello")
+	require.ErrorContains(t, err, "propsJSON expects a component struct, got string")
+
+	engine := New(nil)
+	err = engine.RegisterComponent(CyclicComponent{}, `<script type="application/json">{{ propsJSON . }}</script>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, CyclicComponent{Node: cycle})
+	require.ErrorContains(t, err, "could not serialize field Node of CyclicComponent")
+}
+
+type Box struct {
+	Label    string
+	Children template.HTML
+}
+
+type BoxPage struct{}
+
+func TestRenderManySiblingAndNestedChildren(t *testing.T) {
+	var generate func(prefix string, depth int) (string, string)
+	generate = func(prefix string, depth int) (string, string) {
+		if depth == 0 {
+			return prefix, prefix
+		}
+
+		var tmpl, expected strings.Builder
+		for i := 0; i < 4; i++ {
+			label := fmt.Sprintf("%s-%d", prefix, i)
+			childTemplate, childExpected := generate(label, depth-1)
+			fmt.Fprintf(&tmpl, `<Box label="%s"><i>%s</i></Box>`, label, childTemplate)
+			fmt.Fprintf(&expected, `<div class="%s"><i>%s</i></div>`, label, childExpected)
+		}
+
+		return tmpl.String(), expected.String()
+	}
+
+	pageTemplate, expected := generate("box", 4)
+
+	engine := New(nil)
+	err := engine.RegisterComponent(&Box{}, `<div class="{{.Label}}">{{.Children}}</div>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&BoxPage{}, pageTemplate)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &BoxPage{})
+	require.NoError(t, err)
+	require.Equal(t, expected, b.String())
+}
+
+func TestRenderResponse(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&TestFSComponent{}, `<h1>Hello, {{.Value}}</h1>`)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	err = engine.RenderResponse(rec, &TestFSComponent{Value: "world"})
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Equal(t, "<h1>Hello, world</h1>", rec.Body.String())
+}
+
+func TestRenderResponse_Error(t *testing.T) {
+	engine := New(WithFuncs(FuncMap{
+		"Fail": func() (string, error) { return "", errors.New("oops") },
+	}))
+	err := engine.RegisterComponent(&TestFSComponent{}, `<h1>Hello</h1>{{ Fail }}`)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	err = engine.RenderResponse(rec, &TestFSComponent{Value: "world"})
+	require.ErrorContains(t, err, "oops")
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.NotContains(t, rec.Body.String(), "<h1>Hello</h1>")
+	require.Contains(t, rec.Body.String(), "Internal Server Error")
+}
+
+func TestRenderResponse_ErrorHandler(t *testing.T) {
+	var handled error
+	engine := New(nil, WithErrorHandler(func(w http.ResponseWriter, err error) {
+		handled = err
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("custom error"))
+	}))
+
+	rec := httptest.NewRecorder()
+	err := engine.RenderResponse(rec, &TestFSComponent{Value: "world"})
+	require.ErrorContains(t, err, "No component found for type TestFSComponent")
+	require.Equal(t, err, handled)
+
+	require.Equal(t, http.StatusTeapot, rec.Code)
+	require.Equal(t, "custom error", rec.Body.String())
+}
+
+func TestWithTemplateOption_MissingKey(t *testing.T) {
+	t.Run("missing keys render zero values by default", func(t *testing.T) {
+		engine := New(nil)
+		err := engine.RegisterComponent(MapComponent{}, `<b>{{.Map.typo}}</b>`)
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		err = engine.Render(&b, MapComponent{Map: map[string]string{"Fox": "Fox Mulder"}})
+		require.NoError(t, err)
+		require.Equal(t, "<b></b>", b.String())
+	})
+
+	t.Run("missing keys error with missingkey=error", func(t *testing.T) {
+		engine := New(nil, WithTemplateOption("missingkey=error"))
+		err := engine.RegisterComponent(MapComponent{}, `<b>{{.Map.typo}}</b>`)
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		err = engine.Render(&b, MapComponent{Map: map[string]string{"Fox": "Fox Mulder"}})
+		require.ErrorContains(t, err, `map has no entry for key "typo"`)
+	})
+
+	t.Run("missing keys in children of recompiled templates error", func(t *testing.T) {
+		engine := New(nil, WithTemplateOption("missingkey=error"))
+		err := engine.RegisterComponent(MapComponent{}, `<WrapperComponent name="{{.Map.Fox}}">{{.Map.typo}}</WrapperComponent>`)
+		require.NoError(t, err)
+		err = engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		err = engine.Render(&b, MapComponent{Map: map[string]string{"Fox": "Fox Mulder"}})
+		require.ErrorContains(t, err, `map has no entry for key "typo"`)
+	})
+
+	t.Run("omitted component attributes don't error", func(t *testing.T) {
+		engine := New(nil, WithTemplateOption("missingkey=error"))
+		err := engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+		require.NoError(t, err)
+		err = engine.RegisterComponent(&NestedComponent{}, nestedTemplate)
+		require.NoError(t, err)
+		err = engine.RegisterComponent(MapComponent{}, `<WrapperComponent name="{{.Map.Fox}}"><NestedComponent>Hi</NestedComponent></WrapperComponent><WrapperComponent />`)
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		err = engine.Render(&b, MapComponent{Map: map[string]string{"Fox": "Fox Mulder"}})
+		require.NoError(t, err)
+		require.Contains(t, b.String(), "Name: Fox Mulder")
+		require.Contains(t, b.String(), "Age: 0")
+	})
+}
+
+func TestWithTemplateOption_Invalid(t *testing.T) {
+	engine := New(nil, WithTemplateOption("missingkey=explode"))
+	err := engine.RegisterComponent(MapComponent{}, `<b>{{.Map.Fox}}</b>`)
+	require.ErrorContains(t, err, "invalid template option")
+}
+
+type lookupError struct {
+	Key string
+}
+
+func (e *lookupError) Error() string {
+	return "could not look up " + e.Key
+}
+
+var lookupFuncs = FuncMap{
+	"Lookup": func(key string) (string, error) {
+		return "", &lookupError{Key: key}
+	},
+}
+
+func TestRenderFuncErrors(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		template   string
+		key        string
+		components []string
+	}{
+		{
+			desc:     "at the root",
+			template: `<b>{{ Lookup "root" }}</b>`,
+			key:      "root",
+		},
+		{
+			desc:       "inside children",
+			template:   `<WrapperComponent>{{ Lookup "child" }}</WrapperComponent>`,
+			key:        "child",
+			components: []string{"WrapperComponent"},
+		},
+		{
+			desc:       "inside grandchildren",
+			template:   `<WrapperComponent><NestedComponent>{{ Lookup "grandchild" }}</NestedComponent></WrapperComponent>`,
+			key:        "grandchild",
+			components: []string{"WrapperComponent", "NestedComponent"},
+		},
+		{
+			desc:       "inside a nested component's template",
+			template:   `<WrapperComponent><FailingComponent /></WrapperComponent>`,
+			key:        "nested",
+			components: []string{"WrapperComponent", "FailingComponent"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New(WithFuncs(lookupFuncs))
+			err := engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&NestedComponent{}, nestedTemplate)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&FailingComponent{}, `{{ Lookup "nested" }}`)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&TestFSComponent{}, tC.template)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, &TestFSComponent{})
+			require.Error(t, err)
+
+			var lookupErr *lookupError
+			require.ErrorAs(t, err, &lookupErr)
+			require.Equal(t, tC.key, lookupErr.Key)
+
+			if len(tC.components) > 0 {
+				var componentErr *ComponentError
+				require.ErrorAs(t, err, &componentErr)
+				require.Equal(t, tC.components[0], componentErr.Component)
+			}
+
+			for _, component := range tC.components {
+				require.Contains(t, err.Error(), "error rendering component "+component)
+			}
+		})
+	}
+}
+
+type FailingComponent struct{}
+
+type SafeSidebar struct {
+	Children template.HTML
+	err      any
+}
+
+func (s *SafeSidebar) Recover(w io.Writer, err any) {
+	s.err = err
+	_, _ = w.Write([]byte("<b>Failed to load sidebar</b>"))
+}
+
+func TestRecoverableReceivesUnderlyingError(t *testing.T) {
+	engine := New(WithFuncs(lookupFuncs))
+	err := engine.RegisterComponent(&NestedComponent{}, nestedTemplate)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&SafeSidebar{}, `<aside>{{.Children}}</aside>`)
+	require.NoError(t, err)
+
+	sidebar := &SafeSidebar{Children: "Hello"}
+	var b bytes.Buffer
+	err = engine.Render(&b, sidebar)
+	require.NoError(t, err)
+	require.Equal(t, "<aside>Hello</aside>", b.String())
+	require.Nil(t, sidebar.err)
+
+	err = engine.RegisterComponent(&SafeSidebar{}, `<aside><NestedComponent>{{ Lookup "sidebar" }}</NestedComponent></aside>`)
+	require.NoError(t, err)
+
+	b.Reset()
+	err = engine.Render(&b, sidebar)
+	require.NoError(t, err)
+	require.Equal(t, "<b>Failed to load sidebar</b>", b.String())
+
+	recovered, ok := sidebar.err.(error)
+	require.True(t, ok)
+	var lookupErr *lookupError
+	require.ErrorAs(t, recovered, &lookupErr)
+	require.Equal(t, "sidebar", lookupErr.Key)
+}
+
+var csrfFuncs = FuncMap{
+	"CSRF": func() string { panic("must be overridden") },
+}
+
+func TestRenderPanicIncludesComponent(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"form.glam.html": &fstest.MapFile{Data: []byte(`<form>{{ CSRF }}</form>`)},
+	}
+
+	engine := New(WithFuncs(csrfFuncs))
+	err := engine.RegisterComponentFS(&FormComponent{}, templateFS, "form.glam.html")
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&TestFSComponent{}, `<WrapperComponent><FormComponent /></WrapperComponent>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TestFSComponent{})
+	require.ErrorContains(t, err, "panic in component FormComponent (form.glam.html): must be overridden")
+
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	require.Equal(t, "FormComponent", panicErr.Component)
+	require.Equal(t, "form.glam.html", panicErr.Filename)
+	require.Equal(t, "must be overridden", panicErr.Value)
+
+	// Funcs passed at render time are annotated too
+	err = engine.RegisterComponent(&FormComponent{}, `<form>{{ CSRF }}</form>`)
+	require.NoError(t, err)
+
+	b.Reset()
+	err = engine.RenderWithFuncs(&b, &FormComponent{}, FuncMap{
+		"CSRF": func() string { panic("no request") },
+	})
+	require.ErrorContains(t, err, "panic in component FormComponent: no request")
+
+	// Errors returned by funcs aren't panics
+	b.Reset()
+	err = engine.RenderWithFuncs(&b, &FormComponent{}, FuncMap{
+		"CSRF": func() (string, error) { return "", errors.New("no session") },
+	})
+	require.ErrorContains(t, err, "error calling CSRF: no session")
+	require.False(t, errors.As(err, &panicErr))
+
+	// Panics with an error keep the error
+	b.Reset()
+	err = engine.RenderWithFuncs(&b, &FormComponent{}, FuncMap{
+		"CSRF": func() string { panic(&lookupError{Key: "csrf"}) },
+	})
+	require.ErrorAs(t, err, &panicErr)
+	var lookupErr *lookupError
+	require.ErrorAs(t, err, &lookupErr)
+	require.Equal(t, "csrf", lookupErr.Key)
+
+	// Panics with errors.New values keep the error instead of its string
+	errNoSession := errors.New("no session")
+	b.Reset()
+	err = engine.RenderWithFuncs(&b, &FormComponent{}, FuncMap{
+		"CSRF": func() string { panic(errNoSession) },
+	})
+	require.ErrorAs(t, err, &panicErr)
+	require.Equal(t, errNoSession, panicErr.Value)
+	require.ErrorIs(t, err, errNoSession)
+}
+
+func TestRecoverableReceivesOriginalPanicValue(t *testing.T) {
+	engine := New(WithFuncs(csrfFuncs))
+	err := engine.RegisterComponent(&SafeSidebar{}, `<aside>{{ CSRF }}</aside>`)
+	require.NoError(t, err)
+
+	sidebar := &SafeSidebar{}
+	var b bytes.Buffer
+	err = engine.Render(&b, sidebar)
+	require.NoError(t, err)
+	require.Equal(t, "<b>Failed to load sidebar</b>", b.String())
+	require.Equal(t, "must be overridden", sidebar.err)
+}
+
+func TestRenderEmptyTemplates(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+	}{
+		{desc: "empty template", template: ""},
+		{desc: "whitespace-only template", template: " \n\t "},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New(nil)
+			err := engine.RegisterComponent(&NestedComponent{}, tC.template)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, &NestedComponent{})
+			require.NoError(t, err)
+			require.Equal(t, tC.template, b.String())
+
+			// Empty components can also be rendered by other components
+			err = engine.RegisterComponent(&TestFSComponent{}, `<p><NestedComponent>Hi</NestedComponent></p>`)
+			require.NoError(t, err)
+
+			b.Reset()
+			err = engine.Render(&b, &TestFSComponent{})
+			require.NoError(t, err)
+			require.Equal(t, "<p>"+tC.template+"</p>", b.String())
+		})
+	}
+}
+
+type ButtonComponent struct {
+	Type     string
+	Attrs    template.HTMLAttr `glam:"attrs"`
+	Children template.HTML
+}
+
+func TestForwardedAttributes(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&ButtonComponent{}, `<button {{.Attrs}} type="{{.Type}}">{{.Children}}</button>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&TestFSComponent{}, `<ButtonComponent type="submit" disabled id="save" data-x="{{.Value}}">Save</ButtonComponent><ButtonComponent type="button" />`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TestFSComponent{Value: `"><script>`})
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		`<button data-x="&#34;&gt;&lt;script&gt;" disabled="true" id="save" type="submit">Save</button><button  type="button"></button>`,
+		b.String(),
+	)
+}
+
+type ForwardedLink struct {
+	Attrs    template.HTMLAttr `glam:"attrs"`
+	Children template.HTML
+}
+
+type ForwardedLinkPage struct {
+	URL string
+	JS  string
+}
+
+func TestForwardedAttributes_Unsafe(t *testing.T) {
+	engine := New(nil)
+	err := engine.RegisterComponent(&ForwardedLink{}, `<a {{.Attrs}}>{{.Children}}</a>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&ForwardedLinkPage{}, `<ForwardedLink href="{{.URL}}" data-src="{{.URL}}" title="{{.URL}}">Go</ForwardedLink>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &ForwardedLinkPage{URL: "javascript:alert(1)"})
+	require.NoError(t, err)
+	require.Equal(t, `<a data-src="#ZgotmplZ" href="#ZgotmplZ" title="javascript:alert(1)">Go</a>`, b.String())
+
+	b.Reset()
+	err = engine.Render(&b, &ForwardedLinkPage{URL: "https://example.com/?a=1&b=2"})
+	require.NoError(t, err)
+	require.Equal(t, `<a data-src="https://example.com/?a=1&amp;b=2" href="https://example.com/?a=1&amp;b=2" title="https://example.com/?a=1&amp;b=2">Go</a>`, b.String())
+
+	for _, attribute := range []string{"onclick", "onMouseOver", "style", "srcdoc", "xlink:onload"} {
+		err = engine.RegisterComponent(&ForwardedLinkPage{}, fmt.Sprintf(`<ForwardedLink %s="{{.JS}}">Go</ForwardedLink>`, attribute))
+		require.NoError(t, err)
+
+		err = engine.Render(&b, &ForwardedLinkPage{JS: "alert(1)"})
+		require.ErrorContains(t, err, fmt.Sprintf("attribute %s can't be forwarded since its value would run as a script or style", strings.ToLower(attribute)))
+	}
+}
+
+func TestBooleanAttributesBeforeTagEnd(t *testing.T) {
+	engine := New(nil, WithShadowedTags("Dialog"))
+	err := engine.RegisterComponent(&ButtonComponent{}, `<button type="{{.Type}}" {{.Attrs}}>{{.Children}}</button>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&Dialog{}, `<div role="dialog">{{.Children}}</div>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&TestFSComponent{}, `<ButtonComponent type="submit" id="save" disabled>Save</ButtonComponent><ButtonComponent type="button" disabled/><Dialog>Hi</Dialog><dialog open>Native</dialog>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TestFSComponent{})
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		`<button type="submit" disabled="true" id="save">Save</button><button type="button" disabled="true"></button><div role="dialog">Hi</div><dialog open>Native</dialog>`,
+		b.String(),
+	)
+}
+
+func TestRetainSource(t *testing.T) {
+	source := `<p>{{.Value}}</p>`
+
+	engine := New(nil)
+	err := engine.RegisterComponent(&TestFSComponent{}, source)
+	require.NoError(t, err)
+	require.Panics(t, func() { engine.templateMap["TestFSComponent"].RawContent() })
+
+	engine = New(nil, RetainSource(true))
+	err = engine.RegisterComponent(&TestFSComponent{}, source)
+	require.NoError(t, err)
+	require.Equal(t, source, engine.templateMap["TestFSComponent"].RawContent())
+}
+
+func TestNewOptions(t *testing.T) {
+	upper := func(s string) string { return strings.ToUpper(s) }
+	exclaim := func(s string) string { return s + "!" }
+
+	testCases := []struct {
+		desc     string
+		opts     []Option
+		expected string
+	}{
+		{
+			desc:     "WithFuncs",
+			opts:     []Option{WithFuncs(FuncMap{"shout": upper})},
+			expected: "<p>HI</p>",
+		},
+		{
+			desc:     "later options override earlier options",
+			opts:     []Option{WithFuncs(FuncMap{"shout": upper}), WithFuncs(FuncMap{"shout": exclaim})},
+			expected: "<p>hi!</p>",
+		},
+		{
+			desc:     "html/template FuncMap",
+			opts:     []Option{WithFuncs(template.FuncMap{"shout": upper})},
+			expected: "<p>HI</p>",
+		},
+		{
+			desc:     "WithFuncs with a nil FuncMap",
+			opts:     []Option{WithFuncs(nil), WithFuncs(FuncMap{"shout": upper})},
+			expected: "<p>HI</p>",
+		},
+		{
+			desc:     "nil options are ignored",
+			opts:     []Option{nil, WithFuncs(FuncMap{"shout": upper}), nil},
+			expected: "<p>HI</p>",
+		},
+		{
+			desc:     "FuncMap passed to New for compatibility",
+			opts:     []Option{FuncMap{"shout": upper}},
+			expected: "<p>HI</p>",
+		},
+		{
+			desc:     "nil FuncMap adds no funcs",
+			opts:     []Option{FuncMap(nil), WithFuncs(FuncMap{"shout": upper})},
+			expected: "<p>HI</p>",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New(tC.opts...)
+			err := engine.RegisterComponent(&TestFSComponent{}, `<p>{{ shout .Value }}</p>`)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, &TestFSComponent{Value: "hi"})
+			require.NoError(t, err)
+			require.Equal(t, tC.expected, b.String())
+		})
+	}
+}
+
+func TestNewNil(t *testing.T) {
+	engine := New(nil)
+	require.Contains(t, engine.FuncMap(), "__glamDict")
+
+	var funcs FuncMap
+	engine = New(funcs)
+	require.Contains(t, engine.FuncMap(), "__glamDict")
+
+	err := engine.RegisterComponent(&TestFSComponent{}, `<p>{{.Value}}</p>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TestFSComponent{Value: "hi"})
+	require.NoError(t, err)
+	require.Equal(t, "<p>hi</p>", b.String())
+}
+
+func TestNewDefaults(t *testing.T) {
+	engine := New()
+	require.Contains(t, engine.FuncMap(), "__glamDict")
+	require.Contains(t, engine.FuncMap(), "classes")
+	require.NotContains(t, engine.FuncMap(), "raw")
+	require.NotContains(t, engine.FuncMap(), "safe")
+
+	err := engine.RegisterComponent(Title{}, "")
+	require.ErrorContains(t, err, "component Title conflicts with an existing HTML tag")
+
+	engine = New(WithHTMLTagConflicts(), WithRawFunc())
+	require.Contains(t, engine.FuncMap(), "raw")
+	require.Contains(t, engine.FuncMap(), "safe")
+
+	err = engine.RegisterComponent(&TestFSComponent{}, `<Title></Title>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(Title{}, "<h1>Title</h1>")
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TestFSComponent{})
+	require.NoError(t, err)
+	require.Equal(t, "<h1>Title</h1>", b.String())
+}
+
+type CardComponent struct {
+	Title    string
+	Children template.HTML
+}
+
+func (c CardComponent) Example() map[string]any {
+	return map[string]any{"title": "Example card"}
+}
+
+func TestPreview(t *testing.T) {
+	engine := New(WithPreviewChildren("Lorem ipsum"))
+	err := engine.RegisterComponent(CardComponent{}, `<div class="card"><h2>{{.Title}}</h2>{{.Children}}</div>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&ButtonComponent{}, `<button {{.Attrs}} type="{{.Type}}">{{.Children}}</button>`)
+	require.NoError(t, err)
+
+	html, err := engine.Preview("CardComponent", map[string]any{"title": "Hello"})
+	require.NoError(t, err)
+	require.Equal(t, template.HTML(`<div class="card"><h2>Hello</h2>Lorem ipsum</div>`), html)
+
+	html, err = engine.Preview("CardComponent", nil)
+	require.NoError(t, err)
+	require.Equal(t, template.HTML(`<div class="card"><h2>Example card</h2>Lorem ipsum</div>`), html)
+
+	html, err = engine.Preview("ButtonComponent", map[string]any{"type": "submit", "id": "save"})
+	require.NoError(t, err)
+	require.Equal(t, template.HTML(`<button id="save" type="submit">Lorem ipsum</button>`), html)
+
+	_, err = engine.Preview("Missing", nil)
+	require.ErrorContains(t, err, "No component found with name Missing")
+}
+
+type Profile struct {
+	AvatarURL string
+}
+
+func (p Profile) Initials() string {
+	return "BW"
+}
+
+type User struct {
+	Name    string
+	Profile Profile
+}
+
+type Avatar struct {
+	URL      string
+	Initials string
+}
+
+type ProfilePage struct {
+	User User
+}
+
+func TestBoundAttributeFieldChains(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		expected string
+	}{
+		{
+			desc:     "two level field chain",
+			template: `<Avatar url="{{.User.Name}}" />`,
+			expected: `<img src="Blake" alt="">`,
+		},
+		{
+			desc:     "three level field chain",
+			template: `<Avatar url="{{.User.Profile.AvatarURL}}" />`,
+			expected: `<img src="/avatar.png" alt="">`,
+		},
+		{
+			desc:     "field chain ending in a method call",
+			template: `<Avatar url="{{.User.Profile.AvatarURL}}" initials="{{.User.Profile.Initials}}" />`,
+			expected: `<img src="/avatar.png" alt="BW">`,
+		},
+		{
+			desc:     "field chain inside children",
+			template: `<WrapperComponent name="{{.User.Name}}"><Avatar url="{{.User.Profile.AvatarURL}}" /></WrapperComponent>`,
+			expected: "<div>\n\tName: Blake\n\tAge: 0\n\t<img src=\"/avatar.png\" alt=\"\">\n</div>\n",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New()
+			err := engine.RegisterComponent(&Avatar{}, `<img src="{{.URL}}" alt="{{.Initials}}">`)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&ProfilePage{}, tC.template)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, &ProfilePage{User: User{Name: "Blake", Profile: Profile{AvatarURL: "/avatar.png"}}})
+			require.NoError(t, err)
+			require.Equal(t, tC.expected, b.String())
+		})
+	}
+}
+
+type EchoComponent struct {
+	Value string
+}
+
+type SecretPage struct {
+	Secret string
+}
+
+func TestLiteralAttributesAreInert(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		expected string
+	}{
+		{
+			desc:     "double quotes",
+			template: `<EchoComponent value='say "hi"' />`,
+			expected: `say &#34;hi&#34;`,
+		},
+		{
+			desc:     "closing and opening actions",
+			template: `<EchoComponent value="}}{{.Secret}}" />`,
+			expected: `}}{{.Secret}}`,
+		},
+		{
+			desc:     "multiple actions",
+			template: `<EchoComponent value="{{.Secret}} and {{.Secret}}" />`,
+			expected: `{{.Secret}} and {{.Secret}}`,
+		},
+		{
+			desc:     "backslashes",
+			template: `<EchoComponent value="C:\new\" />`,
+			expected: `C:\new\`,
+		},
+		{
+			desc:     "quote breaking out of the string",
+			template: `<EchoComponent value='\" (.Secret) \"' />`,
+			expected: `\&#34; (.Secret) \&#34;`,
+		},
+		{
+			desc:     "single action is still bound",
+			template: `<EchoComponent value="{{- .Secret -}}" />`,
+			expected: `hunter2`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New()
+			err := engine.RegisterComponent(&EchoComponent{}, `{{.Value}}`)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&SecretPage{}, tC.template)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, &SecretPage{Secret: "hunter2"})
+			require.NoError(t, err)
+			require.Equal(t, tC.expected, b.String())
+		})
+	}
+}
+
+func TestRegisterComponentFSGlob(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"components/wrapper.glam.html": &fstest.MapFile{Data: []byte(wrapperTemplate)},
+		"components/page.glam.html":    &fstest.MapFile{Data: []byte(`<WrapperComponent name="{{.Value}}">Hi</WrapperComponent>`)},
+		"components/unused.glam.html":  &fstest.MapFile{Data: []byte(`<p>unused</p>`)},
+		"layout.glam.html":             &fstest.MapFile{Data: []byte(`<p>layout</p>`)},
+	}
+
+	resolved := make([]string, 0)
+	engine := New()
+	err := engine.RegisterComponentFSGlob(templateFS, "components/*.glam.html", func(filename string) (any, bool) {
+		resolved = append(resolved, filename)
+
+		switch filename {
+		case "components/wrapper.glam.html":
+			return &WrapperComponent{}, true
+		case "components/page.glam.html":
+			return &TestFSComponent{}, true
+		default:
+			return nil, false
+		}
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"components/page.glam.html", "components/unused.glam.html", "components/wrapper.glam.html"}, resolved)
+	require.Len(t, engine.KnownComponents(), 2)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TestFSComponent{Value: "Fox Mulder"})
+	require.NoError(t, err)
+	require.Equal(t, "<div>\n\tName: Fox Mulder\n\tAge: 0\n\tHi\n</div>\n", b.String())
+
+	err = engine.RegisterComponentFSGlob(templateFS, "[", func(string) (any, bool) { return nil, false })
+	require.ErrorContains(t, err, "could not glob files")
+}
+
+type ArticleBody struct {
+	HTML     string
+	Children template.HTML
+}
+
+func (ArticleBody) Trusted() {}
+
+func TestTrustedComponents(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&ArticleBody{}, `<article>{{.HTML}}{{.Children}}</article>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&TestFSComponent{}, `<p>{{.Value}}</p><ArticleBody html="{{.Value}}"><em>Hi</em></ArticleBody>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &TestFSComponent{Value: "<strong>Sanitized</strong>"})
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		`<p>&lt;strong&gt;Sanitized&lt;/strong&gt;</p><article><strong>Sanitized</strong><em>Hi</em></article>`,
+		b.String(),
+	)
+}
+
+func TestAppendRender(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&TestFSComponent{}, `<WrapperComponent name="{{.Value}}">Hi</WrapperComponent>`)
+	require.NoError(t, err)
+	require.Equal(t, 0, engine.SizeHint(&TestFSComponent{}))
+
+	expected := "<div>\n\tName: Fox Mulder\n\tAge: 0\n\tHi\n</div>\n"
+
+	dst := []byte("prefix:")
+	dst, err = engine.AppendRender(dst, &TestFSComponent{Value: "Fox Mulder"})
+	require.NoError(t, err)
+	require.Equal(t, "prefix:"+expected, string(dst))
+	require.Equal(t, len(expected), engine.SizeHint(&TestFSComponent{}))
+
+	dst, err = engine.AppendRender(dst[:0], &TestFSComponent{Value: "Fox Mulder"})
+	require.NoError(t, err)
+	require.Equal(t, expected, string(dst))
+
+	dst, err = engine.AppendRender(dst, &NestedComponent{})
+	require.ErrorContains(t, err, "No component found for type NestedComponent")
+	require.Equal(t, expected, string(dst))
+}
+
+type BenchmarkRow struct {
+	Name  string
+	Email string
+}
+
+type BenchmarkTable struct {
+	Rows []BenchmarkRow
+}
+
+func newBenchmarkEngine(b *testing.B) *Engine {
+	engine := New()
+	err := engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+	require.NoError(b, err)
+	err = engine.RegisterComponent(&BenchmarkTable{}, `<WrapperComponent name="Users" /><table>{{range .Rows}}<tr><td>{{.Name}}</td><td>{{.Email}}</td></tr>{{end}}</table>`)
+	require.NoError(b, err)
+
+	return engine
+}
+
+func benchmarkTable() *BenchmarkTable {
+	rows := make([]BenchmarkRow, 50)
+	for i := range rows {
+		rows[i] = BenchmarkRow{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i)}
+	}
+
+	return &BenchmarkTable{Rows: rows}
+}
+
+func BenchmarkRenderBuffer(b *testing.B) {
+	engine := newBenchmarkEngine(b)
+	table := benchmarkTable()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		err := engine.Render(&buf, table)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppendRender(b *testing.B) {
+	engine := newBenchmarkEngine(b)
+	table := benchmarkTable()
+
+	var dst []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		dst, err = engine.AppendRender(dst[:0], table)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type StaticBadge struct {
+	Label string
+	Tone  string
+}
+
+func BenchmarkStaticLeafComponents(b *testing.B) {
+	engine := New()
+	err := engine.RegisterComponent(&StaticBadge{}, `<span class="badge badge-{{.Tone}}">{{.Label}}</span>`)
+	require.NoError(b, err)
+	err = engine.RegisterComponent(&TestFSComponent{}, strings.Repeat(`<StaticBadge label="New" tone="info" /><StaticBadge label="Beta" tone="warning" />`, 50))
+	require.NoError(b, err)
+
+	var dst []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dst, err = engine.AppendRender(dst[:0], &TestFSComponent{})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type BannerComponent struct {
+	Message  string
+	Children template.HTML
+}
+
+type BannerPage struct {
+	ShowBanner bool
+	Message    string
+	Items      []string
+}
+
+type ConditionalComponent struct {
+	If bool
+}
+
+func TestIfAttribute(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		page     BannerPage
+		expected string
+	}{
+		{
+			desc:     "renders when true",
+			template: `<BannerComponent if="{{.ShowBanner}}" message="{{.Message}}" />`,
+			page:     BannerPage{ShowBanner: true, Message: "Hello"},
+			expected: `<p>Hello</p>`,
+		},
+		{
+			desc:     "skipped when false",
+			template: `<BannerComponent if="{{.ShowBanner}}" message="{{.Message}}" />`,
+			page:     BannerPage{Message: "Hello"},
+			expected: ``,
+		},
+		{
+			desc:     "with children",
+			template: `<BannerComponent if="{{ .ShowBanner }}" message="Hi"><b>{{.Message}}</b></BannerComponent>`,
+			page:     BannerPage{ShowBanner: true, Message: "Hello"},
+			expected: `<p>Hi</p><b>Hello</b>`,
+		},
+		{
+			desc:     "pipelines",
+			template: `<BannerComponent if="{{ and .ShowBanner (eq .Message "Hello") }}" message="{{.Message}}" />`,
+			page:     BannerPage{ShowBanner: true, Message: "Goodbye"},
+			expected: ``,
+		},
+		{
+			desc:     "inside range",
+			template: `{{range .Items}}<BannerComponent if="{{ ne . "skip" }}" message="{{.}}" />{{end}}`,
+			page:     BannerPage{Items: []string{"one", "skip", "two"}},
+			expected: `<p>one</p><p>two</p>`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New()
+			err := engine.RegisterComponent(&BannerComponent{}, `<p>{{.Message}}</p>{{.Children}}`)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&BannerPage{}, tC.template)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, &tC.page)
+			require.NoError(t, err)
+			require.Equal(t, tC.expected, b.String())
+		})
+	}
+}
+
+func TestIfAttributeErrors(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&ConditionalComponent{}, `{{.If}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&BannerComponent{}, `{{.Message}}`)
+	require.NoError(t, err)
+
+	err = engine.RegisterComponent(&BannerPage{}, `<ConditionalComponent if="{{.ShowBanner}}" />`)
+	require.ErrorContains(t, err, "1:1: component ConditionalComponent can't use the reserved if attribute since it has a field named if")
+
+	err = engine.RegisterComponent(&BannerPage{}, `<BannerComponent if="yes" />`)
+	require.ErrorContains(t, err, `1:1: if attribute on component BannerComponent must be a single action, got "yes"`)
+}
+
+type RangeRow struct {
+	Name  string
+	Cells []string
+}
+
+type RangePage struct {
+	Rows []RangeRow
+}
+
+type RowComponent struct {
+	Name     string
+	Children template.HTML
+}
+
+type CellComponent struct {
+	Value string
+}
+
+type RangedComponent struct {
+	Range []string
+}
+
+func TestRangeAttribute(t *testing.T) {
+	rows := []RangeRow{
+		{Name: "one", Cells: []string{"a", "b"}},
+		{Name: "skip", Cells: []string{"c"}},
+		{Name: "two", Cells: []string{"d"}},
+	}
+
+	testCases := []struct {
+		desc     string
+		template string
+		expected string
+	}{
+		{
+			desc:     "renders once per element",
+			template: `<RowComponent range="{{.Rows}}" name="{{.Name}}" />`,
+			expected: `<tr>one</tr><tr>skip</tr><tr>two</tr>`,
+		},
+		{
+			desc:     "element is dot in children",
+			template: `<RowComponent range="{{.Rows}}" name="{{.Name}}"><td>{{len .Cells}}</td></RowComponent>`,
+			expected: `<tr>one<td>2</td></tr><tr>skip<td>1</td></tr><tr>two<td>1</td></tr>`,
+		},
+		{
+			desc:     "if is evaluated per element",
+			template: `<RowComponent range="{{.Rows}}" if="{{ne .Name "skip"}}" name="{{.Name}}" />`,
+			expected: `<tr>one</tr><tr>two</tr>`,
+		},
+		{
+			desc:     "nested ranges",
+			template: `<RowComponent range="{{.Rows}}" name="{{.Name}}"><CellComponent range="{{.Cells}}" value="{{.}}" /></RowComponent>`,
+			expected: `<tr>one<td>a</td><td>b</td></tr><tr>skip<td>c</td></tr><tr>two<td>d</td></tr>`,
+		},
+		{
+			desc:     "nested ranges with if",
+			template: `<RowComponent range="{{.Rows}}" name="{{.Name}}"><CellComponent range="{{.Cells}}" if="{{ne . "b"}}" value="{{.}}" /></RowComponent>`,
+			expected: `<tr>one<td>a</td></tr><tr>skip<td>c</td></tr><tr>two<td>d</td></tr>`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New()
+			err := engine.RegisterComponent(&RowComponent{}, `<tr>{{.Name}}{{.Children}}</tr>`)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&CellComponent{}, `<td>{{.Value}}</td>`)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&RangePage{}, tC.template)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, &RangePage{Rows: rows})
+			require.NoError(t, err)
+			require.Equal(t, tC.expected, b.String())
+		})
+	}
+}
+
+func TestRangeAttributeErrors(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&RangedComponent{}, `{{.Range}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&CellComponent{}, `{{.Value}}`)
+	require.NoError(t, err)
+
+	err = engine.RegisterComponent(&RangePage{}, `<RangedComponent range="{{.Rows}}" />`)
+	require.ErrorContains(t, err, "component RangedComponent can't use the reserved range attribute since it has a field named range")
+
+	err = engine.RegisterComponent(&RangePage{}, `<p><CellComponent range="rows" /></p>`)
+	require.ErrorContains(t, err, `1:4: range attribute on component CellComponent must be a single action, got "rows"`)
+}
+
+func TestRenderByName(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&GreetingPage{}, `<WrapperComponent name="{{.Name}}">Hi</WrapperComponent>`)
+	require.NoError(t, err)
+
+	expected := "<div>\n\tName: Fox Mulder\n\tAge: 0\n\tHi\n</div>\n"
+
+	var b bytes.Buffer
+	err = engine.RenderByName(&b, "GreetingPage", &GreetingPage{Name: "Fox Mulder"})
+	require.NoError(t, err)
+	require.Equal(t, expected, b.String())
+
+	b.Reset()
+	err = engine.RenderByName(&b, "GreetingPage", map[string]any{"Name": "Fox Mulder"})
+	require.NoError(t, err)
+	require.Equal(t, expected, b.String())
+
+	err = engine.RenderByName(&b, "MissingComponent", nil)
+	require.ErrorContains(t, err, "No component found with name MissingComponent")
+}
+
+type WelcomeEmail struct {
+	Name string
+}
+
+type EmailButton struct {
+	URL      string `attr:"url"`
+	Children template.HTML
+}
+
+type EmailFooter struct{}
+
+func TestRenderMulti(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&EmailButton{}, `<a href="{{.URL}}">{{.Children}}</a>{{target "text"}}{{.Children}}: {{.URL}}{{end}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&EmailFooter{}, `Unsubscribe`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(
+		&WelcomeEmail{},
+		`{{target "subject"}}Welcome, {{.Name}}{{end}}<p>Hi {{.Name}}</p><EmailButton url="/start">Get started</EmailButton>`+
+			`{{target "text"}}{{if .Name}}Hi {{.Name}}{{end}}{{"\n"}}{{end}}<EmailFooter></EmailFooter>`+
+			`{{- target "text" }}{{"\n"}}<EmailFooter></EmailFooter>{{ end -}}`,
+	)
+	require.NoError(t, err)
+
+	var html, text, subject bytes.Buffer
+	err = engine.RenderMulti(map[string]io.Writer{
+		"html":    &html,
+		"text":    &text,
+		"subject": &subject,
+	}, &WelcomeEmail{Name: "Fox"})
+	require.NoError(t, err)
+
+	require.Equal(t, `<p>Hi Fox</p><a href="/start">Get started</a>Unsubscribe`, html.String())
+	require.Equal(t, "Get started: /startHi Fox\n\nUnsubscribe", text.String())
+	require.Equal(t, "Welcome, Fox", subject.String())
+
+	// Render writes targeted content like any other content
+	var b bytes.Buffer
+	err = engine.Render(&b, &EmailFooter{})
+	require.NoError(t, err)
+	require.Equal(t, "Unsubscribe", b.String())
+
+	b.Reset()
+	err = engine.Render(&b, &EmailButton{URL: "/start", Children: "Go"})
+	require.NoError(t, err)
+	require.Equal(t, `<a href="/start">Go</a>Go: /start`, b.String())
+
+	err = engine.RenderMulti(map[string]io.Writer{"html": &html}, &WelcomeEmail{Name: "Fox"})
+	require.ErrorContains(t, err, `no writer provided for target "subject"`)
+
+	err = engine.RenderMulti(map[string]io.Writer{"text": &text}, &WelcomeEmail{Name: "Fox"})
+	require.ErrorContains(t, err, `no writer provided for the "html" target`)
+}
+
+type IconProps struct {
+	Name  string
+	Label string `attr:"aria-label"`
+}
+
+type IconPage struct {
+	Icon string
+}
+
+func TestRegisterFunc(t *testing.T) {
+	engine := New()
+
+	// Register the page first so it's recompiled when the functions are
+	// registered
+	err := engine.RegisterComponent(
+		&IconPage{},
+		`<Icon name="{{.Icon}}" aria-label="Save"></Icon> <Badge kind="new" count="{{3}}">Hi</Badge>`,
+	)
+	require.NoError(t, err)
+
+	err = engine.RegisterFunc("Icon", func(props IconProps, children template.HTML) (template.HTML, error) {
+		return template.HTML(fmt.Sprintf(`<svg aria-label="%s"><use href="#%s"></use></svg>`, props.Label, props.Name)), nil
+	})
+	require.NoError(t, err)
+
+	err = engine.RegisterFunc("Badge", func(attrs map[string]any, children template.HTML) (template.HTML, error) {
+		if attrs["kind"] == "error" {
+			return "", errors.New("unsupported kind")
+		}
+
+		return template.HTML(fmt.Sprintf(`<span class="%s">%s %d</span>`, attrs["kind"], children, attrs["count"])), nil
+	})
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &IconPage{Icon: "floppy"})
+	require.NoError(t, err)
+	require.Equal(t, `<svg aria-label="Save"><use href="#floppy"></use></svg> <span class="new">Hi 3</span>`, b.String())
+
+	preview, err := engine.Preview("Badge", map[string]any{"kind": "beta", "count": 1})
+	require.NoError(t, err)
+	require.Equal(t, template.HTML(`<span class="beta"> 1</span>`), preview)
+
+	err = engine.RegisterComponent(&GreetingPage{}, `<Badge kind="error">Oops</Badge>`)
+	require.NoError(t, err)
+
+	err = engine.Render(&b, &GreetingPage{})
+	require.ErrorContains(t, err, "error rendering component Badge: unsupported kind")
+
+	var componentErr *ComponentError
+	require.ErrorAs(t, err, &componentErr)
+	require.Equal(t, "Badge", componentErr.Component)
+}
+
+func TestRegisterFuncErrors(t *testing.T) {
+	engine := New()
+
+	err := engine.RegisterFunc("icon", func(IconProps, template.HTML) (template.HTML, error) { return "", nil })
+	require.ErrorContains(t, err, `function component "icon" must be capitalized`)
+
+	err = engine.RegisterFunc("Icon", "<svg></svg>")
+	require.ErrorContains(t, err, "function component Icon must be a function, got string")
+
+	err = engine.RegisterFunc("Icon", func(IconProps) (template.HTML, error) { return "", nil })
+	require.ErrorContains(t, err, "function component Icon must have the signature func(props, template.HTML) (template.HTML, error)")
+
+	err = engine.RegisterFunc("Icon", func(IconProps, template.HTML) string { return "" })
+	require.ErrorContains(t, err, "function component Icon must have the signature")
+
+	err = engine.RegisterFunc("Icon", func(string, template.HTML) (template.HTML, error) { return "", nil })
+	require.ErrorContains(t, err, "props of function component Icon must be a struct, a pointer to a struct, or map[string]any, got string")
+
+	err = engine.RegisterFunc("Button", func(IconProps, template.HTML) (template.HTML, error) { return "", nil })
+	require.ErrorContains(t, err, "component Button conflicts with an existing HTML tag")
+}
+
+type StringChildrenComponent struct {
+	Children string
+}
+
+type ChildlessComponent struct {
+	Title string
+}
+
+type ChildlessPage struct{}
+
+func TestChildrenFieldType(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&StringChildrenComponent{}, `<div>{{.Children}}</div>`)
+	require.ErrorContains(t, err, "component StringChildrenComponent must declare Children as template.HTML, got string")
+
+	err = engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate)
+	require.NoError(t, err)
+
+	// Child content is ignored by components without a Children field
+	err = engine.RegisterComponent(&ChildlessComponent{}, `<h1>{{.Title}}</h1>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&ChildlessPage{}, `<ChildlessComponent title="Hi"><p>Ignored</p></ChildlessComponent>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &ChildlessPage{})
+	require.NoError(t, err)
+	require.Equal(t, "<h1>Hi</h1>", b.String())
+}
+
+type Block interface {
+	Kind() string
+}
+
+type TextBlock struct {
+	Text string
+}
+
+func (TextBlock) Kind() string { return "text" }
+
+type ImageBlock struct {
+	Src string
+}
+
+func (*ImageBlock) Kind() string { return "image" }
+
+type QuoteBlock struct{}
+
+func (QuoteBlock) Kind() string { return "quote" }
+
+type BlockPage struct {
+	Blocks []Block
+	Hero   Block
+}
+
+func TestRenderConcreteType(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(TextBlock{}, `<p>{{.Text}}</p>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&ImageBlock{}, `<img src="{{.Src}}">`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&BlockPage{}, `{{render .Hero}}{{range .Blocks}}{{render .}}{{end}}`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &BlockPage{
+		Hero:   &ImageBlock{Src: "/hero.png"},
+		Blocks: []Block{TextBlock{Text: "One"}, &TextBlock{Text: "Two"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, `<img src="/hero.png"><p>One</p><p>Two</p>`, b.String())
+
+	// Pointers to pointers and interfaces are unwrapped
+	var block Block = &ImageBlock{Src: "/a.png"}
+	image := &ImageBlock{Src: "/b.png"}
+
+	b.Reset()
+	err = engine.Render(&b, &block)
+	require.NoError(t, err)
+	err = engine.Render(&b, &image)
+	require.NoError(t, err)
+	require.Equal(t, `<img src="/a.png"><img src="/b.png">`, b.String())
+
+	// Errors describe the concrete type that isn't registered
+	err = engine.Render(&b, &BlockPage{Hero: QuoteBlock{}})
+	require.ErrorContains(t, err, "No component found for type QuoteBlock (github.com/blakewilliams/glam.QuoteBlock)")
+
+	err = engine.Render(&b, nil)
+	require.ErrorContains(t, err, "cannot render a nil component")
+
+	var nilImage *ImageBlock
+	err = engine.Render(&b, nilImage)
+	require.ErrorContains(t, err, "cannot render a nil *glam.ImageBlock")
+}
+
+type LabelComponent struct {
+	Label string
+	Count int
+}
+
+type LabelPage struct{}
+
+func TestPureFuncs(t *testing.T) {
+	pureCalls := 0
+	impureCalls := 0
+
+	engine := New(
+		WithPureFuncs(FuncMap{
+			"pluralize": func(word string, count int) string {
+				pureCalls++
+				return fmt.Sprintf("%d %ss", count, word)
+			},
+		}),
+		WithFuncs(FuncMap{
+			"nonce": func() string {
+				impureCalls++
+				return fmt.Sprintf("nonce-%d", impureCalls)
+			},
+		}),
+	)
+	err := engine.RegisterComponent(&LabelComponent{}, `{{.Label}} ({{.Count}})`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&LabelPage{}, `<LabelComponent label="{{ pluralize "item" 5 }}" count="{{5}}" /> <LabelComponent label="{{ nonce }}" />`)
+	require.NoError(t, err)
+	require.Equal(t, 1, pureCalls)
+
+	for i := 1; i <= 2; i++ {
+		var b bytes.Buffer
+		err = engine.Render(&b, &LabelPage{})
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("5 items (5) nonce-%d (0)", i), b.String())
+	}
+
+	require.Equal(t, 1, pureCalls)
+	require.Equal(t, 2, impureCalls)
+
+	// Replacing a pure func using WithFuncs makes it impure
+	engine = New(
+		WithPureFuncs(FuncMap{"nonce": func() string { return "pure" }}),
+		WithFuncs(FuncMap{"nonce": func() string { return "impure" }}),
+	)
+	err = engine.RegisterComponent(&LabelComponent{}, `{{.Label}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&LabelPage{}, `<LabelComponent label="{{ nonce }}" />`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.RenderWithFuncs(&b, &LabelPage{}, FuncMap{"nonce": func() string { return "request" }})
+	require.NoError(t, err)
+	require.Equal(t, "request", b.String())
+}
+
+type OptionalAttributesPage struct {
+	Alt   string
+	Title string
+	Type  string
+}
+
+func TestOptionalAttributes(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&ButtonComponent{}, `<button type="{{.Type}}" {{.Attrs}}>{{.Children}}</button>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(
+		&OptionalAttributesPage{},
+		`<img src="/a.png" alt?="{{.Alt}}" title?='{{ .Title }}'>`+
+			`<ButtonComponent type?="{{.Type}}" title?="{{.Title}}" data-alt?="{{.Alt}}">Go</ButtonComponent>`,
+	)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc     string
+		page     *OptionalAttributesPage
+		expected string
+	}{
+		{
+			desc:     "empty values are omitted",
+			page:     &OptionalAttributesPage{},
+			expected: `<img src="/a.png"  ><button type="" >Go</button>`,
+		},
+		{
+			desc:     "values are rendered",
+			page:     &OptionalAttributesPage{Alt: `A "cat"`, Title: "Cat", Type: "submit"},
+			expected: `<img src="/a.png" alt="A &#34;cat&#34;" title='Cat'><button type="submit" data-alt="A &#34;cat&#34;" title="Cat">Go</button>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var b bytes.Buffer
+			err := engine.Render(&b, tc.page)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, b.String())
+		})
+	}
+}
+
+func TestOptionalAttributeErrors(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&ButtonComponent{}, `<button {{.Attrs}}>{{.Children}}</button>`)
+	require.NoError(t, err)
+
+	err = engine.RegisterComponent(&OptionalAttributesPage{}, `<img alt?="a {{.Alt}}">`)
+	require.ErrorContains(t, err, `1:6: optional attribute alt? must be a single action, got "a {{.Alt}}"`)
+
+	err = engine.RegisterComponent(&OptionalAttributesPage{}, `<ButtonComponent title?="Hi">Go</ButtonComponent>`)
+	require.ErrorContains(t, err, `optional attribute title? on component ButtonComponent must be a single action, got "Hi"`)
+}
+
+type OldButton struct {
+	Label string
+}
+
+type DeprecationPage struct{}
+
+func TestRegisterComponentDeprecated(t *testing.T) {
+	var deprecations []Deprecation
+	engine := New(WithDeprecationHandler(func(d Deprecation) {
+		deprecations = append(deprecations, d)
+	}))
+
+	err := engine.RegisterComponentDeprecated(&OldButton{}, `<button>{{.Label}}</button>`, "use ButtonComponent instead")
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&DeprecationPage{}, `<OldButton label="One" /><OldButton label="Two" />`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &DeprecationPage{})
+	require.NoError(t, err)
+	require.Equal(t, "<button>One</button><button>Two</button>", b.String())
+
+	expected := Deprecation{Component: "OldButton", Message: "use ButtonComponent instead", ReferencedBy: "DeprecationPage"}
+	require.Equal(t, []Deprecation{expected, expected}, deprecations)
+
+	// Rendering the deprecated component directly isn't reported
+	deprecations = nil
+	err = engine.Render(&b, &OldButton{Label: "Three"})
+	require.NoError(t, err)
+	require.Empty(t, deprecations)
+
+	// Registering the component again removes the deprecation
+	err = engine.RegisterComponent(&OldButton{}, `<button>{{.Label}}</button>`)
+	require.NoError(t, err)
+	err = engine.Render(&b, &DeprecationPage{})
+	require.NoError(t, err)
+	require.Empty(t, deprecations)
+
+	// Deprecated components render without a handler
+	engine = New()
+	err = engine.RegisterComponentDeprecated(&OldButton{}, `<button>{{.Label}}</button>`, "use ButtonComponent instead")
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&DeprecationPage{}, `<OldButton label="One" />`)
+	require.NoError(t, err)
+
+	b.Reset()
+	err = engine.Render(&b, &DeprecationPage{})
+	require.NoError(t, err)
+	require.Equal(t, "<button>One</button>", b.String())
+}
+
+type ConcurrentItem struct {
+	Name     string
+	Index    int
+	Attrs    template.HTMLAttr `glam:"attrs"`
+	Children template.HTML
+}
+
+type ConcurrentPage struct {
+	User  string
+	Items []string
+}
+
+func TestConcurrentRender(t *testing.T) {
+	engine := New(WithFuncs(FuncMap{"requestID": func() string { return "none" }}))
+	err := engine.RegisterComponent(&ConcurrentItem{}, `<li {{.Attrs}}>{{.Name}}: {{.Children}}</li>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(
+		&ConcurrentPage{},
+		`<h1>{{.User}} {{requestID}}</h1><ul>`+
+			`<ConcurrentItem range="{{.Items}}" name="{{.}}" data-static="1"><b>{{.}}</b></ConcurrentItem>`+
+			`<ConcurrentItem name="static" /></ul>`,
+	)
+	require.NoError(t, err)
+
+	const goroutines = 32
+	const renders = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			page := &ConcurrentPage{
+				User:  fmt.Sprintf("user-%d", i),
+				Items: []string{fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i)},
+			}
+			list := fmt.Sprintf(`<ul><li data-static="1">a%[1]d: <b>a%[1]d</b></li><li data-static="1">b%[1]d: <b>b%[1]d</b></li><li >static: </li></ul>`, i)
+
+			for j := 0; j < renders; j++ {
+				var b bytes.Buffer
+				var err error
+				var expected string
+
+				// Alternate between the shared template and per render funcs,
+				// which execute a clone
+				if j%2 == 0 {
+					err = engine.RenderWithFuncs(&b, page, FuncMap{
+						"requestID": func() string { return fmt.Sprintf("request-%d", i) },
+					})
+					expected = fmt.Sprintf("<h1>user-%[1]d request-%[1]d</h1>", i) + list
+				} else {
+					err = engine.Render(&b, page)
+					expected = fmt.Sprintf("<h1>user-%d none</h1>", i) + list
+				}
+
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if b.String() != expected {
+					errs <- fmt.Errorf("goroutine %d rendered %q, expected %q", i, b.String(), expected)
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+type PartialProfile struct {
+	Name string
+}
+
+type PartialCard struct {
+	Name string
+}
+
+type PartialBadge struct {
+	Label    string
+	Children template.HTML
+}
+
+func TestRegisterPartials(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&PartialProfile{}, `<section><PartialBadge label="own">me</PartialBadge>{{template "avatar" .}}</section>`)
+	require.NoError(t, err)
+
+	err = engine.RegisterPartials(`
+{{define "avatar"}}<img alt="{{.Name}}"> <PartialBadge label="{{.Name}}">new</PartialBadge>{{end}}
+{{/* Partials can reference other partials */}}
+{{define "title"}}<h2>{{template "avatar" .}}</h2>{{end}}
+`)
+	require.NoError(t, err)
+
+	err = engine.RegisterComponent(&PartialCard{}, `<div>{{template "title" .}}</div>`)
+	require.NoError(t, err)
+
+	// PartialBadge is rendered as raw HTML until it's registered
+	var b strings.Builder
+	err = engine.Render(&b, &PartialCard{Name: "Fox"})
+	require.NoError(t, err)
+	require.Equal(t, `<div><h2><img alt="Fox"> <PartialBadge label="Fox">new</PartialBadge></h2></div>`, b.String())
+
+	err = engine.RegisterComponent(&PartialBadge{}, `<span title="{{.Label}}">{{.Children}}</span>`)
+	require.NoError(t, err)
+
+	b.Reset()
+	err = engine.Render(&b, &PartialProfile{Name: "<Fox>"})
+	require.NoError(t, err)
+	require.Equal(t, `<section><span title="own">me</span><img alt="&lt;Fox&gt;"> <span title="&lt;Fox&gt;">new</span></section>`, b.String())
+
+	b.Reset()
+	err = engine.Render(&b, &PartialCard{Name: "Fox"})
+	require.NoError(t, err)
+	require.Equal(t, `<div><h2><img alt="Fox"> <span title="Fox">new</span></h2></div>`, b.String())
+
+	// Partials can be replaced
+	err = engine.RegisterPartials(`{{define "avatar"}}<i>{{.Name}}</i>{{end}}`)
+	require.NoError(t, err)
+
+	b.Reset()
+	err = engine.Render(&b, &PartialCard{Name: "Fox"})
+	require.NoError(t, err)
+	require.Equal(t, `<div><h2><i>Fox</i></h2></div>`, b.String())
+}
+
+func TestRegisterPartials_Invalid(t *testing.T) {
+	engine := New()
+
+	err := engine.RegisterPartials(`{{define "avatar"}}<img>{{end}}<p>Hi</p>`)
+	require.EqualError(t, err, `partials can only contain {{define}} blocks, got "<p>Hi</p>"`)
+
+	err = engine.RegisterPartials(`{{define "avatar"}}<img>`)
+	require.ErrorContains(t, err, "could not parse partials:")
+
+	err = engine.RegisterPartials(`{{.Name}}`)
+	require.EqualError(t, err, `partials can only contain {{define}} blocks, got "{{.Name}}"`)
+}
+
+type SpacedBox struct {
+	Title   string
+	Padding int
+	Attrs   template.HTMLAttr `glam:"attrs"`
+}
+
+type SpacedPage struct {
+	Title string
+}
+
+func TestWithAttributeTransformer(t *testing.T) {
+	var calls []string
+	engine := New(WithAttributeTransformer(func(component string, field string, value any) (any, error) {
+		calls = append(calls, component+"."+field)
+
+		switch {
+		case field == "Padding" && value == "space-4":
+			return 16, nil
+		case field == "Padding":
+			return nil, fmt.Errorf("unknown spacing token %q", value)
+		}
+
+		if s, ok := value.(string); ok {
+			return strings.TrimSpace(s), nil
+		}
+
+		return value, nil
+	}))
+
+	err := engine.RegisterComponent(&SpacedBox{}, `<div style="padding: {{.Padding}}px" {{.Attrs}}>{{.Title}}</div>`)
+	require.NoError(t, err)
+
+	err = engine.RegisterFunc("SpacedLabel", func(props map[string]any, _ template.HTML) (template.HTML, error) {
+		return template.HTML(fmt.Sprintf("<label>%s</label>", props["text"])), nil
+	})
+	require.NoError(t, err)
+
+	err = engine.RegisterComponent(&SpacedPage{}, `<SpacedBox title="{{printf "  %s " .Title}}" padding="space-4" id=" box "></SpacedBox><SpacedLabel text=" hi "></SpacedLabel>`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &SpacedPage{Title: "Hello"})
+	require.NoError(t, err)
+	require.Equal(t, `<div style="padding: 16px" id="box">Hello</div><label>hi</label>`, b.String())
+
+	slices.Sort(calls)
+	require.Equal(t, []string{"SpacedBox.Padding", "SpacedBox.Title", "SpacedBox.id", "SpacedLabel.text"}, calls)
+
+	err = engine.RegisterComponent(&SpacedPage{}, `<SpacedBox title="{{.Title}}" padding="space-100"></SpacedBox>`)
+	require.NoError(t, err)
+
+	err = engine.Render(&b, &SpacedPage{Title: "Hello"})
+	require.ErrorContains(t, err, `error rendering component SpacedBox: could not transform attribute padding: unknown spacing token "space-100"`)
+}
+
+type ChartCard struct {
+	Children template.HTML
+}
+
+type Use struct{}
+
+type ChartPage struct {
+	Points string
+}
+
+func TestForeignContentInChildren(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&ChartCard{}, `<div class="card">{{.Children}}</div>`)
+	require.NoError(t, err)
+
+	err = engine.RegisterComponent(&Use{}, `<b>use</b>`)
+	require.NoError(t, err)
+
+	err = engine.RegisterComponent(&ChartPage{}, `<ChartCard>Chart:<Use /><svg viewBox="0 0 10 10"><Use href="#dot"/><use href="#dot"/><polyline points="{{.Points}}"/></svg></ChartCard>`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &ChartPage{Points: "0,0 10,10"})
+	require.NoError(t, err)
+	require.Equal(t, `<div class="card">Chart:<b>use</b><svg viewBox="0 0 10 10"><Use href="#dot"/><use href="#dot"/><polyline points="0,0 10,10"/></svg></div>`, b.String())
+}
+
+type CounterButton struct {
+	Count int
+	Label string
+}
+
+type CounterPage struct {
+	Total any
+}
+
+func TestUnassignableAttributes(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"page.glam.html": {Data: []byte(`<CounterButton count="{{.Total}}" label="Clicks"></CounterButton>`)},
+	}
+
+	engine := New()
+	err := engine.RegisterComponent(&CounterButton{}, `<button>{{.Label}}: {{.Count}}</button>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponentFS(&CounterPage{}, templateFS, "page.glam.html")
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &CounterPage{Total: 3})
+	require.NoError(t, err)
+	require.Equal(t, "<button>Clicks: 3</button>", b.String())
+
+	// nil values leave the field unset
+	b.Reset()
+	err = engine.Render(&b, &CounterPage{})
+	require.NoError(t, err)
+	require.Equal(t, "<button>Clicks: 0</button>", b.String())
+
+	err = engine.Render(&b, &CounterPage{Total: struct{ N int }{3}})
+	require.ErrorContains(t, err, `error rendering component CounterButton: cannot assign value of type struct { N int } to field CounterButton.Count (int), attribute "count" in template page.glam.html`)
+
+	var assignmentErr *AssignmentError
+	require.ErrorAs(t, err, &assignmentErr)
+	require.Equal(t, "Count", assignmentErr.Field.Name)
+	require.Equal(t, "CounterPage", assignmentErr.Template)
+}
+
+func TestWithLenientAssignment(t *testing.T) {
+	var warnings []string
+	engine := New(WithLenientAssignment(), WithWarningHandler(func(w Warning) {
+		warnings = append(warnings, w.String())
+	}))
+
+	err := engine.RegisterComponent(&CounterButton{}, `<button>{{.Label}}: {{.Count}}</button>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&CounterPage{}, `<CounterButton count="{{.Total}}" label="Clicks"></CounterButton>`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &CounterPage{Total: "3"})
+	require.NoError(t, err)
+	require.Equal(t, "<button>Clicks: 0</button>", b.String())
+	require.Equal(t, []string{
+		`CounterPage: cannot assign value of type string to field CounterButton.Count (int), attribute "count" in template CounterPage, skipping it`,
+	}, warnings)
+}
+
+type ModalComponent struct {
+	Body  template.HTML
+	Style template.CSS
+}
+
+type ModalPage struct {
+	Markdown any
+}
+
+func TestTypedStringAttributes(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&ModalComponent{}, `<dialog style="{{.Style}}">{{.Body}}</dialog>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&ModalPage{}, `<ModalComponent Body="{{.Markdown}}" Style="color: red"></ModalComponent>`)
+	require.NoError(t, err)
+
+	// template.HTML expressions
+	var b strings.Builder
+	err = engine.Render(&b, &ModalPage{Markdown: template.HTML("<p>Hello</p>")})
+	require.NoError(t, err)
+	require.Equal(t, `<dialog style="color: red"><p>Hello</p></dialog>`, b.String())
+
+	// string expressions aren't converted since they may be untrusted
+	err = engine.Render(&b, &ModalPage{Markdown: "<p>Hi</p>"})
+	require.ErrorContains(t, err, "cannot assign value of type string to field ModalComponent.Body (template.HTML)")
+
+	var assignmentErr *AssignmentError
+	require.ErrorAs(t, err, &assignmentErr)
+
+	// literals
+	err = engine.RegisterComponent(&ModalPage{}, `<ModalComponent Body="<b>Literal</b>"></ModalComponent>`)
+	require.NoError(t, err)
+	b.Reset()
+	err = engine.Render(&b, &ModalPage{})
+	require.NoError(t, err)
+	require.Equal(t, `<dialog style=""><b>Literal</b></dialog>`, b.String())
+
+	// literals alongside expressions
+	err = engine.RegisterComponent(&ModalPage{}, `<ModalComponent Body="{{.Markdown}}" Style="color: red"></ModalComponent>`)
+	require.NoError(t, err)
+	b.Reset()
+	err = engine.Render(&b, &ModalPage{Markdown: template.HTML("<p>Hello</p>")})
+	require.NoError(t, err)
+	require.Equal(t, `<dialog style="color: red"><p>Hello</p></dialog>`, b.String())
+
+	// other typed strings aren't converted
+	err = engine.RegisterComponent(&ModalPage{}, `<ModalComponent Body="{{.Markdown}}"></ModalComponent>`)
+	require.NoError(t, err)
+	err = engine.Render(&b, &ModalPage{Markdown: template.JS("alert(1)")})
+	require.ErrorContains(t, err, "cannot assign value of type template.JS to field ModalComponent.Body (template.HTML)")
+}
+
+type ModalProps struct {
+	Body template.HTML
+}
+
+func TestTypedStringAttributes_FuncComponents(t *testing.T) {
+	engine := New()
+	err := engine.RegisterFunc("Modal", func(props ModalProps, children template.HTML) (template.HTML, error) {
+		return "<dialog>" + props.Body + "</dialog>", nil
+	})
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&ModalPage{}, `<Modal Body="<b>Literal</b>"></Modal><Modal Body="{{.Markdown}}"></Modal>`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &ModalPage{Markdown: template.HTML("<p>Hello</p>")})
+	require.NoError(t, err)
+	require.Equal(t, `<dialog><b>Literal</b></dialog><dialog><p>Hello</p></dialog>`, b.String())
+
+	err = engine.Render(&b, &ModalPage{Markdown: "<p>Hi</p>"})
+	require.ErrorContains(t, err, "cannot assign value of type string to field ModalProps.Body (template.HTML)")
+}
+
+func TestWithLenientTypedStrings(t *testing.T) {
+	engine := New(WithLenientTypedStrings())
+	err := engine.RegisterComponent(&ModalComponent{}, `<dialog>{{.Body}}</dialog>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&ModalPage{}, `<ModalComponent Body="{{.Markdown}}"></ModalComponent>`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &ModalPage{Markdown: "<p>Hi</p>"})
+	require.NoError(t, err)
+	require.Equal(t, `<dialog><p>Hi</p></dialog>`, b.String())
+
+	b.Reset()
+	err = engine.Render(&b, &ModalPage{Markdown: template.HTML("<p>Hello</p>")})
+	require.NoError(t, err)
+	require.Equal(t, `<dialog><p>Hello</p></dialog>`, b.String())
+}
+
+type RecompilePage struct{}
+
+type recompileObserver struct {
+	recordingSink
+	recompiles []string
+}
+
+func (o *recompileObserver) ObserveRecompile(component string, registered string) {
+	o.recompiles = append(o.recompiles, component+" <- "+registered)
+}
+
+func TestRecompiles(t *testing.T) {
+	const count = 50
+
+	var page strings.Builder
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&page, "<Item%d />", i)
+	}
+
+	observer := &recompileObserver{}
+	engine := New(WithMetrics(observer))
+	err := engine.RegisterComponent(&RecompilePage{}, page.String())
+	require.NoError(t, err)
+
+	// Registering components after the page that renders them recompiles it
+	// once per component, rather than once per previous compilation
+	for i := count - 1; i >= 0; i-- {
+		err := engine.RegisterFunc(fmt.Sprintf("Item%d", i), func(_ map[string]any, _ template.HTML) (template.HTML, error) {
+			return template.HTML(fmt.Sprintf("<i>%d</i>", i)), nil
+		})
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, count, engine.Recompiles())
+	require.Len(t, observer.recompiles, count)
+	require.Equal(t, "RecompilePage <- Item49", observer.recompiles[0])
+
+	var b strings.Builder
+	err = engine.Render(&b, &RecompilePage{})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(b.String(), "<i>0</i><i>1</i>"))
+	require.True(t, strings.HasSuffix(b.String(), "<i>49</i>"))
+}
+
+func TestWithRecompileLimit(t *testing.T) {
+	engine := New(WithRecompileLimit(1))
+	err := engine.RegisterComponent(&RecompilePage{}, `<Item0 /><Item1 />`)
+	require.NoError(t, err)
+
+	item := func(_ map[string]any, _ template.HTML) (template.HTML, error) {
+		return "<i></i>", nil
+	}
+	err = engine.RegisterFunc("Item0", item)
+	require.NoError(t, err)
+	err = engine.RegisterFunc("Item1", item)
+	require.EqualError(t, err, "could not register function component: could not recompile RecompilePage after registering Item1: exceeded the limit of 1 recompiles, register components before the components that render them")
+	require.Equal(t, 2, engine.Recompiles())
+}
+
+type TemplatePageData struct {
+	Title string
+}
+
+func TestRegisterTemplate(t *testing.T) {
+	engine := New(WithStrictBindings())
+	err := engine.RegisterComponent(&StrictBadge{}, `<b>{{.Label}}</b>`)
+	require.NoError(t, err)
+	err = engine.RegisterTemplate("main.glam.html", &TemplatePageData{}, `<h1>{{.Title}}</h1><StrictBadge label="{{.Title}}" />`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.RenderByName(&b, "main.glam.html", &TemplatePageData{Title: "Home"})
+	require.NoError(t, err)
+	require.Equal(t, "<h1>Home</h1><b>Home</b>", b.String())
+
+	b.Reset()
+	err = engine.RenderByName(&b, "main.glam.html", map[string]any{"Title": "Home"})
+	require.EqualError(t, err, "template main.glam.html expects data of type *glam.TemplatePageData, got map[string]interface {}")
+	require.Equal(t, "", b.String())
+
+	err = engine.RenderByName(&b, "main.glam.html", nil)
+	require.EqualError(t, err, "template main.glam.html expects data of type *glam.TemplatePageData, got nil")
+
+	err = engine.RegisterTemplate("layout.glam.html", TemplatePageData{}, `<title>{{.Title}}</title>`)
+	require.NoError(t, err)
+	err = engine.RenderByName(&b, "layout.glam.html", &TemplatePageData{Title: "Home"})
+	require.NoError(t, err)
+	require.Equal(t, "<title>Home</title>", b.String())
+}
+
+func TestRegisterTemplateErrors(t *testing.T) {
+	engine := New(WithStrictBindings())
+	err := engine.RegisterComponent(&StrictBadge{}, `<b>{{.Label}}</b>`)
+	require.NoError(t, err)
+
+	err = engine.RegisterTemplate("main.glam.html", &TemplatePageData{}, `<StrictBadge label="{{.Titel}}" />`)
+	require.ErrorContains(t, err, "attribute of component StrictBadge references .Titel, but *glam.TemplatePageData has no field or method Titel")
+
+	err = engine.RegisterTemplate("StrictBadge", &TemplatePageData{}, `<b></b>`)
+	require.EqualError(t, err, "template StrictBadge can't have the same name as a registered component")
+
+	err = engine.RegisterTemplate("main.glam.html", nil, `<b></b>`)
+	require.EqualError(t, err, "template main.glam.html must be given a value of the type of data it expects, got nil")
+}
+
+func TestCommentsInComponentAttributes(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&QuotedLabel{}, `<b>{{.Label}}</b>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&QuotedLabelPage{}, `<QuotedLabel label="{{/* TODO */}}real" />`+
+		`<QuotedLabel label="{{/* it's "}}" */}}{{.Name}}" />`+
+		`<QuotedLabel label="{{- /* TODO */ -}} {{.Name}}" />`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &QuotedLabelPage{Name: "Fox"})
+	require.NoError(t, err)
+	require.Equal(t, "<b>real</b><b>Fox</b><b>Fox</b>", b.String())
+}
+
+type TextGreeting struct {
+	Name     string
+	Children template.HTML
+}
+
+type TextEmail struct {
+	Name string
+}
+
+func TestWithTextTemplates(t *testing.T) {
+	engine := New(WithTextTemplates())
+	err := engine.RegisterComponent(&TextGreeting{}, `Hi {{.Name}} & welcome, {{.Children}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&TextEmail{}, "<TextGreeting name=\"{{.Name}}\">it's <great></TextGreeting>\nReply to \"{{.Name}}\" <support@example.com>")
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &TextEmail{Name: `O'Brien <ob@example.com>`})
+	require.NoError(t, err)
+	require.Equal(t, "Hi O'Brien <ob@example.com> & welcome, it's <great>\nReply to \"O'Brien <ob@example.com>\" <support@example.com>", b.String())
+
+	b.Reset()
+	err = engine.RenderTemplate(&b, `<TextGreeting name="{{.}}">&</TextGreeting>`, "<Fox>")
+	require.NoError(t, err)
+	require.Equal(t, "Hi <Fox> & welcome, &", b.String())
+}
+
+type CaseBadge struct {
+	Name     string
+	UserName string `attr:"userName"`
+}
+
+type CaseBadgePage struct{}
+
+func TestAttributeNamesAreCaseInsensitive(t *testing.T) {
+	engine := New(WithStrictBindings())
+	err := engine.RegisterComponent(&CaseBadge{}, `<b>{{.Name}} ({{.UserName}})</b>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&CaseBadgePage{}, `<CaseBadge name="a" userName="x" /><CaseBadge Name="b" username="y" /><CaseBadge NAME="c" USERNAME="z" />`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &CaseBadgePage{})
+	require.NoError(t, err)
+	require.Equal(t, "<b>a (x)</b><b>b (y)</b><b>c (z)</b>", b.String())
+
+	html, err := engine.Preview("CaseBadge", map[string]any{"Name": "Fox", "userName": "fmulder"})
+	require.NoError(t, err)
+	require.Equal(t, template.HTML("<b>Fox (fmulder)</b>"), html)
+}
+
+type LabeledButton struct {
+	Label string
+}
+
+type LabeledInput struct {
+	Label string
+	Name  string
+}
+
+type LabeledForm struct{}
+
+func TestWithAttributeTransformer_AcrossComponents(t *testing.T) {
+	engine := New(WithAttributeTransformer(func(_ string, field string, value any) (any, error) {
+		if s, ok := value.(string); ok && field == "Label" {
+			return strings.ToUpper(s), nil
+		}
+
+		return value, nil
+	}))
+
+	err := engine.RegisterComponent(&LabeledButton{}, `<button>{{.Label}}</button>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&LabeledInput{}, `<label>{{.Label}} <input name="{{.Name}}"></label>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&LabeledForm{}, `<LabeledInput label="email" name="email" /><LabeledButton label="save" />`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &LabeledForm{})
+	require.NoError(t, err)
+	require.Equal(t, `<label>EMAIL <input name="email"></label><button>SAVE</button>`, b.String())
+}
+
+type KeyedComponent struct {
+	Key string
+}
+
+func TestKeyAttribute(t *testing.T) {
+	engine := New(WithStrictBindings())
+	err := engine.RegisterComponent(&RowComponent{}, `<tr class="row">{{.Name}}{{.Children}}</tr>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&CellComponent{}, `{{.Value}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&RangePage{}, `<RowComponent range="{{.Rows}}" key="{{.Name}}" name="{{.Name}}" />`+
+		`<RowComponent key="static" name="static" />`+
+		`<CellComponent key="text" value="no element" />`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &RangePage{Rows: []RangeRow{{Name: "one"}, {Name: `"two"`}}})
+	require.NoError(t, err)
+	require.Equal(t, `<tr data-glam-key="one" class="row">one</tr>`+
+		`<tr data-glam-key="&#34;two&#34;" class="row">&#34;two&#34;</tr>`+
+		`<tr data-glam-key="static" class="row">static</tr>`+
+		`no element`, b.String())
+
+	// Bindings of keyed components are still checked
+	err = engine.RegisterComponent(&RangePage{}, `<CellComponent key="cell" value="{{.Nme}}" />`)
+	require.ErrorContains(t, err, "attribute of component CellComponent references .Nme, but *glam.RangePage has no field or method Nme")
+	err = engine.RegisterComponent(&RangePage{}, `<CellComponent key="cell" vaule="x" />`)
+	require.ErrorContains(t, err, "component CellComponent has no field for attribute vaule")
+
+	err = engine.RegisterComponent(&KeyedComponent{}, `{{.Key}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&RangePage{}, `<KeyedComponent key="1" />`)
+	require.ErrorContains(t, err, "component KeyedComponent can't use the reserved key attribute since it has a field named key")
+}
+
+type CommentedRow struct {
+	Name string
+}
+
+func (CommentedRow) Trusted() {}
+
+func TestKeyAttribute_LeadingComment(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&CommentedRow{}, `<!-- <b>row</b> --><tr class="row">{{.Name}}</tr>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&RangePage{}, `<CommentedRow key="one" name="one" />`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &RangePage{})
+	require.NoError(t, err)
+	require.Equal(t, `<!-- <b>row</b> --><tr data-glam-key="one" class="row">one</tr>`, b.String())
+}
+
+type IncludePage struct {
+	Name string
+}
+
+func TestWithIncludeFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"snippets/analytics.html": {Data: []byte(`<script src="/a.js"></script>`)},
+		"snippets/footer.html":    {Data: []byte(`<footer><CellComponent value="{{.Name}}" />{{include "snippets/analytics.html"}}</footer>`)},
+	}
+
+	engine := New(WithIncludeFS(fsys))
+	err := engine.RegisterComponent(&IncludePage{}, `<main>{{.Name}}</main>{{ include "snippets/footer.html" }}`)
+	require.NoError(t, err)
+
+	// Components in included files are recompiled when they're registered
+	err = engine.RegisterComponent(&CellComponent{}, `<td>{{.Value}}</td>`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &IncludePage{Name: "Fox"})
+	require.NoError(t, err)
+	require.Equal(t, `<main>Fox</main><footer><td>Fox</td><script src="/a.js"></script></footer>`, b.String())
+
+	// Changed includes are spliced in again when the template is registered
+	fsys["snippets/analytics.html"] = &fstest.MapFile{Data: []byte(`<script src="/b.js"></script>`)}
+	err = engine.RegisterComponent(&IncludePage{}, `{{include "snippets/analytics.html"}}`)
+	require.NoError(t, err)
+
+	b.Reset()
+	err = engine.Render(&b, &IncludePage{})
+	require.NoError(t, err)
+	require.Equal(t, `<script src="/b.js"></script>`, b.String())
+}
+
+func TestWithIncludeFS_Errors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.html":       {Data: []byte(`{{include "b.html"}}`)},
+		"b.html":       {Data: []byte("\n{{include \"a.html\"}}")},
+		"missing.html": {Data: []byte(`{{include "nope.html"}}`)},
+	}
+
+	engine := New(WithIncludeFS(fsys))
+
+	err := engine.RegisterComponent(&IncludePage{}, "<main>\n  {{include \"nope.html\"}}</main>")
+	require.EqualError(t, err, `could not register template: could not parse template IncludePage: 2:3: could not include "nope.html": open nope.html: file does not exist`)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	err = engine.RegisterComponent(&IncludePage{}, `{{include "missing.html"}}`)
+	require.EqualError(t, err, `could not register template: could not parse template IncludePage: 1:1: could not include "missing.html": 1:1: could not include "nope.html": open nope.html: file does not exist`)
+
+	err = engine.RegisterComponent(&IncludePage{}, `{{include "a.html"}}`)
+	require.ErrorContains(t, err, `1:1: could not include "a.html": 1:1: could not include "b.html": 2:1: could not include "a.html": include cycle a.html -> b.html -> a.html`)
+}
+
+type WrapperTextComponent struct {
+	Children template.HTML
+}
+
+type InnerTextComponent struct{}
+
+type WrapperTextPage struct{}
+
+func TestTextBeforeNestedComponent(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&WrapperTextComponent{}, `<div>{{.Children}}</div>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&InnerTextComponent{}, `<i>inner</i>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&WrapperTextPage{}, `<WrapperTextComponent>X<InnerTextComponent/>Y<b>Z</b></WrapperTextComponent>`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &WrapperTextPage{})
+	require.NoError(t, err)
+	require.Equal(t, "<div>X<i>inner</i>Y<b>Z</b></div>", b.String())
+}
+
+type InlineCount struct {
+	N        int
+	Children template.HTML
+}
+
+type InlineCountPage struct {
+	N int
+}
+
+func TestInlineComponentWhitespace(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&WrapperTextComponent{}, `<p>{{.Children}}</p>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&InlineCount{}, `<b>{{.N}}{{.Children}}</b>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&InlineCountPage{}, `You have <InlineCount n="{{.N}}"/> messages, `+
+		`<InlineCount n="{{.N}}">new</InlineCount> today. `+
+		`<WrapperTextComponent>Read <InlineCount n="{{1}}" /> of <InlineCount n="{{.N}}"></InlineCount> now</WrapperTextComponent>`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &InlineCountPage{N: 3})
+	require.NoError(t, err)
+	require.Equal(t, "You have <b>3</b> messages, <b>3new</b> today. <p>Read <b>1</b> of <b>3</b> now</p>", b.String())
+}
+
+type greetingRepository struct {
+	greeting string
+}
+
+type FactoryGreeting struct {
+	Name string
+	repo *greetingRepository
+}
+
+func (g *FactoryGreeting) Greeting() string {
+	return g.repo.greeting + ", " + g.Name
+}
+
+type FactoryPage struct{}
+
+func TestRegisterComponentFactory(t *testing.T) {
+	repo := &greetingRepository{greeting: "Hello"}
+	engine := New()
+	err := engine.RegisterComponentFactory(&FactoryGreeting{}, func() any {
+		return &FactoryGreeting{repo: repo}
+	}, `<p>{{.Greeting}}</p>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&FactoryPage{}, `<FactoryGreeting name="Fox" /><FactoryGreeting name="Dana" />`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &FactoryPage{})
+	require.NoError(t, err)
+	require.Equal(t, "<p>Hello, Fox</p><p>Hello, Dana</p>", b.String())
+
+	html, err := engine.Preview("FactoryGreeting", map[string]any{"name": "Walter"})
+	require.NoError(t, err)
+	require.Equal(t, template.HTML("<p>Hello, Walter</p>"), html)
+
+	err = engine.RegisterComponentFactory(&FactoryGreeting{}, func() any {
+		return FactoryGreeting{repo: repo}
+	}, `<p>{{.Greeting}}</p>`)
+	require.EqualError(t, err, "factory for component FactoryGreeting must return *glam.FactoryGreeting, got glam.FactoryGreeting")
+}
+
+func TestWithComponentFactory(t *testing.T) {
+	repo := &greetingRepository{greeting: "Hi"}
+	var constructed []string
+	engine := New(WithComponentFactory(func(componentType reflect.Type) any {
+		constructed = append(constructed, componentType.String())
+		if componentType == reflect.TypeOf(&FactoryGreeting{}) {
+			return &FactoryGreeting{repo: repo}
+		}
+
+		return nil
+	}))
+	err := engine.RegisterComponent(&FactoryGreeting{}, `<p>{{.Greeting}}</p>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&CellComponent{}, `<td>{{.Value}}</td>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&FactoryPage{}, `<FactoryGreeting name="Fox" /><CellComponent value="zero" />`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &FactoryPage{})
+	require.NoError(t, err)
+	require.Equal(t, "<p>Hi, Fox</p><td>zero</td>", b.String())
+	require.Equal(t, []string{"*glam.FactoryGreeting", "*glam.CellComponent"}, constructed)
+
+	engine = New(WithComponentFactory(func(reflect.Type) any {
+		return CellComponent{}
+	}))
+	err = engine.RegisterComponent(&CellComponent{}, `<td>{{.Value}}</td>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&FactoryPage{}, `<CellComponent value="zero" />`)
+	require.NoError(t, err)
+
+	err = engine.Render(&b, &FactoryPage{})
+	require.ErrorContains(t, err, "component CellComponent must be constructed as a non-nil *glam.CellComponent, got glam.CellComponent")
+}
+
+type RowRenderer func(row string) string
+
+type CallbackTable struct {
+	Rows      []string
+	RenderRow RowRenderer
+}
+
+type CallbackTablePage struct {
+	Rows      []string
+	RenderRow func(string) string
+	Count     func(string) int
+}
+
+func TestFuncAttributes(t *testing.T) {
+	engine := New(WithStrictBindings())
+	err := engine.RegisterComponent(&CallbackTable{}, `{{range .Rows}}<td>{{call $.RenderRow .}}</td>{{end}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&CallbackTablePage{}, `<table><CallbackTable rows="{{.Rows}}" renderRow="{{.RenderRow}}" /></table>`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &CallbackTablePage{Rows: []string{"a", "b"}, RenderRow: strings.ToUpper})
+	require.NoError(t, err)
+	require.Equal(t, "<table><td>A</td><td>B</td></table>", b.String())
+
+	err = engine.RegisterComponent(&CallbackTablePage{}, `<CallbackTable renderRow="upper" />`)
+	require.ErrorContains(t, err, "attribute renderrow of component CallbackTable must be a glam.RowRenderer, got string")
+
+	err = engine.RegisterComponent(&CallbackTablePage{}, `<CallbackTable renderRow="{{.Count}}" />`)
+	require.ErrorContains(t, err, "attribute renderrow of component CallbackTable must be a glam.RowRenderer, got func(string) int")
+
+	// Without strict bindings, mismatched funcs fail when rendered
+	engine = New()
+	err = engine.RegisterComponent(&CallbackTable{}, `{{range .Rows}}{{call $.RenderRow .}}{{end}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&CallbackTablePage{}, `<CallbackTable renderRow="{{.Count}}" />`)
+	require.NoError(t, err)
+
+	err = engine.Render(&b, &CallbackTablePage{Count: func(string) int { return 0 }})
+	var assignmentErr *AssignmentError
+	require.ErrorAs(t, err, &assignmentErr)
+	require.Equal(t, "RenderRow", assignmentErr.Field.Name)
+}
+
+type BalancedAlert struct {
+	Dismissible bool
+}
+
+type BalancedPage struct {
+	Dismissible bool
+}
+
+func TestWithBalancedOutputCheck(t *testing.T) {
+	engine := New(WithBalancedOutputCheck())
+	err := engine.RegisterComponent(&WrapperTextComponent{}, `<div class="wrapper">{{.Children}}</div>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&BalancedAlert{}, `{{if .Dismissible}}<div class="dismissible">{{end}}<p>Alert<br>`+
+		`<script>document.write("<div>")</script>{{if .Dismissible}}<button>x</button>{{end}}`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&BalancedPage{}, `<main><WrapperTextComponent><BalancedAlert dismissible="{{.Dismissible}}" /></WrapperTextComponent></main>`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &BalancedPage{})
+	require.NoError(t, err)
+	require.Equal(t, `<main><div class="wrapper"><p>Alert<br><script>document.write("<div>")</script></div></main>`, b.String())
+
+	b.Reset()
+	err = engine.Render(&b, &BalancedPage{Dismissible: true})
+	var unbalancedErr *UnbalancedError
+	require.ErrorAs(t, err, &unbalancedErr)
+	require.Equal(t, "BalancedAlert", unbalancedErr.Component)
+	require.Equal(t, "div", unbalancedErr.Tag)
+	require.ErrorContains(t, err, "component BalancedAlert rendered <div> without closing it")
+	require.Equal(t, "", b.String())
+
+	// Child content is checked as part of the component whose template
+	// contains it
+	err = engine.RegisterComponent(&BalancedPage{}, `<WrapperTextComponent></section></WrapperTextComponent>`)
+	require.NoError(t, err)
+	err = engine.Render(&b, &BalancedPage{})
+	require.ErrorContains(t, err, "child content in component BalancedPage rendered </section> without opening it")
+
+	// Only SVG and MathML elements can be self-closing
+	err = engine.RegisterComponent(&BalancedAlert{}, `<svg><path d="M0 0"/></svg>Alert`)
+	require.NoError(t, err)
+	b.Reset()
+	err = engine.Render(&b, &BalancedAlert{})
+	require.NoError(t, err)
+	require.Equal(t, `<svg><path d="M0 0"/></svg>Alert`, b.String())
+
+	err = engine.RegisterComponent(&BalancedAlert{}, `<div/>Alert`)
+	require.NoError(t, err)
+	err = engine.Render(&b, &BalancedAlert{})
+	require.ErrorContains(t, err, "component BalancedAlert rendered <div> without closing it")
+
+	// Output isn't checked by default
+	engine = New()
+	err = engine.RegisterComponent(&BalancedAlert{}, `{{if .Dismissible}}<div class="dismissible">{{end}}Alert`)
+	require.NoError(t, err)
+	b.Reset()
+	err = engine.Render(&b, &BalancedAlert{Dismissible: true})
+	require.NoError(t, err)
+	require.Equal(t, `<div class="dismissible">Alert`, b.String())
+}
+
+type PointerGreeting struct {
+	Name string
+}
+
+func (g *PointerGreeting) Greeting() string {
+	return "Hi " + g.Name
+}
+
+type PointerGreetingPage struct{}
+
+func TestPointerAndValueComponents(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		registered any
+		rendered   any
+	}{
+		{desc: "register pointer, render pointer", registered: &PointerGreeting{}, rendered: &PointerGreeting{Name: "Fox"}},
+		{desc: "register pointer, render value", registered: &PointerGreeting{}, rendered: PointerGreeting{Name: "Fox"}},
+		{desc: "register value, render pointer", registered: PointerGreeting{}, rendered: &PointerGreeting{Name: "Fox"}},
+		{desc: "register value, render value", registered: PointerGreeting{}, rendered: PointerGreeting{Name: "Fox"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New()
+			err := engine.RegisterComponent(tC.registered, `<p>{{.Greeting}}</p>`)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&PointerGreetingPage{}, `<PointerGreeting name="Owl" />`)
+			require.NoError(t, err)
+
+			var b strings.Builder
+			err = engine.Render(&b, tC.rendered)
+			require.NoError(t, err)
+			require.Equal(t, "<p>Hi Fox</p>", b.String())
+
+			b.Reset()
+			err = engine.Render(&b, PointerGreetingPage{})
+			require.NoError(t, err)
+			require.Equal(t, "<p>Hi Owl</p>", b.String())
+		})
+	}
+}
+
+type CMSPage struct {
+	Title string
+}
+
+type CMSSecret struct{}
+
+type CMSLater struct{}
+
+func TestRegisterUntrustedComponent(t *testing.T) {
+	engine := New(WithFuncs(FuncMap{
+		"upper":  strings.ToUpper,
+		"secret": func() string { return "secret" },
+	}))
+	err := engine.RegisterComponent(&CellComponent{}, `<td>{{.Value}}</td>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&CMSSecret{}, `secret`)
+	require.NoError(t, err)
+	err = engine.RegisterPartials(`{{define "footer"}}{{secret}}{{end}}`)
+	require.NoError(t, err)
+
+	policy := Policy{Funcs: []string{"upper"}, Components: []string{"CellComponent", "CMSLater"}}
+	err = engine.RegisterUntrustedComponent(&CMSPage{}, `<h1>{{upper .Title}}</h1>{{if eq .Title "Home"}}<CellComponent value="{{.Title}}" />{{end}}<CMSLater />`, policy)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &CMSPage{Title: "Home"})
+	require.NoError(t, err)
+	require.Equal(t, "<h1>HOME</h1><td>Home</td><CMSLater />", b.String())
+
+	testCases := []struct {
+		template string
+		err      string
+	}{
+		{template: "<h1>\n  {{secret}}</h1>", err: "2:5: func secret isn't allowed by the template's policy"},
+		{template: `{{with .Title}}{{printf "%s" (render .)}}{{end}}`, err: "1:31: func render isn't allowed by the template's policy"},
+		{template: `{{call .Title}}`, err: "1:3: func call isn't allowed by the template's policy"},
+		{template: `{{__glamDict "a" 1}}`, err: "1:3: internal func __glamDict can't be called"},
+		{template: `<p><CMSSecret /></p>`, err: "1:4: component CMSSecret isn't allowed by the template's policy"},
+		// Component tags split across a define compile into a valid template,
+		// but the source can't be checked on its own
+		{template: `{{call .Title}}<CellComponent>{{end}}{{define "y"}}</CellComponent>`, err: "template can't be checked against its policy"},
+	}
+	for _, tC := range testCases {
+		err = engine.RegisterUntrustedComponent(&CMSPage{}, tC.template, policy)
+		require.ErrorContains(t, err, tC.err)
+	}
+
+	// Untrusted templates aren't given partials
+	err = engine.RegisterUntrustedComponent(&CMSPage{}, `{{template "footer"}}`, policy)
+	require.NoError(t, err)
+	err = engine.Render(&b, &CMSPage{})
+	require.ErrorContains(t, err, `no such template "footer"`)
+
+	// The policy is checked again when the template is recompiled
+	err = engine.RegisterUntrustedComponent(&CMSPage{}, `<CMSLater /><CMSSecret />`, Policy{Components: []string{"CMSLater"}})
+	require.ErrorContains(t, err, "component CMSSecret isn't allowed")
+	err = engine.RegisterUntrustedComponent(&CMSPage{}, `<CMSLater />`, Policy{})
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&CMSLater{}, `later`)
+	require.ErrorContains(t, err, "could not recompile template: could not parse template CMSPage: 1:1: component CMSLater isn't allowed by the template's policy")
+}
+
+func TestWithParserLimits(t *testing.T) {
+	engine := New(WithParserLimits(ParserLimits{MaxDepth: 2, MaxAttributes: 2, MaxTags: 10}))
+	err := engine.RegisterComponent(&WrapperTextComponent{}, `<div>{{.Children}}</div>`)
+	require.NoError(t, err)
+
+	err = engine.RegisterUntrustedComponent(&CMSPage{}, strings.Repeat("<WrapperTextComponent>", 3)+strings.Repeat("</WrapperTextComponent>", 3), Policy{Components: []string{"WrapperTextComponent"}})
+	require.ErrorContains(t, err, "components can't be nested more than 2 levels deep")
+
+	err = engine.RegisterComponent(&CMSPage{}, `<a href="/" class="link" title="Home">Home</a>`)
+	require.ErrorContains(t, err, "1:26: tag has more than 2 attributes")
+
+	err = engine.RegisterComponent(&CMSPage{}, `<WrapperTextComponent><WrapperTextComponent><a href="/" class="link">Home</a></WrapperTextComponent></WrapperTextComponent>`)
+	require.NoError(t, err)
+}
+
+// ExternalWidget stands in for a type from another package, whose tags can't
+// be changed
+type ExternalWidget struct {
+	ID       string
+	Title    string
+	OnSelect func() string
+}
+
+type WidgetPage struct {
+	WidgetID string
+	Select   func() string
+}
+
+func TestRegisterComponentWithMapping(t *testing.T) {
+	engine := New(WithStrictBindings())
+	err := engine.RegisterComponent(&WidgetPage{}, `<ExternalWidget data-id="{{.WidgetID}}" Title="Widget" data-select="{{.Select}}"></ExternalWidget>`)
+	require.NoError(t, err)
+
+	err = engine.RegisterComponentWithMapping(&ExternalWidget{}, `<div id="{{.ID}}">{{.Title}} {{call .OnSelect}}</div>`, map[string]string{
+		"data-id":     "ID",
+		"Data-Select": "OnSelect",
+	})
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &WidgetPage{WidgetID: "widget-1", Select: func() string { return "selected" }})
+	require.NoError(t, err)
+	require.Equal(t, `<div id="widget-1">Widget selected</div>`, b.String())
+
+	components := engine.Components()
+	require.Equal(t, []AttributeInfo{
+		{Name: "data-id", Field: "ID", Type: "string"},
+		{Name: "title", Field: "Title", Type: "string"},
+		{Name: "data-select", Field: "OnSelect", Type: "func() string"},
+	}, components[0].Attributes)
+
+	// Mapped attributes are checked when bindings are strict
+	err = engine.RegisterComponent(&WidgetPage{}, `<ExternalWidget data-select="{{.WidgetID}}"></ExternalWidget>`)
+	require.ErrorContains(t, err, "attribute data-select of component ExternalWidget must be a func() string, got string")
+
+	err = engine.RegisterComponentWithMapping(&ExternalWidget{}, `{{.ID}}`, map[string]string{"data-id": "Id"})
+	require.EqualError(t, err, "component ExternalWidget has no field Id to map attribute data-id to")
+
+	// Registering the component again removes the mapping
+	err = engine.RegisterComponent(&ExternalWidget{}, `<div id="{{.ID}}"></div>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&WidgetPage{}, `<ExternalWidget data-id="{{.WidgetID}}"></ExternalWidget>`)
+	require.ErrorContains(t, err, "component ExternalWidget has no field for attribute data-id")
+}
@@ -0,0 +1,34 @@
+package glam
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/blakewilliams/glam/internal/template"
+)
+
+// RenderEscaped renders the given component as plain text, like an email
+// subject line, and writes it to w. The component's template is executed
+// using text/template instead of html/template, and each value it
+// interpolates is escaped using escaper, e.g. `{{.Name}}` is written as
+// `Tom &amp; Jerry` when escaper is html.EscapeString. Literal text in the
+// template is written as-is, as are template.HTML values, like child content
+// and nested components, which are rendered as text too. Function
+// components write their output as-is.
+//
+// Use html.EscapeString to write the text into HTML, EscapeJSONString to
+// write it into a JSON string, or nil to write values unescaped.
+func (e *Engine) RenderEscaped(w io.Writer, renderable any, escaper func(string) string) error {
+	return e.Render(&template.TextWriter{Writer: w, Escape: escaper}, renderable)
+}
+
+// EscapeJSONString escapes s so it can be written inside of a JSON string,
+// without the surrounding quotes. Like encoding/json, `<`, `>`, and `&` are
+// escaped so the JSON can be embedded in HTML.
+func EscapeJSONString(s string) string {
+	// Marshaling a string can't fail
+	encoded, _ := json.Marshal(s)
+
+	return strings.TrimSuffix(strings.TrimPrefix(string(encoded), `"`), `"`)
+}
@@ -0,0 +1,83 @@
+package glam
+
+import (
+	"bytes"
+	"html"
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type SubjectLine struct {
+	Name  string
+	Order string
+}
+
+func TestRenderEscaped(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&SubjectLine{}, `Order {{.Order}} for {{.Name}} has "shipped"`)
+	require.NoError(t, err)
+
+	subject := &SubjectLine{Name: `Tom & Jerry <tj@example.com>`, Order: "#12"}
+
+	var b bytes.Buffer
+	err = engine.RenderEscaped(&b, subject, nil)
+	require.NoError(t, err)
+	require.Equal(t, `Order #12 for Tom & Jerry <tj@example.com> has "shipped"`, b.String())
+
+	b.Reset()
+	err = engine.RenderEscaped(&b, subject, html.EscapeString)
+	require.NoError(t, err)
+	require.Equal(t, `Order #12 for Tom &amp; Jerry &lt;tj@example.com&gt; has "shipped"`, b.String())
+
+	b.Reset()
+	err = engine.RenderEscaped(&b, subject, EscapeJSONString)
+	require.NoError(t, err)
+	require.Equal(t, `Order #12 for Tom \u0026 Jerry \u003ctj@example.com\u003e has "shipped"`, b.String())
+
+	b.Reset()
+	err = engine.RenderEscaped(&b, &CMSPage{}, nil)
+	require.ErrorContains(t, err, "No component found")
+}
+
+type MessageLink struct {
+	URL      string
+	Children template.HTML
+}
+
+type MessageBody struct {
+	Name string
+	URL  string
+}
+
+func TestRenderEscaped_NoHTMLEscaping(t *testing.T) {
+	engine := New()
+	err := engine.RegisterComponent(&MessageLink{}, `<a href="{{.URL}}">{{.Children}}</a>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&MessageBody{}, `<script>var name = "{{.Name}}"</script><MessageLink url="{{.URL}}">Hi {{.Name}}</MessageLink>`)
+	require.NoError(t, err)
+
+	body := &MessageBody{Name: `O'Brien & "Co"`, URL: "javascript:alert('a b')"}
+
+	var b bytes.Buffer
+	err = engine.RenderEscaped(&b, body, nil)
+	require.NoError(t, err)
+	require.Equal(t, `<script>var name = "O'Brien & "Co""</script><a href="javascript:alert('a b')">Hi O'Brien & "Co"</a>`, b.String())
+
+	b.Reset()
+	err = engine.RenderEscaped(&b, body, EscapeJSONString)
+	require.NoError(t, err)
+	require.Equal(t, `<script>var name = "O'Brien \u0026 \"Co\""</script><a href="javascript:alert('a b')">Hi O'Brien \u0026 \"Co\"</a>`, b.String())
+
+	// HTML renders are unaffected by text renders
+	b.Reset()
+	err = engine.Render(&b, body)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `href="#ZgotmplZ"`)
+}
+
+func TestEscapeJSONString(t *testing.T) {
+	require.Equal(t, `line\nbreak \"quoted\" \\ tab\t`, EscapeJSONString("line\nbreak \"quoted\" \\ tab\t"))
+	require.Equal(t, "", EscapeJSONString(""))
+}
@@ -0,0 +1,59 @@
+// Package glamtest provides helpers for testing glam components, handling
+// buffer setup and failing the test when a component fails to render.
+package glamtest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/blakewilliams/glam"
+)
+
+// Render renders the component using the engine and returns the output. The
+// test fails immediately if the component can't be rendered.
+func Render(t testing.TB, engine *glam.Engine, component any) string {
+	t.Helper()
+
+	var b bytes.Buffer
+	err := engine.Render(&b, component)
+	if err != nil {
+		t.Fatalf("could not render %T: %s", component, err)
+		return ""
+	}
+
+	return b.String()
+}
+
+// AssertContains renders the component using the engine and fails the test
+// if the output doesn't contain substr.
+func AssertContains(t testing.TB, engine *glam.Engine, component any, substr string) {
+	t.Helper()
+
+	output := Render(t, engine, component)
+	if !strings.Contains(output, substr) {
+		t.Errorf("expected rendered %T to contain %q, got:\n%s", component, substr, output)
+	}
+}
+
+// AssertNotContains renders the component using the engine and fails the
+// test if the output contains substr.
+func AssertNotContains(t testing.TB, engine *glam.Engine, component any, substr string) {
+	t.Helper()
+
+	output := Render(t, engine, component)
+	if strings.Contains(output, substr) {
+		t.Errorf("expected rendered %T not to contain %q, got:\n%s", component, substr, output)
+	}
+}
+
+// AssertRenders renders the component using the engine and fails the test if
+// the output isn't equal to expected.
+func AssertRenders(t testing.TB, engine *glam.Engine, component any, expected string) {
+	t.Helper()
+
+	output := Render(t, engine, component)
+	if output != expected {
+		t.Errorf("expected rendered %T to equal:\n%s\ngot:\n%s", component, expected, output)
+	}
+}
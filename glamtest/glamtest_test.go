@@ -0,0 +1,82 @@
+package glamtest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/blakewilliams/glam"
+	"github.com/stretchr/testify/require"
+)
+
+type GreetingComponent struct {
+	Name string
+}
+
+type UnregisteredComponent struct{}
+
+// recordingT records failures instead of failing the test, so the helpers'
+// failures can be asserted on
+type recordingT struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingT) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	r.fatal = true
+}
+
+func newEngine(t *testing.T) *glam.Engine {
+	engine := glam.New()
+	err := engine.RegisterComponent(&GreetingComponent{}, `<p>Hello, {{.Name}}</p>`)
+	require.NoError(t, err)
+
+	return engine
+}
+
+func TestRender(t *testing.T) {
+	engine := newEngine(t)
+
+	require.Equal(t, "<p>Hello, Fox</p>", Render(t, engine, &GreetingComponent{Name: "Fox"}))
+
+	rt := &recordingT{}
+	require.Equal(t, "", Render(rt, engine, &UnregisteredComponent{}))
+	require.True(t, rt.fatal)
+	require.Len(t, rt.errors, 1)
+	require.Contains(t, rt.errors[0], "could not render *glamtest.UnregisteredComponent: No component found for type UnregisteredComponent")
+}
+
+func TestAssertContains(t *testing.T) {
+	engine := newEngine(t)
+
+	AssertContains(t, engine, &GreetingComponent{Name: "Fox"}, "Hello, Fox")
+	AssertNotContains(t, engine, &GreetingComponent{Name: "Fox"}, "Dana")
+
+	rt := &recordingT{}
+	AssertContains(rt, engine, &GreetingComponent{Name: "Fox"}, "Dana")
+	AssertNotContains(rt, engine, &GreetingComponent{Name: "Fox"}, "Fox")
+	require.False(t, rt.fatal)
+	require.Equal(t, []string{
+		"expected rendered *glamtest.GreetingComponent to contain \"Dana\", got:\n<p>Hello, Fox</p>",
+		"expected rendered *glamtest.GreetingComponent not to contain \"Fox\", got:\n<p>Hello, Fox</p>",
+	}, rt.errors)
+}
+
+func TestAssertRenders(t *testing.T) {
+	engine := newEngine(t)
+
+	AssertRenders(t, engine, &GreetingComponent{Name: "Fox"}, "<p>Hello, Fox</p>")
+
+	rt := &recordingT{}
+	AssertRenders(rt, engine, &GreetingComponent{Name: "Fox"}, "<p>Hello, Dana</p>")
+	require.Equal(t, []string{
+		"expected rendered *glamtest.GreetingComponent to equal:\n<p>Hello, Dana</p>\ngot:\n<p>Hello, Fox</p>",
+	}, rt.errors)
+}
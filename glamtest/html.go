@@ -0,0 +1,375 @@
+package glamtest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/blakewilliams/glam"
+	"golang.org/x/net/html"
+)
+
+// AssertValidHTML renders the component using the engine and fails the test
+// if the output isn't a well-formed HTML fragment, like the content of a
+// <body>. The output is tokenized using golang.org/x/net/html, and it's
+// stricter than browsers: every element must be closed in the order it was
+// opened, except for void elements like <br> and elements whose end tag HTML
+// allows to be omitted, like </li>, so tags mismatched by conditionals are
+// reported. Failures include the position of the problem and an excerpt of
+// the output.
+//
+// glam.WithBalancedOutputCheck checks every render for unbalanced tags using
+// the same rules, without the checks for fragments and documents.
+func AssertValidHTML(t testing.TB, engine *glam.Engine, component any) {
+	t.Helper()
+
+	assertValidHTML(t, engine, component, false)
+}
+
+// AssertValidHTMLDocument renders the component using the engine and fails
+// the test if the output isn't a well-formed HTML document. Documents must
+// start with a doctype and consist of a single <html> element, which is
+// validated like AssertValidHTML.
+func AssertValidHTMLDocument(t testing.TB, engine *glam.Engine, component any) {
+	t.Helper()
+
+	assertValidHTML(t, engine, component, true)
+}
+
+func assertValidHTML(t testing.TB, engine *glam.Engine, component any, document bool) {
+	t.Helper()
+
+	output := Render(t, engine, component)
+
+	err := validateHTML(output, document)
+	if err != nil {
+		t.Errorf("expected rendered %T to be valid HTML, %s\n%s", component, err, err.excerpt(output))
+	}
+}
+
+// htmlError describes invalid HTML at a position in the output
+type htmlError struct {
+	line    int
+	column  int
+	message string
+}
+
+func (e *htmlError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.line, e.column, e.message)
+}
+
+// excerpt returns the lines surrounding the error, pointing at its column
+func (e *htmlError) excerpt(output string) string {
+	lines := strings.Split(output, "\n")
+	first := max(e.line-2, 0)
+	last := min(e.line+1, len(lines))
+	width := len(fmt.Sprint(last))
+
+	var b strings.Builder
+	for i := first; i < last; i++ {
+		marker := " "
+		if i+1 == e.line {
+			marker = ">"
+		}
+
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, i+1, lines[i])
+		if i+1 == e.line {
+			fmt.Fprintf(&b, "  %*s | %s^\n", width, "", strings.Repeat(" ", e.column-1))
+		}
+	}
+
+	return b.String()
+}
+
+// voidElements can't have content, so they're never closed
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// optionalEndTags are elements whose end tag can be omitted, so they're
+// implicitly closed by their parent's end tag or the end of the output
+var optionalEndTags = map[string]bool{
+	"body": true, "colgroup": true, "dd": true, "dt": true, "head": true,
+	"html": true, "li": true, "optgroup": true, "option": true, "p": true,
+	"rp": true, "rt": true, "tbody": true, "td": true, "tfoot": true,
+	"th": true, "thead": true, "tr": true,
+}
+
+// foreignElements start SVG and MathML content, whose elements can be
+// self-closing and whose <title> and <style> contain tags instead of text
+var foreignElements = map[string]bool{
+	"svg": true, "math": true,
+}
+
+// documentElements are only valid in documents, not fragments
+var documentElements = map[string]bool{
+	"html": true, "head": true, "body": true,
+}
+
+type (
+	// htmlValidator checks that tags in HTML output are balanced
+	htmlValidator struct {
+		output   string
+		document bool
+		open     []openElement
+		// foreign is the number of open elements in SVG or MathML content
+		foreign int
+		// openedRoot and closedRoot are true once the <html> element of a
+		// document is opened and explicitly closed
+		openedRoot bool
+		closedRoot bool
+	}
+
+	openElement struct {
+		name   string
+		line   int
+		column int
+	}
+)
+
+// validateHTML returns an error describing the first unbalanced, misnested,
+// or unclosed tag in the output.
+func validateHTML(output string, document bool) *htmlError {
+	v := &htmlValidator{output: output, document: document}
+
+	return v.validate()
+}
+
+func (v *htmlValidator) validate() *htmlError {
+	if v.document && !hasPrefixFold(v.output[v.contentStart():], "<!doctype") {
+		return v.errorf(v.contentStart(), "expected document to start with <!DOCTYPE html>")
+	}
+
+	z := html.NewTokenizer(strings.NewReader(v.output))
+	pos := 0
+	for {
+		tokenType := z.Next()
+		raw := string(z.Raw())
+		start := pos
+		pos += len(raw)
+
+		var err *htmlError
+		switch tokenType {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return v.errorf(start, "%s", z.Err())
+			}
+
+			// The tokenizer drops a tag that's cut off by the end of the output
+			if raw != "" {
+				return v.unclosedTag(start, raw)
+			}
+
+			return v.finish()
+		case html.TextToken:
+			content := strings.TrimLeftFunc(raw, unicode.IsSpace)
+			if v.document && len(v.open) == 0 && content != "" {
+				return v.errorf(start+len(raw)-len(content), "unexpected content outside of <html>")
+			}
+		case html.CommentToken:
+			if strings.HasPrefix(raw, "<!--") && !strings.HasSuffix(raw, "-->") {
+				return v.errorf(start, "unclosed comment")
+			}
+		case html.DoctypeToken:
+			if !v.document || start != v.contentStart() {
+				return v.errorf(start, "unexpected doctype")
+			}
+			if !strings.HasSuffix(raw, ">") {
+				return v.errorf(start, "unclosed doctype")
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			err = v.startTag(z, start, tokenType == html.SelfClosingTagToken)
+		case html.EndTagToken:
+			err = v.endTag(z, start)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (v *htmlValidator) startTag(z *html.Tokenizer, start int, selfClosing bool) *htmlError {
+	rawName, hasAttributes := z.TagName()
+	name := string(rawName)
+
+	// A tag missing its > swallows the next tag as an attribute
+	for hasAttributes {
+		var key []byte
+		key, _, hasAttributes = z.TagAttr()
+		if strings.ContainsAny(string(key), `<"'`) {
+			return v.errorf(start, "unclosed <%s> tag", name)
+		}
+	}
+
+	if documentElements[name] && !v.document {
+		return v.errorf(start, "<%s> isn't allowed in a fragment", name)
+	}
+
+	if v.document {
+		switch {
+		case v.closedRoot || (len(v.open) == 0 && name != "html"):
+			return v.errorf(start, "unexpected <%s> outside of <html>", name)
+		case name == "html" && len(v.open) > 0:
+			return v.errorf(start, "unexpected <html> inside of <%s>", v.open[len(v.open)-1].name)
+		case name == "html":
+			v.openedRoot = true
+		}
+	}
+
+	if voidElements[name] {
+		return nil
+	}
+
+	inForeignContent := v.foreign > 0 || foreignElements[name]
+	if selfClosing {
+		// Elements in SVG and MathML can be self-closing, but HTML elements
+		// ignore the / and remain open
+		if !inForeignContent {
+			return v.errorf(start, "<%s/> can't be self-closing since it isn't a void element", name)
+		}
+
+		return nil
+	}
+
+	line, column := v.position(start)
+	v.open = append(v.open, openElement{name: name, line: line, column: column})
+
+	// Elements like <title> contain tags instead of text in foreign content
+	if inForeignContent {
+		v.foreign++
+		z.NextIsNotRawText()
+	}
+
+	return nil
+}
+
+func (v *htmlValidator) endTag(z *html.Tokenizer, start int) *htmlError {
+	rawName, _ := z.TagName()
+	name := string(rawName)
+
+	if voidElements[name] {
+		return v.errorf(start, "</%s> closes a void element, which can't have an end tag", name)
+	}
+
+	if len(v.open) == 0 {
+		return v.errorf(start, "unexpected </%s>, no elements are open", name)
+	}
+
+	index := -1
+	for i := len(v.open) - 1; i >= 0; i-- {
+		if v.open[i].name == name {
+			index = i
+			break
+		}
+	}
+
+	if index == -1 {
+		current := v.open[len(v.open)-1]
+		return v.errorf(start, "unexpected </%s>, expected </%s> to close <%s> opened at %d:%d", name, current.name, current.name, current.line, current.column)
+	}
+
+	// Elements opened after the closed element must have been closed, unless
+	// their end tag is optional, like </li>
+	if unclosed, ok := v.lastRequiringEndTag(v.open[index+1:]); ok {
+		return v.errorf(start, "</%s> closes <%s> before <%s> opened at %d:%d is closed", name, name, unclosed.name, unclosed.line, unclosed.column)
+	}
+
+	v.foreign = max(v.foreign-(len(v.open)-index), 0)
+	v.open = v.open[:index]
+	if v.document && name == "html" {
+		v.closedRoot = true
+	}
+
+	return nil
+}
+
+// finish returns an error if an element that must be closed is still open at
+// the end of the output
+func (v *htmlValidator) finish() *htmlError {
+	if unclosed, ok := v.lastRequiringEndTag(v.open); ok {
+		return &htmlError{line: unclosed.line, column: unclosed.column, message: fmt.Sprintf("<%s> is never closed", unclosed.name)}
+	}
+
+	if v.document && !v.openedRoot {
+		return v.errorf(len(v.output), "expected document to contain an <html> element")
+	}
+
+	return nil
+}
+
+// lastRequiringEndTag returns the innermost of the given elements whose end
+// tag can't be omitted
+func (v *htmlValidator) lastRequiringEndTag(elements []openElement) (openElement, bool) {
+	for i := len(elements) - 1; i >= 0; i-- {
+		if !optionalEndTags[elements[i].name] {
+			return elements[i], true
+		}
+	}
+
+	return openElement{}, false
+}
+
+// unclosedTag returns an error for a tag that's cut off by the end of the
+// output, pointing at its unclosed attribute value if it has one.
+func (v *htmlValidator) unclosedTag(start int, raw string) *htmlError {
+	name := strings.TrimPrefix(raw, "<")
+	if end := strings.IndexAny(name, " \t\n\r\f/>"); end != -1 {
+		name = name[:end]
+	}
+	name = strings.ToLower(name)
+
+	var quote byte
+	quoteStart := 0
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case quote != 0:
+			if raw[i] == quote {
+				quote = 0
+			}
+		case raw[i] == '"' || raw[i] == '\'':
+			quote = raw[i]
+			quoteStart = i
+		}
+	}
+
+	if quote != 0 {
+		return v.errorf(start+quoteStart, "unclosed attribute value in <%s>", name)
+	}
+
+	return v.errorf(start, "unclosed <%s> tag", name)
+}
+
+// contentStart returns the position of the first non-whitespace character,
+// where a document's doctype must be
+func (v *htmlValidator) contentStart() int {
+	return len(v.output) - len(strings.TrimLeftFunc(v.output, unicode.IsSpace))
+}
+
+func hasPrefixFold(s string, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func (v *htmlValidator) position(pos int) (line int, column int) {
+	line, column = 1, 1
+	for _, r := range v.output[:pos] {
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+
+	return line, column
+}
+
+func (v *htmlValidator) errorf(pos int, format string, args ...any) *htmlError {
+	line, column := v.position(pos)
+
+	return &htmlError{line: line, column: column, message: fmt.Sprintf(format, args...)}
+}
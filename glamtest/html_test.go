@@ -0,0 +1,134 @@
+package glamtest
+
+import (
+	"testing"
+
+	"github.com/blakewilliams/glam"
+	"github.com/stretchr/testify/require"
+)
+
+type ListComponent struct {
+	Open bool
+}
+
+func TestValidateHTML(t *testing.T) {
+	testCases := map[string]string{
+		`<p>Hello</p>`: "",
+		`<div><img src="a.png"><br/><input type="text" /></div>`:    "",
+		`<ul><li>One</li><li>Two</li></ul>`:                         "",
+		`<!-- <div> --><p>1 < 2</p>`:                                "",
+		`<script>if (a < b) { document.write("<div>") }</script>`:   "",
+		`<svg><path d="M0 0" /><circle r="1"></circle></svg>`:       "",
+		`<p title="a > b">Hi</P>`:                                   "",
+		`<div><span></div></span>`:                                  "1:12: </div> closes <div> before <span> opened at 1:6 is closed",
+		`<div>` + "\n" + `<p>Hi</span>`:                             "2:6: unexpected </span>, expected </p> to close <p> opened at 2:1",
+		`<p>Hi</p></div>`:                                           "1:10: unexpected </div>, no elements are open",
+		"<section>\n  <p>Hi</p>":                                    "1:1: <section> is never closed",
+		`<div/>`:                                                    "1:1: <div/> can't be self-closing since it isn't a void element",
+		`<br></br>`:                                                 "1:5: </br> closes a void element, which can't have an end tag",
+		`<a href="/x>Hi</a>`:                                        `1:9: unclosed attribute value in <a>`,
+		`<p>Hi<!-- </p>`:                                            "1:6: unclosed comment",
+		`<body><p>Hi</p></body>`:                                    "1:1: <body> isn't allowed in a fragment",
+		`<!DOCTYPE html><p>Hi</p>`:                                  "1:1: unexpected doctype",
+		`<div class="a"<p>Hi</p></div>`:                             "1:1: unclosed <div> tag",
+		`<textarea>` + "\n" + `<b>`:                                 "1:1: <textarea> is never closed",
+		`<table><tr><td>1</td></tr></table><ul><li>One<li>Two</ul>`: "",
+		`<ul><li>One</ul><p>Text`:                                   "",
+		`<ul><li><span>One</ul>`:                                    "1:18: </ul> closes <ul> before <span> opened at 1:9 is closed",
+		`<svg><title><b>Chart</b></title></svg>`:                    "",
+		`<math><mi>x</mi><mspace /></math><p>a</p>`:                 "",
+		`<input disabled><label for="a">A</label><select><option>1</option></select>`: "",
+	}
+
+	for output, expected := range testCases {
+		t.Run(output, func(t *testing.T) {
+			err := validateHTML(output, false)
+			if expected == "" {
+				require.Nil(t, err)
+				return
+			}
+
+			require.NotNil(t, err)
+			require.Equal(t, expected, err.Error())
+		})
+	}
+}
+
+func TestValidateHTMLDocument(t *testing.T) {
+	testCases := map[string]string{
+		"<!DOCTYPE html>\n<html><head><title>Hi</title></head><body><p>Hi</p></body></html>\n": "",
+		"<!doctype html><!-- page --><html lang=\"en\"></html>":                                "",
+		"<html></html>":                                   "1:1: expected document to start with <!DOCTYPE html>",
+		"<!DOCTYPE html><p>Hi</p>":                        "1:16: unexpected <p> outside of <html>",
+		"<!DOCTYPE html><html></html><p>Hi</p>":           "1:29: unexpected <p> outside of <html>",
+		"<!DOCTYPE html><html></html>Hi":                  "1:29: unexpected content outside of <html>",
+		"<!DOCTYPE html><html><body><html></html></body>": "1:28: unexpected <html> inside of <body>",
+		"<!DOCTYPE html>":                                 "1:16: expected document to contain an <html> element",
+		"<!DOCTYPE html><html><body><div></html>":         "1:33: </html> closes <html> before <div> opened at 1:28 is closed",
+		"<!DOCTYPE html><html><body><p>Hi":                "",
+	}
+
+	for output, expected := range testCases {
+		t.Run(output, func(t *testing.T) {
+			err := validateHTML(output, true)
+			if expected == "" {
+				require.Nil(t, err)
+				return
+			}
+
+			require.NotNil(t, err)
+			require.Equal(t, expected, err.Error())
+		})
+	}
+}
+
+func TestAssertValidHTML(t *testing.T) {
+	engine := glam.New()
+	err := engine.RegisterComponent(&ListComponent{}, "<ul>\n  {{if .Open}}<li>{{end}}One</li>\n</ul>")
+	require.NoError(t, err)
+
+	AssertValidHTML(t, engine, &ListComponent{Open: true})
+
+	rt := &recordingT{}
+	AssertValidHTML(rt, engine, &ListComponent{Open: false})
+	require.False(t, rt.fatal)
+	require.Equal(t, []string{
+		"expected rendered *glamtest.ListComponent to be valid HTML, 2:6: unexpected </li>, expected </ul> to close <ul> opened at 1:1\n" +
+			"  1 | <ul>\n" +
+			"> 2 |   One</li>\n" +
+			"    |      ^\n" +
+			"  3 | </ul>\n",
+	}, rt.errors)
+}
+
+type MenuComponent struct {
+	Items []string
+}
+
+func TestAssertValidHTML_OptionalEndTags(t *testing.T) {
+	engine := glam.New(glam.WithBalancedOutputCheck())
+	err := engine.RegisterComponent(&MenuComponent{}, `<ul>{{range .Items}}<li>{{.}}{{end}}</ul>`)
+	require.NoError(t, err)
+
+	// The engine's balance check and AssertValidHTML both accept omitted
+	// end tags
+	AssertValidHTML(t, engine, &MenuComponent{Items: []string{"Home", "About"}})
+}
+
+type PageComponent struct{}
+
+func TestAssertValidHTMLDocument(t *testing.T) {
+	engine := glam.New()
+	err := engine.RegisterComponent(&PageComponent{}, `<!DOCTYPE html><html><body><p>Hi</p></body></html>`)
+	require.NoError(t, err)
+
+	AssertValidHTMLDocument(t, engine, &PageComponent{})
+
+	rt := &recordingT{}
+	AssertValidHTML(rt, engine, &PageComponent{})
+	require.Equal(t, []string{
+		"expected rendered *glamtest.PageComponent to be valid HTML, 1:1: unexpected doctype\n" +
+			"> 1 | <!DOCTYPE html><html><body><p>Hi</p></body></html>\n" +
+			"    | ^\n",
+	}, rt.errors)
+}
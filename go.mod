@@ -2,7 +2,10 @@ module github.com/blakewilliams/glam
 
 go 1.23.0
 
-require github.com/stretchr/testify v1.9.0
+require (
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/net v0.43.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -0,0 +1,260 @@
+package glam
+
+import (
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/blakewilliams/glam/internal/template"
+)
+
+// cssPropertyPattern matches CSS property names, including custom properties
+var cssPropertyPattern = regexp.MustCompile(`^-{0,2}[a-zA-Z][a-zA-Z0-9-]*$`)
+
+// CSS builds an inline style from a map of property names to values or a
+// struct, so styles can be computed by a component, e.g.:
+//
+//	style="{{ css .Styles }}"
+//
+// Map properties are sorted by name. Struct properties are rendered in the
+// order the fields are declared, using the `css` tag as the property name when
+// present and the kebab-cased field name otherwise. Fields tagged with
+// `css:"-"` and empty values are omitted.
+//
+// Since the result is marked as safe CSS, values that could escape the
+// property they're assigned to, like `red; background: url(...)`, return an
+// error instead.
+//
+// It's available in templates as `css`.
+func CSS(styles any) (htmltemplate.CSS, error) {
+	v := reflect.ValueOf(styles)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+
+		v = v.Elem()
+	}
+
+	type property struct {
+		name  string
+		value any
+	}
+	var properties []property
+
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		for _, key := range keys {
+			properties = append(properties, property{name: key.String(), value: v.MapIndex(key).Interface()})
+		}
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name := field.Tag.Get("css")
+			if name == "-" {
+				continue
+			}
+
+			if name == "" {
+				name = kebabCase(field.Name)
+			}
+
+			properties = append(properties, property{name: name, value: v.Field(i).Interface()})
+		}
+	default:
+		return "", fmt.Errorf("css expects a map with string keys or a struct, got %T", styles)
+	}
+
+	var b strings.Builder
+	for _, p := range properties {
+		if p.value == nil {
+			continue
+		}
+
+		value := fmt.Sprint(p.value)
+		if value == "" {
+			continue
+		}
+
+		if !cssPropertyPattern.MatchString(p.name) {
+			return "", fmt.Errorf("invalid CSS property name %q", p.name)
+		}
+
+		if !safeCSSValue(value) {
+			return "", fmt.Errorf("unsafe value %q for CSS property %s", value, p.name)
+		}
+
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+
+		fmt.Fprintf(&b, "%s: %s;", p.name, value)
+	}
+
+	return htmltemplate.CSS(b.String()), nil
+}
+
+// safeCSSValue reports whether value can be used as a CSS property value
+// without ending the declaration or loading external resources.
+func safeCSSValue(value string) bool {
+	if strings.ContainsAny(value, ";{}<>\"'`\\") || strings.Contains(value, "/*") {
+		return false
+	}
+
+	lower := strings.ToLower(value)
+
+	return !strings.Contains(lower, "url(") && !strings.Contains(lower, "expression(") && !strings.Contains(lower, "image-set(")
+}
+
+// kebabCase converts a Go field name into a CSS property name, e.g.
+// BackgroundColor becomes background-color.
+func kebabCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word at the start of a capitalized word, including
+			// the last letter of an acronym, e.g. the V in CSSVar
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteRune('-')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// tagNamePattern matches valid element and attribute names for Tag
+var tagNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]*$`)
+
+// unsafeTagNames are elements Tag refuses to render, since their content or
+// attributes can run scripts, load resources, or change how the rest of the
+// page is parsed.
+var unsafeTagNames = map[string]bool{
+	"applet":    true,
+	"base":      true,
+	"embed":     true,
+	"frame":     true,
+	"frameset":  true,
+	"iframe":    true,
+	"link":      true,
+	"math":      true,
+	"meta":      true,
+	"noembed":   true,
+	"noframes":  true,
+	"noscript":  true,
+	"object":    true,
+	"plaintext": true,
+	"script":    true,
+	"style":     true,
+	"svg":       true,
+	"template":  true,
+	"textarea":  true,
+	"title":     true,
+	"xmp":       true,
+}
+
+// tagName returns the lowercased tag name for Tag and EndTag, or an error when
+// it's invalid or unsafe.
+func tagName(name string, defaultName string) (string, error) {
+	if name == "" {
+		name = defaultName
+	}
+
+	if !tagNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid tag name %q", name)
+	}
+
+	name = strings.ToLower(name)
+	if unsafeTagNames[name] {
+		return "", fmt.Errorf("unsafe tag name %q", name)
+	}
+
+	return name, nil
+}
+
+// Tag renders the start tag of an element whose name is chosen at render time,
+// falling back to defaultName when name is empty. Attributes can be passed as
+// alternating name and value pairs, and values are escaped. This allows a
+// component to render a configurable outer element using an `As` field:
+//
+//	{{ tag .As "div" "class" .Class }}{{ .Children }}{{ endTag .As "div" }}
+//
+// Elements that can run scripts or change how the page is parsed, like
+// `script`, `style`, or `iframe`, and `on*`, `style`, and `srcdoc`
+// attributes return an error. URL attributes, like `href`, are filtered like
+// they are by html/template, so unsafe URLs like `javascript:` are replaced
+// with `#ZgotmplZ` unless they're passed as a template.URL.
+//
+// It's available in templates as `tag`.
+func Tag(name string, defaultName string, attrs ...any) (htmltemplate.HTML, error) {
+	name, err := tagName(name, defaultName)
+	if err != nil {
+		return "", err
+	}
+
+	if len(attrs)%2 != 0 {
+		return "", fmt.Errorf("tag expects attribute name and value pairs, got %d arguments", len(attrs))
+	}
+
+	var b strings.Builder
+	b.WriteString("<")
+	b.WriteString(name)
+
+	for i := 0; i < len(attrs); i += 2 {
+		attrName, ok := attrs[i].(string)
+		if !ok || !tagNamePattern.MatchString(attrName) {
+			return "", fmt.Errorf("invalid attribute name %v for tag %s", attrs[i], name)
+		}
+
+		attrName = strings.ToLower(attrName)
+		if strings.HasPrefix(attrName, "on") || attrName == "style" || attrName == "srcdoc" {
+			return "", fmt.Errorf("unsafe attribute name %s for tag %s", attrName, name)
+		}
+
+		value := fmt.Sprint(attrs[i+1])
+		if _, trusted := attrs[i+1].(htmltemplate.URL); !trusted && template.IsURLAttribute(attrName) {
+			value = template.FilterURL(value)
+		}
+
+		fmt.Fprintf(&b, ` %s="%s"`, attrName, html.EscapeString(value))
+	}
+
+	b.WriteString(">")
+
+	return htmltemplate.HTML(b.String()), nil
+}
+
+// EndTag renders the end tag matching a Tag call with the same arguments.
+// Void elements, like `br` or `img`, have no end tag so nothing is rendered.
+//
+// It's available in templates as `endTag`.
+func EndTag(name string, defaultName string) (htmltemplate.HTML, error) {
+	name, err := tagName(name, defaultName)
+	if err != nil {
+		return "", err
+	}
+
+	if template.IsVoidElement(name) {
+		return "", nil
+	}
+
+	return htmltemplate.HTML("</" + name + ">"), nil
+}
@@ -0,0 +1,216 @@
+package glam
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type BoxComponent struct {
+	As       string
+	Class    string
+	Children template.HTML
+}
+
+var boxTemplate = `{{ tag .As "div" "class" .Class }}{{ .Children }}{{ endTag .As "div" }}`
+
+func TestTag(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		expected string
+	}{
+		{
+			desc:     "default tag",
+			template: `<BoxComponent class="card">Hello</BoxComponent>`,
+			expected: `<div class="card">Hello</div>`,
+		},
+		{
+			desc:     "tag from attribute",
+			template: `<BoxComponent as="section" class="card">Hello</BoxComponent>`,
+			expected: `<section class="card">Hello</section>`,
+		},
+		{
+			desc:     "void tag has no end tag",
+			template: `<BoxComponent as="hr" class="divider" />`,
+			expected: `<hr class="divider">`,
+		},
+		{
+			desc:     "attribute values are escaped",
+			template: `<BoxComponent class="{{.Value}}"></BoxComponent>`,
+			expected: `<div class="&#34;&gt;&lt;script&gt;"></div>`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New(nil)
+			err := engine.RegisterComponent(&BoxComponent{}, boxTemplate)
+			require.NoError(t, err)
+			err = engine.RegisterComponent(&TestFSComponent{}, tC.template)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, &TestFSComponent{Value: `"><script>`})
+			require.NoError(t, err)
+			require.Equal(t, tC.expected, b.String())
+		})
+	}
+}
+
+func TestTag_Errors(t *testing.T) {
+	_, err := Tag("div onclick=alert(1)", "div")
+	require.ErrorContains(t, err, `invalid tag name "div onclick=alert(1)"`)
+
+	_, err = Tag("div", "div", "class")
+	require.ErrorContains(t, err, "tag expects attribute name and value pairs, got 1 arguments")
+
+	_, err = Tag("div", "div", "on click", "alert(1)")
+	require.ErrorContains(t, err, "invalid attribute name on click for tag div")
+
+	_, err = EndTag("/div><script>", "div")
+	require.ErrorContains(t, err, `invalid tag name "/div><script>"`)
+
+	_, err = Tag("SCRIPT", "div")
+	require.ErrorContains(t, err, `unsafe tag name "script"`)
+
+	_, err = EndTag("iframe", "div")
+	require.ErrorContains(t, err, `unsafe tag name "iframe"`)
+
+	_, err = Tag("div", "div", "onClick", "alert(1)")
+	require.ErrorContains(t, err, "unsafe attribute name onclick for tag div")
+
+	_, err = Tag("div", "div", "style", "color: red")
+	require.ErrorContains(t, err, "unsafe attribute name style for tag div")
+}
+
+func TestTag_URLAttributes(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		attrs    []any
+		expected template.HTML
+	}{
+		{
+			desc:     "javascript URLs are filtered",
+			attrs:    []any{"href", "javascript:alert(1)"},
+			expected: `<a href="#ZgotmplZ">`,
+		},
+		{
+			desc:     "scheme is case-insensitive",
+			attrs:    []any{"HREF", " JavaScript:alert(1)"},
+			expected: `<a href="#ZgotmplZ">`,
+		},
+		{
+			desc:     "data attributes with URL names are filtered",
+			attrs:    []any{"data-src", "data:text/html,<script>"},
+			expected: `<a data-src="#ZgotmplZ">`,
+		},
+		{
+			desc:     "safe URLs are kept",
+			attrs:    []any{"href", "https://example.com/?q=a&b", "ping", "/track"},
+			expected: `<a href="https://example.com/?q=a&amp;b" ping="/track">`,
+		},
+		{
+			desc:     "relative URLs are kept",
+			attrs:    []any{"href", "/docs/a:b"},
+			expected: `<a href="/docs/a:b">`,
+		},
+		{
+			desc:     "template.URL values are trusted",
+			attrs:    []any{"href", template.URL("tel:+15555555555")},
+			expected: `<a href="tel:+15555555555">`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			tag, err := Tag("a", "div", tC.attrs...)
+			require.NoError(t, err)
+			require.Equal(t, tC.expected, tag)
+		})
+	}
+}
+
+type CardStyles struct {
+	BackgroundColor string
+	ZIndex          int
+	Width           string `css:"max-width"`
+	Theme           string `css:"-"`
+	Border          string
+	CSSVar          string `css:"--accent"`
+}
+
+type StyledComponent struct {
+	Styles any
+}
+
+func TestCSS(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		styles   any
+		expected string
+	}{
+		{
+			desc:     "map is sorted by property",
+			styles:   map[string]string{"margin": "0 auto", "color": "red", "--gap": "4px", "display": ""},
+			expected: `<div style="--gap: 4px; color: red; margin: 0 auto;"></div>`,
+		},
+		{
+			desc:     "struct uses field order",
+			styles:   CardStyles{BackgroundColor: "rgb(0, 0, 0)", ZIndex: 2, Width: "10em", Theme: "dark", CSSVar: "#fff"},
+			expected: `<div style="background-color: rgb(0, 0, 0); z-index: 2; max-width: 10em; --accent: #fff;"></div>`,
+		},
+		{
+			desc:     "struct pointer",
+			styles:   &CardStyles{Border: "1px solid"},
+			expected: `<div style="z-index: 0; border: 1px solid;"></div>`,
+		},
+		{
+			desc:     "nil pointer",
+			styles:   (*CardStyles)(nil),
+			expected: `<div style=""></div>`,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			engine := New()
+			err := engine.RegisterComponent(&StyledComponent{}, `<div style="{{ css .Styles }}"></div>`)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = engine.Render(&b, &StyledComponent{Styles: tC.styles})
+			require.NoError(t, err)
+			require.Equal(t, tC.expected, b.String())
+		})
+	}
+}
+
+func TestCSS_Errors(t *testing.T) {
+	_, err := CSS("color: red")
+	require.ErrorContains(t, err, "css expects a map with string keys or a struct, got string")
+
+	_, err = CSS(map[string]string{"color: red; x": "blue"})
+	require.ErrorContains(t, err, `invalid CSS property name "color: red; x"`)
+
+	unsafe := []string{
+		"red; background: blue",
+		`red" onclick="alert(1)`,
+		"url(https://example.com/track)",
+		"expression(alert(1))",
+		"red /* comment",
+		"}</style><script>",
+	}
+	for _, value := range unsafe {
+		_, err = CSS(map[string]string{"color": value})
+		require.ErrorContains(t, err, "unsafe value")
+	}
+
+	engine := New()
+	err = engine.RegisterComponent(&StyledComponent{}, `<div style="{{ css .Styles }}"></div>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = engine.Render(&b, &StyledComponent{Styles: map[string]string{"color": "red;position:fixed"}})
+	require.ErrorContains(t, err, `unsafe value "red;position:fixed" for CSS property color`)
+}
@@ -0,0 +1,186 @@
+package template
+
+import (
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// Analysis describes what rendering a template involves, determined from its
+// compiled parse trees without executing it.
+type Analysis struct {
+	// Components are the names of the components the template renders
+	Components []string
+	// Funcs are the names of the funcs the template calls, excluding
+	// text/template's builtins and glam's internal funcs
+	Funcs []string
+	// Fields are the fields, methods, and variable chains the template
+	// accesses, like `.User.Name`. Fields accessed inside of `range` and
+	// `with` are relative to their dot.
+	Fields []string
+	// Dynamic are the commands that render components chosen at render
+	// time, like `render .` or `call .Render`
+	Dynamic []string
+}
+
+// builtinFuncs are the funcs text/template provides to every template
+var builtinFuncs = map[string]bool{
+	"and": true, "call": true, "html": true, "index": true, "slice": true,
+	"js": true, "len": true, "not": true, "or": true, "print": true,
+	"printf": true, "println": true, "urlquery": true, "eq": true, "ge": true,
+	"gt": true, "le": true, "lt": true, "ne": true,
+}
+
+// dynamicFuncs are the funcs that render a value that's only known at render
+// time
+var dynamicFuncs = map[string]bool{
+	"call":   true,
+	"render": true,
+}
+
+type analyzer struct {
+	base       executor
+	visited    map[string]bool
+	components map[string]bool
+	funcs      map[string]bool
+	fields     map[string]bool
+	dynamic    []string
+}
+
+// Analyze walks the parse trees reachable from the template, following child
+// content and `{{template}}` actions, and reports the components, funcs, and
+// fields it uses. Components rendered by the template aren't followed, since
+// their templates belong to the renderer.
+func (t *Template) Analyze() Analysis {
+	a := &analyzer{
+		base:       t.base,
+		visited:    make(map[string]bool),
+		components: make(map[string]bool),
+		funcs:      make(map[string]bool),
+		fields:     make(map[string]bool),
+	}
+	a.walkTree(t.Name)
+
+	return Analysis{
+		Components: sortedKeys(a.components),
+		Funcs:      sortedKeys(a.funcs),
+		Fields:     sortedKeys(a.fields),
+		Dynamic:    a.dynamic,
+	}
+}
+
+func (a *analyzer) walkTree(name string) {
+	if name == "" || a.visited[name] {
+		return
+	}
+	a.visited[name] = true
+
+	tree := a.base.tree(name)
+	if tree == nil {
+		return
+	}
+
+	a.walkList(tree.Root)
+}
+
+func (a *analyzer) walkList(list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+
+	for _, node := range list.Nodes {
+		switch node := node.(type) {
+		case *parse.ActionNode:
+			a.walkPipe(node.Pipe)
+		case *parse.IfNode:
+			a.walkBranch(&node.BranchNode)
+		case *parse.RangeNode:
+			a.walkBranch(&node.BranchNode)
+		case *parse.WithNode:
+			a.walkBranch(&node.BranchNode)
+		case *parse.TemplateNode:
+			a.walkPipe(node.Pipe)
+			a.walkTree(node.Name)
+		}
+	}
+}
+
+func (a *analyzer) walkBranch(branch *parse.BranchNode) {
+	a.walkPipe(branch.Pipe)
+	a.walkList(branch.List)
+	a.walkList(branch.ElseList)
+}
+
+func (a *analyzer) walkPipe(pipe *parse.PipeNode) {
+	if pipe == nil {
+		return
+	}
+
+	for _, cmd := range pipe.Cmds {
+		a.walkCommand(cmd)
+	}
+}
+
+// walkCommand records the funcs and fields used by the command, following
+// the child content of the components it renders, e.g.:
+//
+//	__glamRenderComponent "Name" "identifier" (__glamDict "name" (.Name)) .
+//	__glamChildComponent "identifier" (__glamDict "name" (.Name)) .
+func (a *analyzer) walkCommand(cmd *parse.CommandNode) {
+	args := cmd.Args
+	if len(args) == 0 {
+		return
+	}
+
+	switch {
+	case isIdentifier(args[0], "__glamRenderComponent") && len(args) > 2:
+		if name, ok := args[1].(*parse.StringNode); ok {
+			a.components[name.Text] = true
+		}
+		if identifier, ok := args[2].(*parse.StringNode); ok {
+			a.walkTree(identifier.Text)
+		}
+	case isIdentifier(args[0], "__glamChildComponent") && len(args) > 1:
+		if identifier, ok := args[1].(*parse.StringNode); ok {
+			a.walkTree(identifier.Text)
+		}
+	}
+
+	if identifier, ok := args[0].(*parse.IdentifierNode); ok && dynamicFuncs[identifier.Ident] {
+		a.dynamic = append(a.dynamic, cmd.String())
+	}
+
+	for _, arg := range args {
+		a.walkArg(arg)
+	}
+}
+
+func (a *analyzer) walkArg(node parse.Node) {
+	switch node := node.(type) {
+	case *parse.IdentifierNode:
+		if !builtinFuncs[node.Ident] && !strings.HasPrefix(node.Ident, "__glam") {
+			a.funcs[node.Ident] = true
+		}
+	case *parse.FieldNode:
+		a.fields[node.String()] = true
+	case *parse.VariableNode:
+		if len(node.Ident) > 1 {
+			a.fields[node.String()] = true
+		}
+	case *parse.ChainNode:
+		a.fields[node.String()] = true
+		a.walkArg(node.Node)
+	case *parse.PipeNode:
+		a.walkPipe(node)
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
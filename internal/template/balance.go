@@ -0,0 +1,214 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnbalancedError is returned when Options.CheckBalance is set and a
+// component renders a start tag without its end tag, or an end tag without
+// its start tag.
+type UnbalancedError struct {
+	// Component is the name of the component that rendered the tag. For child
+	// content, it's the component whose template contains the content.
+	Component string
+	// Tag is the lowercased name of the tag, e.g. "div"
+	Tag string
+	// Closing is true when an end tag was rendered without its start tag
+	Closing bool
+	// ChildContent is true when the tag was rendered by child content
+	ChildContent bool
+}
+
+func (e *UnbalancedError) Error() string {
+	source := "component " + e.Component
+	if e.ChildContent {
+		source = "child content in component " + e.Component
+	}
+
+	if e.Closing {
+		return fmt.Sprintf("%s rendered </%s> without opening it", source, e.Tag)
+	}
+
+	return fmt.Sprintf("%s rendered <%s> without closing it", source, e.Tag)
+}
+
+// optionalEndTags are HTML elements whose end tag can be omitted, so they're
+// implicitly closed by their parent's end tag or the end of the output
+var optionalEndTags htmlTags = map[string]bool{
+	"body":     true,
+	"colgroup": true,
+	"dd":       true,
+	"dt":       true,
+	"head":     true,
+	"html":     true,
+	"li":       true,
+	"optgroup": true,
+	"option":   true,
+	"p":        true,
+	"rp":       true,
+	"rt":       true,
+	"tbody":    true,
+	"td":       true,
+	"tfoot":    true,
+	"th":       true,
+	"thead":    true,
+	"tr":       true,
+}
+
+// foreignTags are elements that start foreign content, whose elements can be
+// self-closing and whose `<title>` and `<style>` contain tags instead of text
+var foreignTags htmlTags = map[string]bool{
+	"math": true,
+	"svg":  true,
+}
+
+// rawTextTags are HTML elements whose content is text, so tags in it aren't
+// parsed until their end tag
+var rawTextTags htmlTags = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"textarea": true,
+	"title":    true,
+}
+
+// checkBalance returns the first tag in the given HTML that's opened without
+// being closed, or closed without being opened. Void elements, self-closing
+// tags in SVG and MathML like `<path />`, comments, and the content of raw
+// text elements like `<script>` are skipped. HTML elements like `<div/>`
+// ignore the / and must still be closed. Inside of SVG and MathML content, elements like
+// `<title>` contain tags instead of text.
+func checkBalance(html string) (tag string, closing bool, ok bool) {
+	var open []string
+	foreign := 0
+
+	for i := 0; i < len(html); {
+		next := strings.IndexByte(html[i:], '<')
+		if next == -1 {
+			break
+		}
+		i += next
+		rest := html[i:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end == -1 {
+				return "", false, true
+			}
+			i += 4 + end + 3
+		case strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"):
+			i += tagEnd(rest)
+		case strings.HasPrefix(rest, "</") && len(rest) > 2 && isASCIILetter(rest[2]):
+			name := strings.ToLower(tagNameOf(rest[2:]))
+			i += tagEnd(rest)
+
+			index := lastIndex(open, name)
+			if index == -1 {
+				return name, true, false
+			}
+
+			// Tags opened after the closed tag must have been closed, unless
+			// their end tag is optional
+			for _, unclosed := range open[index+1:] {
+				if !optionalEndTags.IsKnown(unclosed) {
+					return unclosed, false, false
+				}
+			}
+			foreign = max(foreign-(len(open)-index), 0)
+			open = open[:index]
+		case len(rest) > 1 && isASCIILetter(rest[1]):
+			name := strings.ToLower(tagNameOf(rest[1:]))
+			end := tagEnd(rest)
+			i += end
+
+			if voidHTMLTags.IsKnown(name) {
+				continue
+			}
+
+			// Elements in SVG and MathML can be self-closing, but HTML
+			// elements ignore the / and remain open
+			inForeignContent := foreign > 0 || foreignTags.IsKnown(name)
+			selfClosing := end >= 2 && rest[end-1] == '>' && rest[end-2] == '/'
+			if selfClosing && inForeignContent {
+				continue
+			}
+
+			if rawTextTags.IsKnown(name) && !inForeignContent {
+				endTag := indexFold(html[i:], "</"+name)
+				if endTag == -1 {
+					return name, false, false
+				}
+				i += endTag + tagEnd(html[i+endTag:])
+				continue
+			}
+
+			open = append(open, name)
+			if inForeignContent {
+				foreign++
+			}
+		default:
+			i++
+		}
+	}
+
+	for _, unclosed := range open {
+		if !optionalEndTags.IsKnown(unclosed) {
+			return unclosed, false, false
+		}
+	}
+
+	return "", false, true
+}
+
+// tagNameOf returns the tag name at the start of s
+func tagNameOf(s string) string {
+	end := strings.IndexAny(s, " \t\n\r\f/>")
+	if end == -1 {
+		return s
+	}
+
+	return s[:end]
+}
+
+// tagEnd returns the length of the tag at the start of s, including its `>`,
+// skipping over quoted attribute values.
+func tagEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '>':
+			return i + 1
+		}
+	}
+
+	return len(s)
+}
+
+func lastIndex(values []string, value string) int {
+	for i := len(values) - 1; i >= 0; i-- {
+		if values[i] == value {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// indexFold returns the index of the first case-insensitive match of substr
+// in s, or -1
+func indexFold(s string, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+
+	return -1
+}
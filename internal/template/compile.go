@@ -1,65 +1,138 @@
 package template
 
 import (
-	"crypto/rand"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
-type define struct {
-	Node       *Node
-	identifier string
-}
+type (
+	define struct {
+		Node       *Node
+		identifier string
+	}
+
+	// compiler tracks state while compiling a single template, so that define
+	// identifiers are unique and deterministic within that template.
+	compiler struct {
+		defines int
+
+		// staticAttributes are attribute maps for components that only have
+		// literal attributes, so they can be built once instead of per render
+		staticAttributes []map[string]any
+
+		// constant evaluates attribute pipelines that don't depend on render
+		// data, returning false if the pipeline isn't constant. When nil,
+		// pipelines are always evaluated at render time.
+		constant func(pipeline string) (any, bool)
+
+		// typedLiteral converts literal attribute text passed to a component
+		// to the type of the field it populates, returning false if it's
+		// passed as a string. When nil, literals are always strings.
+		typedLiteral func(component string, attribute string, value string) (any, bool)
+	}
+)
+
+func (c *compiler) newDefine(node *Node) *define {
+	// Identifiers are generated from the tag name, which is validated by the
+	// parser, so this should never happen
+	if !componentTagPattern.MatchString(node.TagName) {
+		panic(fmt.Sprintf("bug: invalid component tag name %q", node.TagName))
+	}
+
+	c.defines++
 
-func newDefine(node *Node) *define {
 	return &define{
 		Node:       node,
-		identifier: fmt.Sprintf("glam__%s__%s", node.TagName, randomString()),
+		identifier: fmt.Sprintf("glam__%s__%d", node.TagName, c.defines),
 	}
 }
-func compile(nodes []*Node) string {
-	primaryContent, defines := rawCompile(nodes)
+
+// flushPoint is emitted between root level nodes and after each root level
+// component so renders can flush output to the client. It's wrapped in an `if` so that it never produces
+// output, regardless of the html/template context it's in.
+const flushPoint = `{{if __glamFlush}}{{end}}`
+
+// compile returns the html/template text for the given nodes along with the
+// static attribute maps referenced by `__glamStaticAttributes`.
+func compile(nodes []*Node) (string, []map[string]any) {
+	return (&compiler{}).compile(nodes)
+}
+
+// compile returns the html/template text for the given nodes along with the
+// static attribute maps referenced by `__glamStaticAttributes`.
+func (c *compiler) compile(nodes []*Node) (string, []map[string]any) {
+	primaryContent, defines := c.rawCompile(nodes, true)
 
 	defineText := strings.Join(defines, "")
 
-	return defineText + primaryContent
+	return defineText + primaryContent, c.staticAttributes
 }
 
 // rawCompile accepts nodes and returns primaryContent, which is rendered in the
 // immediate context, and defineContent, which is content that must be wrapped
 // in a `{{define}}` statement, so it can be rendered and passed to a component
-// as `Children`.
-func rawCompile(nodes []*Node) (primaryContent string, defineContent []string) {
-	// defineReferences is a map of components that need a {{define}} statement so
-	// they can be passed child nodes as HTML text
-	defineReferences := make(map[string]*define)
+// as `Children`. When flushPoints is true, flush points are emitted after raw
+// nodes and after each component that isn't inside of a Go template action.
+func (c *compiler) rawCompile(nodes []*Node, flushPoints bool) (primaryContent string, defineContent []string) {
+	// defineReferences are the components that need a {{define}} statement so
+	// they can be passed child nodes as HTML text, in the order they appear
+	defineReferences := make([]*define, 0)
 	var rawContent strings.Builder
+	inAction := false
+
+	for i, node := range nodes {
+		// Components write their own flush point after rendering. A flush
+		// point after a `{` would start with `{{{`, which isn't a valid
+		// action.
+		if flushPoints && i > 0 && nodes[i-1].Type == NodeTypeRaw && !inAction && !strings.HasSuffix(rawContent.String(), "{") {
+			rawContent.WriteString(flushPoint)
+		}
 
-	for _, node := range nodes {
 		switch {
 		case node.Type == NodeTypeRaw:
 			rawContent.WriteString(node.Raw)
-		case node.Type == NodeTypeComponent && len(node.Children) > 0:
-			definition := newDefine(node)
-			defineReferences[definition.identifier] = definition
+			inAction = endsInAction(node.Raw, inAction)
+		case node.Type == NodeTypeComponent:
+			identifier := ""
+			if len(node.Children) > 0 {
+				definition := c.newDefine(node)
+				defineReferences = append(defineReferences, definition)
+				identifier = definition.identifier
+			}
+
+			// The range wraps the condition so the condition is evaluated
+			// for each element
+			if node.Range != "" {
+				rawContent.WriteString(fmt.Sprintf(`{{range %s}}`, node.Range))
+			}
 
-			var attributes strings.Builder
+			if node.If != "" {
+				rawContent.WriteString(fmt.Sprintf(`{{if %s}}`, node.If))
+			}
 
-			attributes.WriteString(`(__glamDict`)
+			childComponents := c.compileChildComponents(node.ChildComponents, &defineReferences)
+			render := fmt.Sprintf(`__glamRenderComponent "%s" "%s" %s .`, node.TagName, identifier, c.compileAttributes(node.TagName, node.Attributes, childComponents))
+			if node.Key != "" {
+				render = fmt.Sprintf(`__glamKeyed %s (%s)`, keyValue(node.Key), render)
+			}
+			rawContent.WriteString(fmt.Sprintf(`{{%s}}`, render))
 
-			for k, v := range node.Attributes {
-				if strings.HasPrefix(v, "{{") {
-					v = strings.Trim(v, "{} ")
-					attributes.WriteString(fmt.Sprintf(` "%s" (%s)`, k, v))
-					continue
-				}
-				attributes.WriteString(fmt.Sprintf(` "%s" "%s"`, k, v))
+			// Flushing inside of the range flushes after each element
+			if flushPoints && !inAction {
+				rawContent.WriteString(flushPoint)
 			}
 
-			attributes.WriteString(`)`)
-			rawContent.WriteString(fmt.Sprintf(`{{__glamRenderComponent "%s" "%s" %s .}}`, node.TagName, definition.identifier, attributes.String()))
-		case node.Type == NodeTypeComponent && len(node.Children) == 0:
-			rawContent.WriteString(fmt.Sprintf(`{{__glamRenderComponent "%s" "" nil .}}`, node.TagName))
+			if node.If != "" {
+				rawContent.WriteString(`{{end}}`)
+			}
+
+			if node.Range != "" {
+				rawContent.WriteString(`{{end}}`)
+			}
 		}
 	}
 
@@ -69,7 +142,7 @@ func rawCompile(nodes []*Node) (primaryContent string, defineContent []string) {
 	defineCalls := make([]string, 0, len(defineReferences))
 	for _, definition := range defineReferences {
 		var currentContent strings.Builder
-		currentDefineContent, subDefines := rawCompile(definition.Node.Children)
+		currentDefineContent, subDefines := c.rawCompile(definition.Node.Children, false)
 
 		currentContent.WriteString(fmt.Sprintf(`{{define "%s"}}%s{{end}}`, definition.identifier, currentDefineContent))
 		defineCalls = append(defineCalls, subDefines...)
@@ -79,12 +152,286 @@ func rawCompile(nodes []*Node) (primaryContent string, defineContent []string) {
 	return rawContent.String(), defineCalls
 }
 
-func randomString() string {
-	b := make([]byte, 9)
+// compileChildComponents returns the pipelines that collect the given child
+// components, keyed by the attribute name of the field they're collected
+// into. Child components with child content are added to defineReferences so
+// their content can be rendered when they're instantiated.
+func (c *compiler) compileChildComponents(childComponents map[string][]*Node, defineReferences *[]*define) map[string]string {
+	if len(childComponents) == 0 {
+		return nil
+	}
+
+	// Sort the fields so define identifiers are deterministic
+	names := make([]string, 0, len(childComponents))
+	for name := range childComponents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pipelines := make(map[string]string, len(childComponents))
+	for _, name := range names {
+		var b strings.Builder
+
+		b.WriteString(`(__glamChildComponents`)
+		for _, child := range childComponents[name] {
+			identifier := ""
+			if len(child.Children) > 0 {
+				definition := c.newDefine(child)
+				*defineReferences = append(*defineReferences, definition)
+				identifier = definition.identifier
+			}
+
+			grandchildren := c.compileChildComponents(child.ChildComponents, defineReferences)
+			b.WriteString(fmt.Sprintf(` (__glamChildComponent "%s" %s .)`, identifier, c.compileAttributes("", child.Attributes, grandchildren)))
+		}
+		b.WriteString(`)`)
+
+		pipelines[name] = b.String()
+	}
+
+	return pipelines
+}
+
+// compileAttributes returns a `__glamDict` call that builds the attributes
+// passed to a component, including the pipelines that collect its child
+// components. Attributes bound to a Go template action are evaluated in the
+// current context, everything else is passed as a string. Actions that are
+// constant are evaluated once at compile time instead. When every attribute
+// is a literal or constant, the map is built once at compile time and
+// referenced using `__glamStaticAttributes` instead. Literals that populate
+// fields typed as one of html/template's safe strings are converted to the
+// field's type, and referenced from a static attribute map.
+func (c *compiler) compileAttributes(component string, attributes map[string]string, childComponents map[string]string) string {
+	if len(attributes) == 0 && len(childComponents) == 0 {
+		return "nil"
+	}
+
+	if len(childComponents) == 0 {
+		if static, ok := c.literalAttributes(component, attributes); ok {
+			c.staticAttributes = append(c.staticAttributes, static)
+
+			return fmt.Sprintf(`(__glamStaticAttributes %d)`, len(c.staticAttributes)-1)
+		}
+	}
+
+	var b strings.Builder
+
+	b.WriteString(`(__glamDict`)
+
+	// Sort the attributes so the compiled output is deterministic
+	keys := make([]string, 0, len(attributes)+len(childComponents))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	for k := range childComponents {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if pipeline, ok := childComponents[k]; ok {
+			b.WriteString(fmt.Sprintf(` %s %s`, strconv.Quote(k), pipeline))
+			continue
+		}
+
+		v := attributes[k]
+
+		// Optional attributes are removed before the component is rendered
+		// when their value is empty
+		if name, optional := strings.CutSuffix(k, "?"); optional {
+			pipeline, _ := actionPipeline(v)
+			b.WriteString(fmt.Sprintf(` %s (__glamOptional (%s))`, strconv.Quote(name), pipeline))
+			continue
+		}
+
+		if pipeline, ok := actionPipeline(v); ok {
+			if literal, ok := c.constantLiteral(pipeline); ok {
+				b.WriteString(fmt.Sprintf(` %s %s`, strconv.Quote(k), literal))
+				continue
+			}
+
+			b.WriteString(fmt.Sprintf(` %s (%s)`, strconv.Quote(k), pipeline))
+			continue
+		}
+
+		if value := c.literalValue(component, k, v); value != any(v) {
+			c.staticAttributes = append(c.staticAttributes, map[string]any{k: value})
+			b.WriteString(fmt.Sprintf(` %s (index (__glamStaticAttributes %d) %s)`, strconv.Quote(k), len(c.staticAttributes)-1, strconv.Quote(k)))
+			continue
+		}
 
-	if _, err := rand.Read(b); err != nil {
-		panic(err)
+		// Quote literal values so quotes, backslashes, and braces are passed
+		// as inert strings instead of being interpreted as template syntax
+		b.WriteString(fmt.Sprintf(` %s %s`, strconv.Quote(k), strconv.Quote(v)))
+	}
+
+	b.WriteString(`)`)
+
+	return b.String()
+}
+
+// literalAttributes returns the attributes as a map that can be passed to a
+// component if none of them are bound to a Go template action, other than
+// constant actions.
+func (c *compiler) literalAttributes(component string, attributes map[string]string) (map[string]any, bool) {
+	static := make(map[string]any, len(attributes))
+	for k, v := range attributes {
+		if strings.HasSuffix(k, "?") {
+			return nil, false
+		}
+
+		if pipeline, ok := actionPipeline(v); ok {
+			if c.constant == nil {
+				return nil, false
+			}
+
+			value, ok := c.constant(pipeline)
+			if !ok {
+				return nil, false
+			}
+
+			static[k] = value
+			continue
+		}
+
+		static[k] = c.literalValue(component, k, v)
+	}
+
+	return static, true
+}
+
+// literalValue returns the value passed to the given component for literal
+// attribute text, converted to the type of the field it populates if needed.
+func (c *compiler) literalValue(component string, attribute string, value string) any {
+	if c.typedLiteral != nil && component != "" {
+		if typed, ok := c.typedLiteral(component, attribute, value); ok {
+			return typed
+		}
+	}
+
+	return value
+}
+
+// constantLiteral returns the template literal for the value of the given
+// pipeline, if it's constant and its value can be written as a literal that
+// evaluates to the same type.
+func (c *compiler) constantLiteral(pipeline string) (string, bool) {
+	if c.constant == nil {
+		return "", false
+	}
+
+	value, ok := c.constant(pipeline)
+	if !ok {
+		return "", false
+	}
+
+	switch value := value.(type) {
+	case string:
+		return strconv.Quote(value), true
+	case bool:
+		return strconv.FormatBool(value), true
+	case int:
+		return strconv.Itoa(value), true
+	case float64:
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return "", false
+		}
+
+		literal := strconv.FormatFloat(value, 'g', -1, 64)
+		// Numbers without a decimal point or exponent are parsed as ints
+		if !strings.ContainsAny(literal, ".e") {
+			literal += ".0"
+		}
+
+		return literal, true
+	}
+
+	return "", false
+}
+
+// actionPipeline returns the pipeline of the given attribute value if the
+// value is a single Go template action, e.g. `{{ .Name }}`. Values containing
+// anything else, like `a {{.B}}` or `{{.A}}{{.B}}`, are literal values. }}
+// inside of string literals doesn't end the action, e.g.
+// `{{ printf "}}%s" .A }}`.
+func actionPipeline(value string) (string, bool) {
+	if !strings.HasPrefix(value, "{{") || len(value) < 4 || actionEnd(value, 2) != len(value) {
+		return "", false
+	}
+
+	pipeline := value[2 : len(value)-2]
+
+	// Remove trim markers, which aren't valid inside of a pipeline
+	pipeline = strings.TrimPrefix(pipeline, "- ")
+	pipeline = strings.TrimSuffix(pipeline, " -")
+	pipeline = strings.TrimSpace(pipeline)
+	if pipeline == "" {
+		return "", false
+	}
+
+	return pipeline, true
+}
+
+// withoutComments removes Go template comments from an attribute value
+// passed to a component, since they render nothing and would otherwise make
+// the value literal text, e.g. `{{/* TODO */}}{{.Name}}`. Trim markers remove
+// the whitespace next to the comment like they do in templates.
+func withoutComments(value string) string {
+	if !strings.Contains(value, "/*") {
+		return value
+	}
+
+	var b strings.Builder
+	pos := 0
+	for {
+		start := strings.Index(value[pos:], "{{")
+		if start == -1 {
+			break
+		}
+		start += pos
+
+		end := actionEnd(value, start+2)
+		if end == -1 {
+			break
+		}
+
+		action := value[start:end]
+		if !strings.HasPrefix(strings.TrimPrefix(action[2:], "- "), "/*") {
+			b.WriteString(value[pos:end])
+			pos = end
+			continue
+		}
+
+		before := value[pos:start]
+		if strings.HasPrefix(action, "{{- ") {
+			before = strings.TrimRightFunc(before, unicode.IsSpace)
+		}
+		b.WriteString(before)
+
+		pos = end
+		if strings.HasSuffix(action, " -}}") {
+			pos = len(value) - len(strings.TrimLeftFunc(value[pos:], unicode.IsSpace))
+		}
+	}
+
+	b.WriteString(value[pos:])
+
+	return b.String()
+}
+
+// endsInAction reports whether the given content leaves a Go template action
+// open, e.g. `{{ "` when a `<` inside of an action split raw content.
+func endsInAction(content string, inAction bool) bool {
+	for i := 0; i < len(content)-1; i++ {
+		switch content[i : i+2] {
+		case "{{":
+			inAction = true
+			i++
+		case "}}":
+			inAction = false
+			i++
+		}
 	}
 
-	return fmt.Sprintf("%x", b)
+	return inAction
 }
@@ -0,0 +1,114 @@
+package template
+
+import (
+	"io"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+// pureBuiltins are the built-in template funcs that always return the same
+// result for the same arguments. `call` is excluded since it calls an
+// arbitrary function.
+var pureBuiltins = map[string]bool{
+	"and":      true,
+	"eq":       true,
+	"ge":       true,
+	"gt":       true,
+	"html":     true,
+	"index":    true,
+	"js":       true,
+	"le":       true,
+	"len":      true,
+	"lt":       true,
+	"ne":       true,
+	"not":      true,
+	"or":       true,
+	"print":    true,
+	"printf":   true,
+	"println":  true,
+	"slice":    true,
+	"urlquery": true,
+}
+
+// constantValue evaluates the given attribute pipeline at compile time if it
+// only consists of literals and pure funcs, e.g. `printf "%d items" 5`.
+// Pipelines that reference data, variables, or impure funcs aren't constant,
+// and neither are pipelines that fail to evaluate, so that errors are
+// reported when rendering. Only funcs allowed by the template's policy are
+// evaluated.
+func (t *Template) constantValue(pipeline string) (any, bool) {
+	funcs := t.allowedFuncs(t.renderer.FuncMap())
+
+	tree := parse.New("constant")
+	tree.Mode = parse.SkipFuncCheck
+	_, err := tree.Parse("{{"+pipeline+"}}", "", "", map[string]*parse.Tree{})
+	if err != nil || len(tree.Root.Nodes) != 1 {
+		return nil, false
+	}
+
+	action, ok := tree.Root.Nodes[0].(*parse.ActionNode)
+	if !ok || !t.isConstant(action.Pipe, funcs) {
+		return nil, false
+	}
+
+	var value any
+	evaluator, err := texttemplate.New("constant").Funcs(funcs).Funcs(texttemplate.FuncMap{
+		"__glamConstant": func(v any) string {
+			value = v
+			return ""
+		},
+	}).Parse("{{__glamConstant (" + pipeline + ")}}")
+	if err != nil {
+		return nil, false
+	}
+
+	err = evaluator.Execute(io.Discard, nil)
+	if err != nil {
+		return nil, false
+	}
+
+	return value, true
+}
+
+// isConstant reports whether the given pipeline only consists of literals
+// and calls to pure funcs.
+func (t *Template) isConstant(pipe *parse.PipeNode, funcs map[string]any) bool {
+	if len(pipe.Decl) > 0 {
+		return false
+	}
+
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch arg := arg.(type) {
+			case *parse.StringNode, *parse.NumberNode, *parse.BoolNode:
+			case *parse.IdentifierNode:
+				if !t.isPure(arg.Ident, funcs) {
+					return false
+				}
+			case *parse.PipeNode:
+				if !t.isConstant(arg, funcs) {
+					return false
+				}
+			default:
+				// Fields, variables, dot, and chains depend on render data
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// isPure reports whether the func with the given name can be called at
+// compile time. Funcs are impure unless they've been marked as pure, since
+// they may depend on the request, like a CSRF token, or the current time.
+func (t *Template) isPure(name string, funcs map[string]any) bool {
+	if t.options.PureFuncs[name] {
+		return true
+	}
+
+	// Builtins are only pure if they haven't been replaced
+	_, replaced := funcs[name]
+
+	return pureBuiltins[name] && !replaced
+}
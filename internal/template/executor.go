@@ -0,0 +1,195 @@
+package template
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"reflect"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+type (
+	// executor is the subset of html/template and text/template used to
+	// parse and execute compiled templates. Trusted components are executed
+	// using text/template so their output isn't escaped.
+	executor interface {
+		Execute(w io.Writer, data any) error
+		ExecuteTemplate(w io.Writer, name string, data any) error
+
+		clone() (executor, error)
+		defines(name string) bool
+		funcs(funcMap map[string]any)
+		option(opts ...string)
+		parse(content string) (executor, error)
+		// tree returns the parse tree of the template with the given name,
+		// or nil if it isn't defined
+		tree(name string) *parse.Tree
+		// trees returns the parse trees of every template that's defined
+		trees() []*parse.Tree
+	}
+
+	// htmlExecutor executes templates using html/template, which escapes
+	// output based on its context.
+	htmlExecutor struct {
+		*htmltemplate.Template
+	}
+
+	// textExecutor executes templates using text/template, which doesn't
+	// escape output.
+	textExecutor struct {
+		*texttemplate.Template
+	}
+
+	// funcPanic is the error funcs panic with when the func they wrap
+	// panics, so the original value can be recovered from the error
+	// text/template returns.
+	funcPanic struct {
+		Value any
+	}
+)
+
+func (p *funcPanic) Error() string {
+	return fmt.Sprintf("%v", p.Value)
+}
+
+// recoverPanics wraps each func in funcMap so that panics are re-raised as a
+// *funcPanic holding the original value. text/template recovers panics in
+// funcs and converts values that aren't errors into strings, so without this
+// the original value is lost.
+func recoverPanics(funcMap map[string]any) map[string]any {
+	wrapped := make(map[string]any, len(funcMap))
+
+	for name, fn := range funcMap {
+		v := reflect.ValueOf(fn)
+		if v.Kind() != reflect.Func || v.IsNil() {
+			// Let the underlying template report the invalid func
+			wrapped[name] = fn
+			continue
+		}
+
+		wrapped[name] = reflect.MakeFunc(v.Type(), func(args []reflect.Value) []reflect.Value {
+			defer func() {
+				if r := recover(); r != nil {
+					panic(&funcPanic{Value: r})
+				}
+			}()
+
+			if v.Type().IsVariadic() {
+				return v.CallSlice(args)
+			}
+
+			return v.Call(args)
+		}).Interface()
+	}
+
+	return wrapped
+}
+
+func newExecutor(name string, trusted bool) executor {
+	if trusted {
+		return textExecutor{texttemplate.New(name)}
+	}
+
+	return htmlExecutor{htmltemplate.New(name)}
+}
+
+func (e htmlExecutor) clone() (executor, error) {
+	t, err := e.Template.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	return htmlExecutor{t}, nil
+}
+
+func (e htmlExecutor) defines(name string) bool {
+	return e.Template.Lookup(name) != nil
+}
+
+func (e htmlExecutor) funcs(funcMap map[string]any) {
+	e.Template.Funcs(recoverPanics(funcMap))
+}
+
+func (e htmlExecutor) option(opts ...string) {
+	e.Template.Option(opts...)
+}
+
+func (e htmlExecutor) parse(content string) (executor, error) {
+	t, err := e.Template.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return htmlExecutor{t}, nil
+}
+
+func (e htmlExecutor) tree(name string) *parse.Tree {
+	t := e.Template.Lookup(name)
+	if t == nil {
+		return nil
+	}
+
+	return t.Tree
+}
+
+func (e textExecutor) clone() (executor, error) {
+	t, err := e.Template.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	return textExecutor{t}, nil
+}
+
+func (e textExecutor) defines(name string) bool {
+	return e.Template.Lookup(name) != nil
+}
+
+func (e textExecutor) funcs(funcMap map[string]any) {
+	e.Template.Funcs(recoverPanics(funcMap))
+}
+
+func (e textExecutor) option(opts ...string) {
+	e.Template.Option(opts...)
+}
+
+func (e textExecutor) parse(content string) (executor, error) {
+	t, err := e.Template.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return textExecutor{t}, nil
+}
+
+func (e textExecutor) tree(name string) *parse.Tree {
+	t := e.Template.Lookup(name)
+	if t == nil {
+		return nil
+	}
+
+	return t.Tree
+}
+
+func (e htmlExecutor) trees() []*parse.Tree {
+	var trees []*parse.Tree
+	for _, t := range e.Template.Templates() {
+		if t.Tree != nil {
+			trees = append(trees, t.Tree)
+		}
+	}
+
+	return trees
+}
+
+func (e textExecutor) trees() []*parse.Tree {
+	var trees []*parse.Tree
+	for _, t := range e.Template.Templates() {
+		if t.Tree != nil {
+			trees = append(trees, t.Tree)
+		}
+	}
+
+	return trees
+}
@@ -0,0 +1,71 @@
+package template
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// includePattern matches `{{include "path"}}` actions, which are replaced by
+// the content of the file at path when Options.IncludeFS is set
+var includePattern = regexp.MustCompile(`\{\{\s*include\s+("(?:[^"\\]|\\.)*")\s*\}\}`)
+
+// maxIncludeDepth is the maximum number of nested includes, so deeply nested
+// includes fail instead of growing the template without bound
+const maxIncludeDepth = 8
+
+// spliceIncludes replaces the `{{include "path"}}` actions in content with
+// the content of the files they include, which are spliced in recursively.
+// stack contains the files being included, so cycles can be reported.
+func spliceIncludes(fsys fs.FS, content string, stack []string) (string, error) {
+	matches := includePattern.FindAllStringSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return content, nil
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		path, err := strconv.Unquote(content[match[2]:match[3]])
+		if err == nil {
+			var included string
+			included, err = include(fsys, path, stack)
+			if err == nil {
+				b.WriteString(content[last:match[0]])
+				b.WriteString(included)
+				last = match[1]
+				continue
+			}
+		}
+
+		line, column := position([]rune(content), utf8.RuneCountInString(content[:match[0]]))
+		return "", fmt.Errorf("%d:%d: could not include %s: %w", line, column, content[match[2]:match[3]], err)
+	}
+	b.WriteString(content[last:])
+
+	return b.String(), nil
+}
+
+// include returns the content of the file at path, with its own includes
+// spliced in.
+func include(fsys fs.FS, path string, stack []string) (string, error) {
+	for _, including := range stack {
+		if including == path {
+			return "", fmt.Errorf("include cycle %s -> %s", strings.Join(stack, " -> "), path)
+		}
+	}
+
+	if len(stack) == maxIncludeDepth {
+		return "", fmt.Errorf("includes can't be nested more than %d levels deep", maxIncludeDepth)
+	}
+
+	content, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return "", err
+	}
+
+	return spliceIncludes(fsys, string(content), append(stack, path))
+}
@@ -0,0 +1,69 @@
+package template
+
+import (
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"strconv"
+	"strings"
+)
+
+// KeyAttribute is the attribute the value of a component's `key` attribute is
+// rendered as, e.g. `<Item key="{{.ID}}" />` renders `data-glam-key="1"` on
+// the first element of the Item component's output.
+const KeyAttribute = "data-glam-key"
+
+// keyValue returns the template expression that evaluates to the value of a
+// component's `key` attribute.
+func keyValue(value string) string {
+	if pipeline, ok := actionPipeline(value); ok {
+		return fmt.Sprintf("(%s)", pipeline)
+	}
+
+	return strconv.Quote(value)
+}
+
+// withKey adds the key to the first start tag of the rendered component as a
+// KeyAttribute attribute. Text, comments, doctypes, end tags, and a `<` that
+// doesn't start a complete tag are skipped. Output without an element is
+// returned unchanged, since there's nothing to identify.
+func withKey(key any, content htmltemplate.HTML) htmltemplate.HTML {
+	s := string(content)
+	for i := 0; i < len(s); {
+		next := strings.IndexByte(s[i:], '<')
+		if next == -1 {
+			break
+		}
+		i += next
+		rest := s[i:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end == -1 {
+				return content
+			}
+			i += 4 + end + 3
+		case strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"), strings.HasPrefix(rest, "</"):
+			i += tagEnd(rest)
+		case len(rest) > 1 && isASCIILetter(rest[1]):
+			// A tag cut off by the end of the output isn't an element
+			if !strings.HasSuffix(rest[:tagEnd(rest)], ">") {
+				return content
+			}
+
+			end := i + 1 + len(tagNameOf(rest[1:]))
+			attribute := fmt.Sprintf(` %s="%s"`, KeyAttribute, html.EscapeString(fmt.Sprint(key)))
+
+			return htmltemplate.HTML(s[:end] + attribute + s[end:])
+		default:
+			i++
+		}
+	}
+
+	return content
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -2,6 +2,7 @@ package template
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -21,6 +22,19 @@ type Node struct {
 	Attributes map[string]string
 	// Children is a list of child nodes, if this is a component type
 	Children []*Node
+	// ChildComponents are the child component nodes collected into a field
+	// tagged with `glam:"children-components=Name"`, keyed by the field's
+	// attribute name, if this is a component type
+	ChildComponents map[string][]*Node
+	// If is the pipeline of the component's `if` attribute, if any. The
+	// component is only rendered when the pipeline is truthy.
+	If string
+	// Range is the pipeline of the component's `range` attribute, if any. The
+	// component is rendered once per element, with the element as dot.
+	Range string
+	// Key is the value of the component's `key` attribute, if any, which is
+	// rendered as KeyAttribute on the component's first element.
+	Key string
 	// Raw is the raw HTML content of this node, if this is a raw type
 	Raw string
 }
@@ -38,6 +52,29 @@ func (n *Node) String() string {
 	case NodeTypeComponent:
 		b.WriteString(fmt.Sprintf("  TagName: %s\n", n.TagName))
 		b.WriteString(fmt.Sprintf("  Attributes: %s\n", n.Attributes))
+		if n.If != "" {
+			b.WriteString(fmt.Sprintf("  If: %s\n", n.If))
+		}
+		if n.Range != "" {
+			b.WriteString(fmt.Sprintf("  Range: %s\n", n.Range))
+		}
+		if n.Key != "" {
+			b.WriteString(fmt.Sprintf("  Key: %s\n", n.Key))
+		}
+		names := make([]string, 0, len(n.ChildComponents))
+		for name := range n.ChildComponents {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, c := range n.ChildComponents[name] {
+				parts := strings.Split(c.String(), "\n")
+				for i, p := range parts {
+					parts[i] = fmt.Sprintf("  %s", p)
+				}
+				b.WriteString(fmt.Sprintf("  ChildComponents[%s]: %s\n", name, strings.Join(parts, "\n")))
+			}
+		}
 		for _, c := range n.Children {
 			parts := strings.Split(c.String(), "\n")
 			for i, p := range parts {
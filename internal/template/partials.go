@@ -0,0 +1,118 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"text/template/parse"
+)
+
+// ParsePartials checks that partials only consists of `{{define}}` blocks and
+// returns the capitalized tags in them that may refer to components that
+// aren't registered yet, so templates can be given the partials again once
+// they're registered.
+func ParsePartials(partials string, r Renderer, opts Options) (map[string]bool, error) {
+	err := checkPartials(partials)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Template{
+		Name:                            "partials",
+		renderer:                        r,
+		options:                         opts,
+		potentiallyReferencedComponents: make(map[string]bool),
+	}
+
+	_, err = t.parseRoot([]rune(partials), r.KnownComponents())
+	if err != nil {
+		return nil, fmt.Errorf("could not parse partials: %w", err)
+	}
+
+	return t.potentiallyReferencedComponents, nil
+}
+
+// checkPartials returns an error if partials contains anything other than
+// `{{define}}` blocks and whitespace, since parsing it would otherwise
+// replace the content of the templates it's added to.
+func checkPartials(partials string) error {
+	tree := parse.New("partials")
+	tree.Mode = parse.SkipFuncCheck | parse.ParseComments
+	_, err := tree.Parse(partials, "", "", map[string]*parse.Tree{})
+	if err != nil {
+		return fmt.Errorf("could not parse partials: %w", err)
+	}
+
+	for _, node := range tree.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			if strings.TrimSpace(string(node.Text)) == "" {
+				continue
+			}
+		case *parse.CommentNode:
+			continue
+		}
+
+		return fmt.Errorf("partials can only contain {{define}} blocks, got %q", node)
+	}
+
+	return nil
+}
+
+// AddPartials parses the `{{define}}` blocks in partials into the template,
+// so its content can render them using `{{template}}`. Partials are compiled
+// like the template's content, so they can render components, and replace
+// any existing templates with the same name. Templates with a policy aren't
+// given partials.
+func (t *Template) AddPartials(partials string) error {
+	// Partials aren't restricted by the template's policy
+	if t.options.Policy != nil {
+		return nil
+	}
+
+	err := checkPartials(partials)
+	if err != nil {
+		return err
+	}
+
+	// Tags in partials aren't part of the template's own content, so they
+	// aren't tracked as references
+	references, potentiallyReferenced := t.references, t.potentiallyReferencedComponents
+	defer func() {
+		t.pos = 0
+		t.references, t.potentiallyReferencedComponents = references, potentiallyReferenced
+	}()
+	t.potentiallyReferencedComponents = make(map[string]bool)
+
+	t.pos = 0
+	nodes, err := t.parseRoot([]rune(partials), t.renderer.KnownComponents())
+	if err != nil {
+		return fmt.Errorf("could not parse partials: %w", err)
+	}
+
+	// Continue numbering from the template's content so generated
+	// identifiers and static attributes don't conflict with it
+	c := &compiler{
+		defines:          t.defines,
+		staticAttributes: append([]map[string]any(nil), t.staticAttributes...),
+		constant:         t.constantValue,
+		typedLiteral:     t.typedLiteral,
+	}
+	content, defines := c.rawCompile(nodes, false)
+	content = compileTargets(strings.Join(defines, "") + content)
+
+	base, err := t.base.parse(content)
+	if err != nil {
+		return fmt.Errorf("could not parse partials: %w", err)
+	}
+
+	t.base = base
+	t.staticAttributes = c.staticAttributes
+	t.defines = c.defines
+
+	t.executor, err = t.newExecutor(nil)
+	if err != nil {
+		return fmt.Errorf("could not parse partials: %w", err)
+	}
+
+	return nil
+}
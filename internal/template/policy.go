@@ -0,0 +1,140 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"text/template/parse"
+)
+
+// Policy restricts the funcs and components a template can use, so templates
+// written by untrusted authors can only call approved code. text/template's
+// builtins, like `eq` and `printf`, are always allowed except for `call`,
+// which calls arbitrary funcs from data.
+type Policy struct {
+	// Funcs are the names of the funcs the template can call
+	Funcs []string
+	// Components are the names of the components the template can render
+	Components []string
+}
+
+func (p *Policy) allowsFunc(name string) bool {
+	if builtinFuncs[name] && name != "call" {
+		return true
+	}
+
+	for _, allowed := range p.Funcs {
+		if allowed == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Policy) allowsComponent(name string) bool {
+	for _, allowed := range p.Components {
+		if allowed == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowedFuncs returns the funcs in funcMap the template is allowed to call,
+// along with glam's internal funcs, which are called by compiled templates.
+// All funcs are allowed when the template doesn't have a policy.
+func (t *Template) allowedFuncs(funcMap map[string]any) map[string]any {
+	if t.options.Policy == nil {
+		return funcMap
+	}
+
+	allowed := make(map[string]any, len(funcMap))
+	for name, fn := range funcMap {
+		if strings.HasPrefix(name, "__glam") || t.options.Policy.allowsFunc(name) {
+			allowed[name] = fn
+		}
+	}
+
+	return allowed
+}
+
+// checkPolicy returns an error if the template's source calls a func that
+// isn't allowed by its policy, or calls one of glam's internal funcs. It's
+// checked before the template is compiled, since compiled templates call
+// internal funcs and pure funcs are called while compiling. Sources that
+// can't be parsed on their own are rejected, since compiling components can
+// turn them into valid templates whose actions haven't been checked, e.g. by
+// splitting a component's tags across a `{{define}}`.
+func (t *Template) checkPolicy(source string) error {
+	tree := parse.New(t.Name)
+	tree.Mode = parse.SkipFuncCheck
+	trees := map[string]*parse.Tree{}
+	_, err := tree.Parse(source, "", "", trees)
+	if err != nil {
+		return fmt.Errorf("template can't be checked against its policy: %w", err)
+	}
+
+	for _, tree := range trees {
+		err := t.checkPolicyNode(source, tree.Root)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Template) checkPolicyNode(source string, node parse.Node) error {
+	var children []parse.Node
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return nil
+		}
+		children = node.Nodes
+	case *parse.ActionNode:
+		children = []parse.Node{node.Pipe}
+	case *parse.IfNode:
+		children = []parse.Node{node.Pipe, node.List, node.ElseList}
+	case *parse.RangeNode:
+		children = []parse.Node{node.Pipe, node.List, node.ElseList}
+	case *parse.WithNode:
+		children = []parse.Node{node.Pipe, node.List, node.ElseList}
+	case *parse.TemplateNode:
+		children = []parse.Node{node.Pipe}
+	case *parse.PipeNode:
+		if node == nil {
+			return nil
+		}
+		for _, cmd := range node.Cmds {
+			children = append(children, cmd)
+		}
+	case *parse.CommandNode:
+		children = node.Args
+	case *parse.ChainNode:
+		children = []parse.Node{node.Node}
+	case *parse.IdentifierNode:
+		// Node positions are byte offsets, but errors report positions in
+		// runes
+		preceding := []rune(source[:node.Position()])
+		line, column := position(preceding, len(preceding))
+
+		if strings.HasPrefix(node.Ident, "__glam") {
+			return fmt.Errorf("%d:%d: internal func %s can't be called", line, column, node.Ident)
+		}
+
+		if !t.options.Policy.allowsFunc(node.Ident) {
+			return fmt.Errorf("%d:%d: func %s isn't allowed by the template's policy", line, column, node.Ident)
+		}
+	}
+
+	for _, child := range children {
+		err := t.checkPolicyNode(source, child)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
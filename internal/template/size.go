@@ -0,0 +1,44 @@
+package template
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+type (
+	// sizeEstimate tracks a moving average of rendered sizes so buffers can be
+	// allocated close to their final size, avoiding reallocation as they grow.
+	sizeEstimate struct {
+		average atomic.Int64
+	}
+
+	// countingWriter counts the bytes written to the underlying writer
+	countingWriter struct {
+		w io.Writer
+		n int
+	}
+)
+
+// record adds a rendered size to the moving average. Recent sizes are
+// weighted more heavily so the estimate follows changes in the rendered data.
+func (s *sizeEstimate) record(n int) {
+	average := s.average.Load()
+	if average == 0 {
+		s.average.Store(int64(n))
+		return
+	}
+
+	s.average.Store(average + (int64(n)-average)/8)
+}
+
+// hint returns the estimated size of the next render.
+func (s *sizeEstimate) hint() int {
+	return int(s.average.Load())
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+
+	return n, err
+}
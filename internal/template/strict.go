@@ -0,0 +1,434 @@
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template/parse"
+)
+
+// bindingChecker validates the attributes bound to components when templates
+// are compiled with Options.StrictBindings. It's best-effort, only checking
+// bindings where the type of dot is known, which excludes the bodies of
+// `range` and `with` actions.
+type bindingChecker struct {
+	t        *Template
+	trees    map[string]*parse.Tree
+	dataType reflect.Type
+	// checked tracks the trees that were checked, since child content
+	// defines can be referenced more than once
+	checked map[string]bool
+}
+
+// checkBindings parses the compiled template content and checks the bindings
+// of the components rendered by the given root templates, which are executed
+// with the component's data as dot.
+func (t *Template) checkBindings(content string, roots ...string) error {
+	dataType, ok := t.renderer.KnownComponents()[t.Name]
+	if t.options.DataType != nil {
+		dataType, ok = t.options.DataType, true
+	}
+	if !ok || dataType == FuncComponentType {
+		return nil
+	}
+
+	tree := parse.New(t.Name)
+	tree.Mode = parse.SkipFuncCheck
+	trees := map[string]*parse.Tree{}
+	_, err := tree.Parse(content, "", "", trees)
+	if err != nil {
+		// Syntax errors are reported when the template is parsed
+		return nil
+	}
+
+	c := &bindingChecker{t: t, trees: trees, dataType: dataType, checked: make(map[string]bool)}
+	for _, root := range roots {
+		err := c.checkTree(root)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *bindingChecker) checkTree(name string) error {
+	tree, ok := c.trees[name]
+	if !ok || c.checked[name] || tree.Root == nil {
+		return nil
+	}
+	c.checked[name] = true
+
+	return c.checkList(tree.Root)
+}
+
+func (c *bindingChecker) checkList(list *parse.ListNode) error {
+	if list == nil {
+		return nil
+	}
+
+	for _, node := range list.Nodes {
+		var err error
+		switch node := node.(type) {
+		case *parse.ActionNode:
+			err = c.checkAction(node.Pipe)
+		case *parse.IfNode:
+			err = c.checkList(node.List)
+			if err == nil {
+				err = c.checkList(node.ElseList)
+			}
+		// Dot only changes in the body of range and with, not in else
+		case *parse.RangeNode:
+			err = c.checkList(node.ElseList)
+		case *parse.WithNode:
+			err = c.checkList(node.ElseList)
+		case *parse.TemplateNode:
+			// Blocks are executed with dot, e.g. `{{block "footer" .}}`
+			if isDot(node.Pipe) {
+				err = c.checkTree(node.Name)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAction checks the bindings of the component rendered by the given
+// pipeline, if it renders a component, e.g.:
+//
+//	{{__glamRenderComponent "Name" "identifier" (__glamDict "name" (.Name)) .}}
+//
+// Components with a key are rendered inside of a `__glamKeyed` call.
+func (c *bindingChecker) checkAction(pipe *parse.PipeNode) error {
+	if len(pipe.Cmds) != 1 {
+		return nil
+	}
+
+	args := pipe.Cmds[0].Args
+	if len(args) == 3 && isIdentifier(args[0], "__glamKeyed") {
+		if render, ok := args[2].(*parse.PipeNode); ok {
+			return c.checkAction(render)
+		}
+	}
+
+	if len(args) != 5 || !isIdentifier(args[0], "__glamRenderComponent") || !isDot(args[4]) {
+		return nil
+	}
+
+	name, _ := args[1].(*parse.StringNode)
+	identifier, _ := args[2].(*parse.StringNode)
+	if name == nil || identifier == nil {
+		return nil
+	}
+
+	err := c.checkAttributeNames(name.Text, args[3])
+	if err != nil {
+		return err
+	}
+
+	err = c.checkFields(name.Text, args[3])
+	if err != nil {
+		return err
+	}
+
+	err = c.checkFuncAttributes(name.Text, args[3])
+	if err != nil {
+		return err
+	}
+
+	// Child content is executed with the same dot as the component
+	return c.checkTree(identifier.Text)
+}
+
+// checkAttributeNames returns an error if an attribute passed to a registered
+// component doesn't match one of its fields, unless the component forwards
+// unmatched attributes.
+func (c *bindingChecker) checkAttributeNames(component string, attributes parse.Node) error {
+	componentType, ok := c.t.renderer.KnownComponents()[component]
+	if !ok || componentType == FuncComponentType {
+		return nil
+	}
+
+	if componentType.Kind() == reflect.Ptr {
+		componentType = componentType.Elem()
+	}
+
+	fields := make(map[string]bool, componentType.NumField())
+	for i := 0; i < componentType.NumField(); i++ {
+		field := componentType.Field(i)
+		if field.Tag.Get("glam") == "attrs" {
+			return nil
+		}
+
+		fields[strings.ToLower(AttributeName(field))] = true
+	}
+	for attribute := range c.t.attributeMapping(component) {
+		fields[attribute] = true
+	}
+
+	for _, name := range c.attributeNames(attributes) {
+		if !fields[name] {
+			return fmt.Errorf("component %s has no field for attribute %s", component, name)
+		}
+	}
+
+	return nil
+}
+
+// attributeNames returns the names of the attributes built by the given
+// `__glamDict` or `__glamStaticAttributes` call.
+func (c *bindingChecker) attributeNames(attributes parse.Node) []string {
+	pipe, ok := attributes.(*parse.PipeNode)
+	if !ok || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) == 0 {
+		return nil
+	}
+
+	args := pipe.Cmds[0].Args
+	names := make([]string, 0, len(args)/2)
+	switch {
+	case isIdentifier(args[0], "__glamDict"):
+		for i := 1; i < len(args); i += 2 {
+			if name, ok := args[i].(*parse.StringNode); ok {
+				names = append(names, strings.TrimSuffix(name.Text, "?"))
+			}
+		}
+	case isIdentifier(args[0], "__glamStaticAttributes") && len(args) == 2:
+		index, ok := args[1].(*parse.NumberNode)
+		if !ok || !index.IsInt || int(index.Int64) >= len(c.t.staticAttributes) {
+			return nil
+		}
+
+		for name := range c.t.staticAttributes[index.Int64] {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// checkFuncAttributes returns an error if an attribute populating a func field
+// of a registered component is bound to a value that isn't assignable to it,
+// like a string or a field with a different signature. Only literal values and
+// bindings to a single field, like `{{.RenderRow}}`, are checked.
+func (c *bindingChecker) checkFuncAttributes(component string, attributes parse.Node) error {
+	componentType, ok := c.t.renderer.KnownComponents()[component]
+	if !ok || componentType == FuncComponentType {
+		return nil
+	}
+
+	for name, valueType := range c.attributeTypes(attributes) {
+		field, ok := attributeField(derefType(componentType), c.t.attributeMapping(component), name)
+		if !ok || field.Type.Kind() != reflect.Func || valueType.AssignableTo(field.Type) {
+			continue
+		}
+
+		return fmt.Errorf("attribute %s of component %s must be a %s, got %s", name, component, field.Type, valueType)
+	}
+
+	return nil
+}
+
+// attributeTypes returns the types of the values of the attributes built by
+// the given `__glamDict` or `__glamStaticAttributes` call, when they're
+// known.
+func (c *bindingChecker) attributeTypes(attributes parse.Node) map[string]reflect.Type {
+	pipe, ok := attributes.(*parse.PipeNode)
+	if !ok || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) == 0 {
+		return nil
+	}
+
+	args := pipe.Cmds[0].Args
+	types := make(map[string]reflect.Type)
+	switch {
+	case isIdentifier(args[0], "__glamDict"):
+		for i := 1; i+1 < len(args); i += 2 {
+			name, ok := args[i].(*parse.StringNode)
+			if !ok {
+				continue
+			}
+
+			if valueType := c.valueType(args[i+1]); valueType != nil {
+				types[name.Text] = valueType
+			}
+		}
+	case isIdentifier(args[0], "__glamStaticAttributes") && len(args) == 2:
+		index, ok := args[1].(*parse.NumberNode)
+		if !ok || !index.IsInt || int(index.Int64) >= len(c.t.staticAttributes) {
+			return nil
+		}
+
+		for name, value := range c.t.staticAttributes[index.Int64] {
+			if value != nil {
+				types[name] = reflect.TypeOf(value)
+			}
+		}
+	}
+
+	return types
+}
+
+// valueType returns the type of the given attribute value if it's a string
+// or a single field or method of dot, or nil if it can't be determined.
+func (c *bindingChecker) valueType(node parse.Node) reflect.Type {
+	if _, ok := node.(*parse.StringNode); ok {
+		return reflect.TypeOf("")
+	}
+
+	pipe, ok := node.(*parse.PipeNode)
+	if !ok || len(pipe.Decl) != 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return nil
+	}
+
+	var idents []string
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.FieldNode:
+		idents = arg.Ident
+	case *parse.VariableNode:
+		if len(arg.Ident) < 2 || arg.Ident[0] != "$" {
+			return nil
+		}
+		idents = arg.Ident[1:]
+	default:
+		return nil
+	}
+
+	current := c.dataType
+	for _, ident := range idents {
+		next, ok := fieldOrMethod(current, ident)
+		if !ok || next == nil {
+			return nil
+		}
+		current = next
+	}
+
+	return current
+}
+
+// attributeField returns the field of the component populated by the
+// attribute with the given name, using the component's attribute mapping
+// first.
+func attributeField(componentType reflect.Type, mapping map[string]string, name string) (reflect.StructField, bool) {
+	if fieldName, ok := mapping[strings.ToLower(name)]; ok {
+		return componentType.FieldByName(fieldName)
+	}
+
+	for i := 0; i < componentType.NumField(); i++ {
+		field := componentType.Field(i)
+		if field.IsExported() && strings.EqualFold(AttributeName(field), name) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// checkFields returns an error if a field referenced by the given node, like
+// `.Nme`, doesn't exist on the component's data.
+func (c *bindingChecker) checkFields(component string, node parse.Node) error {
+	switch node := node.(type) {
+	case *parse.FieldNode:
+		return c.checkField(component, ".", node.Ident)
+	case *parse.VariableNode:
+		// $ is always the data the template was executed with
+		if len(node.Ident) > 1 && node.Ident[0] == "$" {
+			return c.checkField(component, "$.", node.Ident[1:])
+		}
+	case *parse.PipeNode:
+		for _, cmd := range node.Cmds {
+			err := c.checkFields(component, cmd)
+			if err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range node.Args {
+			err := c.checkFields(component, arg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkField returns an error if the given chain of fields or methods doesn't
+// exist on the component's data. Chains through maps and interfaces can't be
+// checked, so they're skipped.
+func (c *bindingChecker) checkField(component string, prefix string, idents []string) error {
+	current := c.dataType
+	for _, ident := range idents {
+		next, ok := fieldOrMethod(current, ident)
+		if !ok {
+			return fmt.Errorf("attribute of component %s references %s%s, but %s has no field or method %s", component, prefix, strings.Join(idents, "."), current, ident)
+		}
+
+		if next == nil {
+			return nil
+		}
+		current = next
+	}
+
+	return nil
+}
+
+// fieldOrMethod returns the type of the field or the result of the method with
+// the given name, like text/template resolves it. It returns a nil type when
+// the type can't be determined statically, e.g. for map values.
+func fieldOrMethod(t reflect.Type, name string) (reflect.Type, bool) {
+	if t.Kind() != reflect.Interface {
+		if method, ok := reflect.PointerTo(derefType(t)).MethodByName(name); ok {
+			if method.Type.NumOut() == 0 {
+				return nil, true
+			}
+
+			return method.Type.Out(0), true
+		}
+	}
+
+	t = derefType(t)
+	switch t.Kind() {
+	case reflect.Struct:
+		field, ok := t.FieldByName(name)
+		if !ok || !field.IsExported() {
+			return nil, false
+		}
+
+		return field.Type, true
+	case reflect.Map, reflect.Interface:
+		return nil, true
+	}
+
+	return nil, false
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+func isIdentifier(node parse.Node, name string) bool {
+	identifier, ok := node.(*parse.IdentifierNode)
+
+	return ok && identifier.Ident == name
+}
+
+// isDot reports whether the node is `.`, or a pipeline consisting only of `.`
+func isDot(node parse.Node) bool {
+	switch node := node.(type) {
+	case *parse.DotNode:
+		return true
+	case *parse.PipeNode:
+		return node != nil && len(node.Decl) == 0 && len(node.Cmds) == 1 &&
+			len(node.Cmds[0].Args) == 1 && isDot(node.Cmds[0].Args[0])
+	}
+
+	return false
+}
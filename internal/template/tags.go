@@ -132,3 +132,83 @@ var knownHTMLTags htmlTags = map[string]bool{
 func (kt htmlTags) IsKnown(tag string) bool {
 	return kt[strings.ToLower(tag)]
 }
+
+// voidHTMLTags are HTML elements that can't have content or an end tag
+var voidHTMLTags htmlTags = map[string]bool{
+	"area":   true,
+	"base":   true,
+	"br":     true,
+	"col":    true,
+	"embed":  true,
+	"hr":     true,
+	"img":    true,
+	"input":  true,
+	"link":   true,
+	"meta":   true,
+	"param":  true,
+	"source": true,
+	"track":  true,
+	"wbr":    true,
+}
+
+// IsVoidElement returns true if the given tag is an HTML void element, like
+// `br` or `img`, which can't have content or an end tag.
+func IsVoidElement(tag string) bool {
+	return voidHTMLTags.IsKnown(tag)
+}
+
+// urlAttributes are attributes whose values are URLs, matching the attributes
+// html/template treats as URLs.
+var urlAttributes = map[string]bool{
+	"action":     true,
+	"archive":    true,
+	"background": true,
+	"cite":       true,
+	"classid":    true,
+	"codebase":   true,
+	"data":       true,
+	"formaction": true,
+	"href":       true,
+	"icon":       true,
+	"longdesc":   true,
+	"manifest":   true,
+	"poster":     true,
+	"profile":    true,
+	"src":        true,
+	"srcset":     true,
+	"usemap":     true,
+	"xmlns":      true,
+}
+
+// IsURLAttribute returns true if the attribute with the given name has a URL
+// value. Like html/template, a `data-` prefix or namespace is ignored and
+// names containing src, uri, or url are treated as URLs.
+func IsURLAttribute(name string) bool {
+	name = strings.ToLower(name)
+	if namespace, local, ok := strings.Cut(name, ":"); ok {
+		if namespace == "xmlns" {
+			return true
+		}
+
+		name = local
+	}
+	name = strings.TrimPrefix(name, "data-")
+
+	return urlAttributes[name] || strings.Contains(name, "src") || strings.Contains(name, "uri") || strings.Contains(name, "url")
+}
+
+// FilterURL returns value when it's a relative URL or uses the http, https,
+// or mailto schemes, and `#ZgotmplZ` otherwise, like html/template.
+func FilterURL(value string) string {
+	scheme, _, found := strings.Cut(value, ":")
+	if !found || strings.Contains(scheme, "/") {
+		return value
+	}
+
+	switch strings.ToLower(scheme) {
+	case "http", "https", "mailto":
+		return value
+	}
+
+	return "#ZgotmplZ"
+}
@@ -0,0 +1,205 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+type (
+	// TargetWriter routes rendered output to one of several named writers.
+	// Output is written to the default target unless it's rendered inside a
+	// `{{target "name"}}` block, in which case it's written to the named
+	// target's writer.
+	TargetWriter struct {
+		writers map[string]io.Writer
+		frames  []targetFrame
+	}
+
+	// targetFrame is the writer output is currently routed to. Nested
+	// components and child content push a frame that captures output for the
+	// active target into a buffer, so it can be returned to the parent.
+	targetFrame struct {
+		target string
+		w      io.Writer
+	}
+)
+
+// NewTargetWriter returns a TargetWriter that writes to the given writers,
+// keyed by target name. Output outside of a target block is written to
+// defaultTarget.
+func NewTargetWriter(writers map[string]io.Writer, defaultTarget string) *TargetWriter {
+	return &TargetWriter{
+		writers: writers,
+		frames:  []targetFrame{{target: defaultTarget, w: writers[defaultTarget]}},
+	}
+}
+
+func (tw *TargetWriter) Write(p []byte) (int, error) {
+	frame := tw.frames[len(tw.frames)-1]
+	if frame.w == nil {
+		return 0, fmt.Errorf("no writer provided for target %q", frame.target)
+	}
+
+	return frame.w.Write(p)
+}
+
+// enter routes output to the given target until the matching exit. It's a
+// no-op when the template isn't being rendered into multiple targets.
+func (tw *TargetWriter) enter(target string) string {
+	if tw != nil {
+		tw.frames = append(tw.frames, targetFrame{target: target, w: tw.writers[target]})
+	}
+
+	return ""
+}
+
+// exit routes output back to the target that was active before the last
+// enter.
+func (tw *TargetWriter) exit() string {
+	if tw != nil && len(tw.frames) > 1 {
+		tw.frames = tw.frames[:len(tw.frames)-1]
+	}
+
+	return ""
+}
+
+// capture routes output for the active target into w until the returned
+// func is called. Output inside target blocks is still written to the
+// target's writer.
+func (tw *TargetWriter) capture(w io.Writer) func() {
+	target := tw.frames[len(tw.frames)-1].target
+	tw.frames = append(tw.frames, targetFrame{target: target, w: w})
+	depth := len(tw.frames)
+
+	return func() {
+		tw.frames = tw.frames[:depth-1]
+	}
+}
+
+// compileTargets rewrites `{{target "name"}}` blocks into calls that switch
+// the active target. Since `target` isn't a template keyword, its `{{end}}`
+// is found by tracking the blocks opened by other actions.
+func compileTargets(content string) string {
+	var b strings.Builder
+	// blocks records whether each open block is a target block
+	var blocks []bool
+
+	pos := 0
+	for {
+		start := strings.Index(content[pos:], "{{")
+		if start == -1 {
+			break
+		}
+		start += pos
+
+		end := actionEnd(content, start+2)
+		if end == -1 {
+			// Let the template parser report the unclosed action
+			break
+		}
+
+		inner := content[start+2 : end-2]
+		leftTrim := strings.HasPrefix(inner, "- ")
+		rightTrim := strings.HasSuffix(inner, " -")
+		if leftTrim {
+			inner = inner[2:]
+		}
+		if rightTrim {
+			inner = inner[:len(inner)-2]
+		}
+		inner = strings.TrimSpace(inner)
+
+		keyword := inner
+		if i := strings.IndexFunc(inner, unicode.IsSpace); i != -1 {
+			keyword = inner[:i]
+		}
+
+		replacement := ""
+		switch keyword {
+		case "if", "range", "with", "block", "define":
+			blocks = append(blocks, false)
+		case "target":
+			blocks = append(blocks, true)
+			replacement = "__glamTarget " + strings.TrimSpace(inner[len(keyword):])
+		case "end":
+			if len(blocks) == 0 {
+				// Unbalanced, which the template parser will report
+				break
+			}
+
+			if blocks[len(blocks)-1] {
+				replacement = "__glamEndTarget"
+			}
+			blocks = blocks[:len(blocks)-1]
+		}
+
+		if replacement == "" {
+			b.WriteString(content[pos:end])
+			pos = end
+			continue
+		}
+
+		b.WriteString(content[pos:start])
+		b.WriteString("{{")
+		if leftTrim {
+			b.WriteString("- ")
+		}
+		b.WriteString(replacement)
+		if rightTrim {
+			b.WriteString(" -")
+		}
+		b.WriteString("}}")
+		pos = end
+	}
+
+	b.WriteString(content[pos:])
+
+	return b.String()
+}
+
+// actionEnd returns the index just past the `}}` closing the action that
+// starts at pos, skipping over comments and quoted strings. It returns -1 if
+// the action isn't closed.
+func actionEnd(content string, pos int) int {
+	rest := strings.TrimPrefix(content[pos:], "- ")
+	if strings.HasPrefix(rest, "/*") {
+		commentStart := len(content) - len(rest)
+		commentEnd := strings.Index(content[commentStart:], "*/")
+		if commentEnd == -1 {
+			return -1
+		}
+
+		closing := strings.Index(content[commentStart+commentEnd:], "}}")
+		if closing == -1 {
+			return -1
+		}
+
+		return commentStart + commentEnd + closing + 2
+	}
+
+	for i := pos; i < len(content); i++ {
+		switch content[i] {
+		case '"', '\'':
+			quote := content[i]
+			for i++; i < len(content) && content[i] != quote; i++ {
+				if content[i] == '\\' {
+					i++
+				}
+			}
+		case '`':
+			closing := strings.IndexByte(content[i+1:], '`')
+			if closing == -1 {
+				return -1
+			}
+			i += closing + 1
+		case '}':
+			if i+1 < len(content) && content[i+1] == '}' {
+				return i + 2
+			}
+		}
+	}
+
+	return -1
+}
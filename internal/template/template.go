@@ -2,29 +2,67 @@ package template
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"html"
 	htmltemplate "html/template"
 	"io"
+	"io/fs"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 )
 
 type (
 	Template struct {
-		Name         string
-		htmltemplate *htmltemplate.Template
-		rawContent   string
-		renderer     Renderer
+		Name string
+		// base is the parsed template, which is never executed so it can be
+		// cloned when a render provides its own funcs
+		base executor
+		// executor is a clone of base that's executed directly by renders
+		// that don't provide their own funcs
+		executor   executor
+		rawContent string
+		renderer   Renderer
+		options    Options
 
 		// these are temporary until we have compilde into an htmltemplate
 		pos int
+		// foreign is the depth of the <svg> and <math> elements being parsed.
+		// Their content is foreign to HTML, so tags in it are never
+		// components.
+		foreign int
+		// depth is the number of components with child content being
+		// parsed, and tags is the number of tags parsed, so they can be
+		// checked against Options.Limits
+		depth int
+		tags  int
 
 		// potentiallyReferencedComponents is a map of component names that are
 		// referenced in the template, but not registered with the engine. This
 		// allows us to track references and recompile components when dependent
 		// components are registered.
 		potentiallyReferencedComponents map[string]bool
+
+		// references are the potentially referenced components in the order
+		// they appear in the template, along with their positions
+		references []Reference
+
+		// staticAttributes are the attributes of components that only have
+		// literal attributes, built once when the template is compiled. They
+		// must not be modified since they're shared across renders.
+		staticAttributes []map[string]any
+
+		// defines is the number of defines generated for child content, so
+		// templates extending this one can generate unique identifiers
+		defines int
+
+		// renderSize and childrenSize estimate the size of rendered output
+		// and child content so buffers can be sized ahead of time
+		renderSize   sizeEstimate
+		childrenSize sizeEstimate
 	}
 
 	Renderer interface {
@@ -33,28 +71,262 @@ type (
 		FuncMap() htmltemplate.FuncMap
 	}
 
+	// sizeHinter is implemented by renderers that can estimate the rendered
+	// size of a component, so buffers can be allocated ahead of time.
+	sizeHinter interface {
+		SizeHint(component any) int
+	}
+
+	// renderReporter is implemented by renderers that track which templates
+	// render each component, e.g. to report deprecated components.
+	renderReporter interface {
+		ReportRender(name string, referencedBy string)
+	}
+
+	// unassignableReporter is implemented by renderers that report the
+	// attributes skipped when Options.SkipUnassignable is set.
+	unassignableReporter interface {
+		ReportUnassignable(err *AssignmentError)
+	}
+
+	// attributeMapper is implemented by renderers that map attributes to the
+	// fields of components, e.g. for types whose tags can't be changed.
+	// AttributeMapping returns the field names keyed by lowercased attribute
+	// name, or nil if the component has no mapping.
+	attributeMapper interface {
+		AttributeMapping(name string) map[string]string
+	}
+
+	// componentFactory is implemented by renderers that construct the
+	// instances of components themselves, e.g. to inject dependencies.
+	// NewComponent returns nil to use a new zero value instead.
+	componentFactory interface {
+		NewComponent(name string, componentType reflect.Type) any
+	}
+
+	// funcComponentProps is implemented by renderers with function
+	// components, so literal attributes can be converted to the types of
+	// their props' fields. FuncComponentProps returns nil if the props of
+	// the component with the given name aren't a struct.
+	funcComponentProps interface {
+		FuncComponentProps(name string) reflect.Type
+	}
+
 	Recoverable interface {
 		Recover(w io.Writer, err any)
 	}
+
+	// Trusted is implemented by components whose templates are executed using
+	// text/template instead of html/template, so output isn't escaped.
+	Trusted interface {
+		Trusted()
+	}
+
+	// ComponentError is returned when a nested component fails to render,
+	// either while rendering its child content or its own template. Nested
+	// failures are wrapped, so the error message describes the chain of
+	// components that were rendering, and the original error can be
+	// retrieved with errors.As or errors.Is.
+	ComponentError struct {
+		// Component is the name of the component that failed to render
+		Component string
+		// Err is the underlying error
+		Err error
+	}
+
+	// AssignmentError is returned when the value of an attribute can't be
+	// assigned to the component field it populates, e.g. when a struct is
+	// passed to an int field.
+	AssignmentError struct {
+		// Component is the name of the component being instantiated
+		Component string
+		// Field is the field the attribute populates
+		Field reflect.StructField
+		// Attribute is the name of the attribute
+		Attribute string
+		// ValueType is the type of the attribute's value
+		ValueType reflect.Type
+		// Template is the name of the template passing the attribute. It's
+		// empty when the component wasn't rendered by a template.
+		Template string
+		// Filename is the file the template was read from, if any
+		Filename string
+	}
+
+	// PanicError is returned when rendering a component panics. It records
+	// the component that was being rendered and the value passed to panic.
+	PanicError struct {
+		// Component is the name of the component that was being rendered
+		Component string
+		// Filename is the file the component's template was read from, if any
+		Filename string
+		// Value is the value passed to panic
+		Value any
+	}
+
+	// attribute is a single attribute of a tag, as written in the template
+	attribute struct {
+		// name is the lowercased name of the attribute
+		name string
+		// rawName is the name of the attribute as written
+		rawName string
+		value   string
+		// quote is the quote character surrounding the value, if any
+		quote rune
+		// start and end are the positions of the attribute in the template,
+		// including its value
+		start int
+		end   int
+	}
+
+	// Reference is a capitalized tag that may refer to a component that
+	// hasn't been registered yet.
+	Reference struct {
+		// Name is the tag name, without the component tag prefix
+		Name string
+		// Line and Column are the position of the tag in the template,
+		// starting at 1
+		Line   int
+		Column int
+	}
+
+	// FuncComponent is rendered in place of function components, which
+	// aren't backed by a struct. Renderers call the function registered with
+	// Name using the component's attributes and child content.
+	FuncComponent struct {
+		// Name is the name the function was registered with
+		Name string
+		// Attributes are the component's attributes. They must not be
+		// modified since they may be shared across renders.
+		Attributes map[string]any
+		// Children is the component's rendered child content
+		Children htmltemplate.HTML
+	}
+
+	// Options configures how a template is parsed and executed.
+	Options struct {
+		// TemplateOptions are passed to the underlying html/template's Option
+		// method, e.g. "missingkey=error".
+		TemplateOptions []string
+		// Filename is the file the template was read from, if any. It's used
+		// to provide context in errors.
+		Filename string
+		// ShadowedTags are component names that are allowed to share their
+		// name with an HTML tag. The capitalized form, e.g. `<Dialog>`, is
+		// rendered as the component while `<dialog>` remains raw HTML.
+		ShadowedTags map[string]bool
+		// AllowTagConflicts allows any component to share its name with an
+		// HTML tag.
+		AllowTagConflicts bool
+		// RetainSource keeps the raw template content after parsing, even
+		// when it isn't needed for recompilation.
+		RetainSource bool
+		// Trusted executes the template using text/template instead of
+		// html/template, so output isn't escaped.
+		Trusted bool
+		// PureFuncs are the names of funcs that always return the same
+		// result for the same arguments. Attributes that only call pure funcs
+		// with literal arguments are evaluated once when the template is
+		// compiled instead of on every render.
+		PureFuncs map[string]bool
+		// StrictBindings returns an error when a template is parsed if an
+		// attribute passed to a component references a field that doesn't
+		// exist on the template's component, like `name="{{.Nme}}"`, or if a
+		// registered component has no field for an attribute. It's
+		// best-effort, skipping attributes where the type of dot isn't known,
+		// like inside of `range`.
+		StrictBindings bool
+		// DataType is the type of data a template that doesn't belong to a
+		// component is rendered with, so its bindings can be checked when
+		// StrictBindings is set.
+		DataType reflect.Type
+		// ComponentTagPrefix is the prefix that marks a tag as a component,
+		// e.g. "g:" for `<g:Button>`. When empty, capitalized tags are
+		// components.
+		ComponentTagPrefix string
+		// Partials are `{{define}}` blocks parsed into the template after its
+		// content, so it can render them using `{{template}}`.
+		Partials string
+		// IncludeFS is the file system `{{include "path"}}` actions are
+		// resolved against. Included files are spliced into the template
+		// before it's parsed, so they can render components. Positions in
+		// errors after an include are relative to the spliced template.
+		IncludeFS fs.FS
+		// AttributeTransformer is called with the value of each attribute
+		// passed to a component before it's assigned, replacing the value
+		// with the result. Errors are returned as render errors.
+		AttributeTransformer AttributeTransformer
+		// SkipUnassignable skips attributes whose value can't be assigned
+		// to the field they populate instead of failing the render. Skipped
+		// attributes are reported to the renderer, if it implements
+		// ReportUnassignable.
+		SkipUnassignable bool
+		// LenientTypedStrings converts strings from expressions when
+		// they're assigned to fields typed as one of html/template's safe
+		// strings, like template.HTML, instead of returning an
+		// *AssignmentError. Literal attribute text is always converted when
+		// the template is compiled.
+		LenientTypedStrings bool
+		// CheckBalance returns an *UnbalancedError from renders when a
+		// component's output or child content has a start tag without its
+		// end tag, or an end tag without its start tag. Output is buffered
+		// to be checked, so it's intended for development and tests.
+		CheckBalance bool
+		// Policy restricts the funcs and components the template can use.
+		// Templates with a policy are only bound the funcs it allows, and
+		// aren't given partials, which could call any func.
+		Policy *Policy
+		// Limits restricts the size of templates, so parsing a malicious
+		// template returns an error instead of exhausting memory or the
+		// stack.
+		Limits ParserLimits
+	}
+
+	// ParserLimits restricts the size of the templates that can be parsed.
+	// Limits that are zero aren't enforced.
+	ParserLimits struct {
+		// MaxDepth is the maximum depth of nested components with child
+		// content, like `<Card><Card>...</Card></Card>`
+		MaxDepth int
+		// MaxAttributes is the maximum number of attributes on a single tag
+		MaxAttributes int
+		// MaxTags is the maximum number of start tags in a template,
+		// including components
+		MaxTags int
+	}
 )
 
+// FuncComponentType is the type renderers return from KnownComponents for
+// function components.
+var FuncComponentType = reflect.TypeOf(&FuncComponent{})
+
 func New(name string, r Renderer, rawTemplate string) (*Template, error) {
+	return NewWithOptions(name, r, rawTemplate, Options{})
+}
+
+// NewWithOptions parses the given template like New, configured by the
+// provided options.
+func NewWithOptions(name string, r Renderer, rawTemplate string, opts Options) (*Template, error) {
 	t := &Template{
-		Name:         name,
-		htmltemplate: htmltemplate.New(name).Funcs(r.FuncMap()),
-		rawContent:   rawTemplate,
-		renderer:     r,
+		Name:       name,
+		rawContent: rawTemplate,
+		renderer:   r,
+		options:    opts,
+	}
+	t.base = newExecutor(name, opts.Trusted)
+	t.base.funcs(t.allowedFuncs(r.FuncMap()))
+
+	err := applyTemplateOptions(t.base, opts.TemplateOptions)
+	if err != nil {
+		return nil, err
 	}
 
-	// Ensure this component doesn't conflict with an existing HTML tag since
-	// this can break the recompilation strategy (because we don't consider
-	// matching HTML tags a potentially rendered component, so don't recompile
-	// dependencies upon registration)
-	if knownHTMLTags.IsKnown(name) {
-		return nil, fmt.Errorf("component %s conflicts with an existing HTML tag, consider suffixing it with Component", name)
+	err = opts.CheckTagConflict(name)
+	if err != nil {
+		return nil, err
 	}
 
-	err := t.parse()
+	err = t.parse()
 	if err != nil {
 		return nil, fmt.Errorf("could not parse template %s: %w", name, err)
 	}
@@ -62,40 +334,419 @@ func New(name string, r Renderer, rawTemplate string) (*Template, error) {
 	return t, err
 }
 
+// CheckTagConflict returns an error if a component with the given name would
+// conflict with an existing HTML tag, since this can break the recompilation
+// strategy (because we don't consider matching HTML tags a potentially
+// rendered component, so don't recompile dependencies upon registration).
+func (o Options) CheckTagConflict(name string) error {
+	if knownHTMLTags.IsKnown(name) && !o.shadows(name) {
+		return fmt.Errorf("component %s conflicts with an existing HTML tag, consider suffixing it with Component", name)
+	}
+
+	return nil
+}
+
+// shadows returns true if a component with the given name is allowed to share
+// its name with an HTML tag, which is always the case when components are
+// marked with a prefix since they can't be mistaken for HTML tags.
+func (o Options) shadows(name string) bool {
+	return o.AllowTagConflicts || o.ShadowedTags[name] || o.ComponentTagPrefix != ""
+}
+
+// applyTemplateOptions applies the given options to the template, returning an
+// error instead of panicking for unknown options.
+func applyTemplateOptions(t executor, opts []string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid template option: %v", r)
+		}
+	}()
+
+	t.option(opts...)
+
+	return nil
+}
+
+func (e *AssignmentError) Error() string {
+	message := fmt.Sprintf("cannot assign value of type %s to field %s.%s (%s), attribute %q", e.ValueType, e.Component, e.Field.Name, e.Field.Type, e.Attribute)
+	switch {
+	case e.Filename != "":
+		message += " in template " + e.Filename
+	case e.Template != "":
+		message += " in template " + e.Template
+	}
+
+	return message
+}
+
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("error rendering component %s: %s", e.Component, e.Err)
+}
+
+func (e *ComponentError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PanicError) Error() string {
+	if e.Filename != "" {
+		return fmt.Sprintf("panic in component %s (%s): %v", e.Component, e.Filename, e.Value)
+	}
+
+	return fmt.Sprintf("panic in component %s: %v", e.Component, e.Value)
+}
+
+// Unwrap returns the value passed to panic if it's an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+
+	return nil
+}
+
 // Execute delegates to the underlying html/template
-func (t *Template) Execute(w io.Writer, data any, funcMap htmltemplate.FuncMap) (err error) {
-	template, err := t.htmltemplate.Clone()
+func (t *Template) Execute(w io.Writer, data any, funcMap htmltemplate.FuncMap) error {
+	// Multi-target renders are routed by the TargetWriter, so they aren't
+	// counted towards the size estimate
+	if _, ok := w.(*TargetWriter); ok {
+		return t.execute(w, data, funcMap)
+	}
+
+	// Text renders aren't HTML, so they're neither counted nor checked for
+	// balanced tags
+	if _, ok := w.(*TextWriter); ok {
+		return t.execute(w, data, funcMap)
+	}
+
+	if t.options.CheckBalance {
+		return t.executeBalanced(w, data, funcMap)
+	}
+
+	cw := &countingWriter{w: w}
+	err := t.execute(cw, data, funcMap)
+	if err == nil {
+		t.renderSize.record(cw.n)
+	}
+
+	return err
+}
+
+// executeBalanced executes the template into a buffer, returning an
+// *UnbalancedError instead of writing the output if its tags aren't balanced.
+func (t *Template) executeBalanced(w io.Writer, data any, funcMap htmltemplate.FuncMap) error {
+	var b bytes.Buffer
+	b.Grow(t.renderSize.hint())
+
+	err := t.execute(&b, data, funcMap)
 	if err != nil {
-		panic("bug: somehow the template could not be cloned")
+		return err
+	}
+	t.renderSize.record(b.Len())
+
+	if tag, closing, ok := checkBalance(b.String()); !ok {
+		return &UnbalancedError{Component: t.Name, Tag: tag, Closing: closing}
+	}
+
+	_, err = b.WriteTo(w)
+
+	return err
+}
+
+// SizeHint returns the estimated size of the template's rendered output, based
+// on previous renders.
+func (t *Template) SizeHint() int {
+	return t.renderSize.hint()
+}
+
+func (t *Template) execute(w io.Writer, data any, funcMap htmltemplate.FuncMap) (err error) {
+	template := t.executor
+	targets, _ := w.(*TargetWriter)
+	text, _ := w.(*TextWriter)
+
+	// Funcs can't be changed once a template has been executed, so renders
+	// that provide their own funcs or render into multiple targets execute a
+	// fresh clone of the base template
+	if text != nil {
+		template, err = t.newTextExecutor(text)
+		if err != nil {
+			return fmt.Errorf("could not render %s as text: %w", t.Name, err)
+		}
+	} else if funcMap != nil || targets != nil {
+		template, err = t.newExecutor(targets)
+		if err != nil {
+			panic("bug: somehow the template could not be cloned")
+		}
+	}
+
+	if funcMap != nil {
+		// TODO: consider ensuring that all funcs in the func map are in the
+		// existing template funcMap
+		template.funcs(t.allowedFuncs(funcMap))
 	}
 
 	if recoverable, ok := data.(Recoverable); ok {
-		defer func() {
-			r := recover()
-			recoverable.Recover(w, r)
+		// Render into a buffer so partial output isn't written when the
+		// component fails and renders fallback content instead.
+		var b bytes.Buffer
+		var out io.Writer = &b
+		restore := func() {}
+		if targets != nil {
+			restore = targets.capture(&b)
+			out = targets
+		}
+
+		panicValue, err := t.executeRecovering(template, out, data)
+		restore()
+
+		// Recoverable components receive the original panic value instead of
+		// the annotated PanicError
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			panicValue = panicErr.Value
+		}
+
+		if panicValue != nil {
+			recoverable.Recover(w, panicValue)
+			return nil
+		}
+
+		if err != nil {
+			recoverable.Recover(w, err)
 
 			// Ensure we don't return an error and blow up the rest of the chain
-			err = nil
-		}()
+			return nil
+		}
 
-		var b bytes.Buffer
-		err = template.Execute(&b, data)
+		_, err = b.WriteTo(w)
+
+		return err
+	}
+
+	panicValue, err := t.executeRecovering(template, w, data)
+	if panicValue != nil {
+		return t.panicError(panicValue)
+	}
+
+	return err
+}
+
+// executeRecovering executes the template, returning the value passed to
+// panic, if any, or the error returned by execution. text/template recovers
+// panics in funcs and returns them as errors, so funcs that panicked are
+// converted into a *PanicError.
+func (t *Template) executeRecovering(template executor, w io.Writer, data any) (panicValue any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicValue = r
+		}
+	}()
+
+	err = template.Execute(w, data)
+
+	var funcPanic *funcPanic
+	if errors.As(err, &funcPanic) {
+		return nil, t.panicError(funcPanic.Value)
+	}
+
+	return nil, err
+}
+
+// panicError annotates the given panic value with the template's component.
+func (t *Template) panicError(value any) *PanicError {
+	if panicErr, ok := value.(*PanicError); ok {
+		return panicErr
+	}
+
+	return &PanicError{Component: t.Name, Filename: t.options.Filename, Value: value}
+}
+
+// Clone returns a copy of the template that renders nested components using
+// the given renderer. The underlying template is cloned, so the copy can
+// be executed independently of the original.
+func (t *Template) Clone(r Renderer) (*Template, error) {
+	base, err := t.base.clone()
+	if err != nil {
+		return nil, fmt.Errorf("could not clone template %s: %w", t.Name, err)
+	}
+
+	clone := &Template{
+		Name:                            t.Name,
+		base:                            base,
+		rawContent:                      t.rawContent,
+		staticAttributes:                t.staticAttributes,
+		defines:                         t.defines,
+		renderer:                        r,
+		options:                         t.options,
+		potentiallyReferencedComponents: make(map[string]bool, len(t.potentiallyReferencedComponents)),
+	}
+
+	for k, v := range t.potentiallyReferencedComponents {
+		clone.potentiallyReferencedComponents[k] = v
+	}
+	clone.references = t.references
+
+	clone.renderSize.average.Store(t.renderSize.average.Load())
+	clone.childrenSize.average.Store(t.childrenSize.average.Load())
+
+	clone.bindFuncs(clone.base, nil, nil)
+	clone.executor, err = clone.newExecutor(nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not clone template %s: %w", t.Name, err)
+	}
+
+	return clone, nil
+}
+
+// Extend returns a new template with the given name that renders like t,
+// except the templates defined by t's `{{block}}` actions are replaced with
+// the given blocks. Blocks are parsed like any other template, so they can
+// render components. t must define a template for each block.
+func (t *Template) Extend(name string, r Renderer, blocks map[string]string, opts Options) (*Template, error) {
+	if opts.Trusted != t.options.Trusted {
+		return nil, fmt.Errorf("could not extend template %s: %s must be trusted if and only if %s is trusted", t.Name, name, t.Name)
+	}
+
+	err := opts.CheckTagConflict(name)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := t.base.clone()
+	if err != nil {
+		return nil, fmt.Errorf("could not extend template %s: %w", t.Name, err)
+	}
+
+	extended := &Template{
+		Name:                            name,
+		base:                            base,
+		renderer:                        r,
+		options:                         opts,
+		potentiallyReferencedComponents: make(map[string]bool),
+	}
+	extended.base.funcs(r.FuncMap())
+	extended.bindFuncs(extended.base, nil, nil)
+
+	// Continue numbering from the base template so generated identifiers
+	// and static attributes don't conflict with the base's
+	c := &compiler{
+		defines:          t.defines,
+		staticAttributes: append([]map[string]any(nil), t.staticAttributes...),
+		constant:         extended.constantValue,
+		typedLiteral:     extended.typedLiteral,
+	}
+
+	// Sort the blocks so the compiled output is deterministic
+	names := make([]string, 0, len(blocks))
+	for blockName := range blocks {
+		names = append(names, blockName)
+	}
+	sort.Strings(names)
+
+	for _, blockName := range names {
+		if !extended.base.defines(blockName) {
+			return nil, fmt.Errorf("could not extend template %s: block %q isn't defined", t.Name, blockName)
+		}
+
+		extended.pos = 0
+		nodes, err := extended.parseRoot([]rune(blocks[blockName]), r.KnownComponents())
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("could not parse block %q of template %s: %w", blockName, name, err)
 		}
 
-		_, _ = io.Copy(w, &b)
+		content, defines := c.rawCompile(nodes, false)
+		content = strings.Join(defines, "") + fmt.Sprintf(`{{define %q}}%s{{end}}`, blockName, content)
+		extended.staticAttributes = c.staticAttributes
 
-		return nil
+		if opts.StrictBindings {
+			err = extended.checkBindings(content, blockName)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse block %q of template %s: %w", blockName, name, err)
+			}
+		}
+
+		// Parsing a define replaces the existing template with that name
+		extended.base, err = extended.base.parse(compileTargets(content))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse block %q of template %s: %w", blockName, name, err)
+		}
 	}
+	extended.pos = 0
+	extended.staticAttributes = c.staticAttributes
+	extended.defines = c.defines
 
-	if funcMap != nil {
-		// TODO: consider ensuring that all funcs in the func map are in the
-		// existing template funcMap
-		template.Funcs(funcMap)
+	extended.executor, err = extended.newExecutor(nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not extend template %s: %w", t.Name, err)
+	}
+
+	return extended, nil
+}
+
+// newExecutor returns a clone of the base template that renders nested
+// components and child content using the clone. When targets is non-nil,
+// output is routed between the targets' writers.
+func (t *Template) newExecutor(targets *TargetWriter) (executor, error) {
+	executor, err := t.base.clone()
+	if err != nil {
+		return nil, err
+	}
+
+	t.bindFuncs(executor, targets, nil)
+
+	return executor, nil
+}
+
+// bindFuncs binds the funcs that depend on the template and the executor
+// being executed, since child content must be executed using the same
+// executor as its parent. When text is non-nil, nested components are
+// rendered as text too.
+func (t *Template) bindFuncs(executor executor, targets *TargetWriter, text *TextWriter) {
+	funcs := map[string]any{
+		"__glamRenderComponent": t.generateRenderFunc(executor, targets, text),
+		"__glamChildComponent":  t.generateChildComponentFunc(executor, targets, text),
+		"__glamStaticAttributes": func(i int) map[string]any {
+			return t.staticAttributes[i]
+		},
+		"__glamTarget":    targets.enter,
+		"__glamEndTarget": targets.exit,
+	}
+	if !t.userFunc("render") {
+		funcs["render"] = func(value any) (htmltemplate.HTML, error) {
+			return t.renderValue(value, targets, text)
+		}
+	}
+
+	executor.funcs(t.allowedFuncs(funcs))
+}
+
+// userFunc reports whether the renderer provides a func with the given name,
+// which takes precedence over glam's builtin funcs of the same name.
+func (t *Template) userFunc(name string) bool {
+	_, ok := t.renderer.FuncMap()[name]
+
+	return ok
+}
+
+// parseFuncs returns the funcs that compiled templates call, which don't
+// depend on the executor being executed.
+func (t *Template) parseFuncs() map[string]any {
+	funcs := map[string]any{
+		// Flushing is a no-op unless a render provides a flush func
+		"__glamFlush":    func() bool { return false },
+		"__glamOptional": optionalAttribute,
+		"__glamKeyed":    withKey,
+		"__glamChildComponents": func(children ...*childComponent) []*childComponent {
+			return children
+		},
 	}
 
-	return template.Execute(w, data)
+	return funcs
+}
+
+// PotentialReferences returns the capitalized tags in the template that may
+// refer to components that haven't been registered, in the order they appear.
+func (t *Template) PotentialReferences() []Reference {
+	return t.references
 }
 
 func (t *Template) ComponentsPotentiallyReferenced() map[string]bool {
@@ -114,45 +765,86 @@ func (t *Template) RawContent() string {
 // template. It also tracks any components that are referenced in the template
 // so they can be recompiled if/when they are registered with the engine.
 func (t *Template) parse() error {
-	t.htmltemplate.Funcs(htmltemplate.FuncMap{
-		"__glamRenderComponent": t.generateRenderFunc(),
-		"safe": func(s string) htmltemplate.HTML {
-			return htmltemplate.HTML(s)
-		},
-	})
+	t.bindFuncs(t.base, nil, nil)
+	t.base.funcs(t.allowedFuncs(t.parseFuncs()))
 
 	t.potentiallyReferencedComponents = make(map[string]bool)
+	t.references = nil
 
 	// If we have no potentially referenced components that might require
 	// recompilation, we can save some space and remove the content
 	defer func() {
 		t.pos = 0
-		if len(t.potentiallyReferencedComponents) == 0 {
+		if len(t.potentiallyReferencedComponents) == 0 && !t.options.RetainSource {
 			t.rawContent = ""
 		}
 	}()
 
+	// Includes are spliced in on every parse, so recompiling the template
+	// picks up changes to included files
+	source := t.rawContent
+	if t.options.IncludeFS != nil {
+		var err error
+		source, err = spliceIncludes(t.options.IncludeFS, source, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.options.Policy != nil {
+		err := t.checkPolicy(source)
+		if err != nil {
+			return err
+		}
+	}
+
 	// turn template into AST nodes
-	nodes := t.parseRoot([]rune(t.rawContent), t.renderer.KnownComponents())
+	nodes, err := t.parseRoot([]rune(source), t.renderer.KnownComponents())
+	if err != nil {
+		return err
+	}
 
 	// Turn nodes into an html/template compatible string
-	content := compile(nodes)
+	c := &compiler{constant: t.constantValue, typedLiteral: t.typedLiteral}
+	content, staticAttributes := c.compile(nodes)
+	content = compileTargets(content)
+	t.staticAttributes = staticAttributes
+	t.defines = c.defines
+
+	if t.options.StrictBindings {
+		err = t.checkBindings(content, t.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.base, err = t.base.parse(content)
+	if err != nil {
+		return fmt.Errorf("error parsing template: %w", err)
+	}
 
-	var err error
-	t.htmltemplate, err = t.htmltemplate.Parse(content)
+	t.executor, err = t.newExecutor(nil)
 	if err != nil {
 		return fmt.Errorf("error parsing template: %w", err)
 	}
 
+	if t.options.Partials != "" {
+		return t.AddPartials(t.options.Partials)
+	}
+
 	return nil
 }
 
-func (t *Template) parseRoot(runes []rune, components map[string]reflect.Type) []*Node {
+func (t *Template) parseRoot(runes []rune, components map[string]reflect.Type) ([]*Node, error) {
 	nodes := make([]*Node, 0)
+	t.foreign = 0
+	t.depth = 0
+	t.tags = 0
 
 	start := t.pos
 	for t.pos < len(runes) {
-		if runes[t.pos] == '<' {
+		// A < that can't start a tag is left in the raw content
+		if runes[t.pos] == '<' && t.pos+1 < len(runes) && startsTag(runes[t.pos+1]) {
 			if start != t.pos {
 				nodes = append(nodes, &Node{
 					Type: NodeTypeRaw,
@@ -161,7 +853,7 @@ func (t *Template) parseRoot(runes []rune, components map[string]reflect.Type) [
 			}
 			n, err := t.parseTag(runes, components)
 			if err != nil {
-				panic(err)
+				return nil, err
 			}
 			nodes = append(nodes, n)
 
@@ -179,7 +871,7 @@ func (t *Template) parseRoot(runes []rune, components map[string]reflect.Type) [
 		})
 	}
 
-	return nodes
+	return nodes, nil
 }
 
 // ParseTag parses an HTML tag and either emits it, or generates the necessary
@@ -195,11 +887,24 @@ func (t *Template) parseTag(runes []rune, components map[string]reflect.Type) (*
 	// skip the <
 	t.pos++
 
+	// A < at the end of the input or that isn't followed by something that
+	// can start a tag, like in `a < b`, is literal text
+	if t.pos >= len(runes) || !startsTag(runes[t.pos]) {
+		return &Node{
+			Type: NodeTypeRaw,
+			Raw:  string(runes[start:t.pos]),
+		}, nil
+	}
+
 	// If we're in a closing tag, we can just emit it
 	if runes[t.pos] == '/' {
-		for runes[t.pos] != '>' {
+		for t.pos < len(runes) && runes[t.pos] != '>' {
 			t.pos++
 		}
+		if t.pos >= len(runes) {
+			return nil, t.unterminatedTag(runes)
+		}
+		t.closeForeignElement(runes[start+2 : t.pos])
 
 		// skip the >
 		t.pos++
@@ -211,24 +916,42 @@ func (t *Template) parseTag(runes []rune, components map[string]reflect.Type) (*
 		}, nil
 	}
 
+	t.tags++
+	if limit := t.options.Limits.MaxTags; limit > 0 && t.tags > limit {
+		t.pos = start
+		return nil, t.parseError(runes, "template has more than %d tags", limit)
+	}
+
 	// If we have a matching component, we need to generate the relevant code and omit the tag
 	// and the end tag from the output
-	if unicode.IsUpper(runes[t.pos]) {
-		tagNameStart := t.pos
+	if prefixLength, ok := t.componentTag(runes); ok && t.foreign == 0 {
+		tagStart := t.pos
+		tagNameStart := t.pos + prefixLength
+		t.pos = tagNameStart
 
 		// loop until we find the end of tag name
-		for runes[t.pos] != ' ' && runes[t.pos] != '>' && runes[t.pos] != '/' {
+		for t.pos < len(runes) && !unicode.IsSpace(runes[t.pos]) && runes[t.pos] != '>' && runes[t.pos] != '/' {
 			t.pos++
 		}
 
 		tagName := runes[tagNameStart:t.pos]
 
+		// Tag names are used to generate code, so ensure they can't contain
+		// anything that could be interpreted as template syntax
+		if !componentTagPattern.MatchString(string(tagName)) {
+			t.pos = tagNameStart
+			return nil, t.parseError(runes, "invalid tag name %q", string(tagName))
+		}
+
 		attrs, err := t.parseAttributes(runes)
 		if err != nil {
 			return nil, fmt.Errorf("error parsing attributes: %w", err)
 		}
 
 		t.skipWhitespace(runes)
+		if t.pos >= len(runes) {
+			return nil, t.unterminatedTag(runes)
+		}
 
 		switch runes[t.pos] {
 		// we're in a self closing tag
@@ -240,21 +963,23 @@ func (t *Template) parseTag(runes []rune, components map[string]reflect.Type) (*
 			t.skipWhitespace(runes)
 
 			// Ensure we're actually closing the component
-			if runes[t.pos] != '>' {
+			if t.pos >= len(runes) || runes[t.pos] != '>' {
 				return nil, fmt.Errorf("found invalid HTML")
 			}
 
 			// Skip the >
 			t.pos++
 
-			if _, ok := components[string(tagName)]; ok {
-				return &Node{
-					Type:       NodeTypeComponent,
-					TagName:    string(tagName),
-					Attributes: attrs,
-					Children:   make([]*Node, 0),
-				}, nil
+			if componentType, ok := components[string(tagName)]; ok {
+				return t.componentNode(runes, start, string(tagName), componentType, attrs, make([]*Node, 0))
 			}
+
+			t.trackReference(runes, start, string(tagName))
+
+			return &Node{
+				Type: NodeTypeRaw,
+				Raw:  string(runes[start:t.pos]),
+			}, nil
 		// We're in a full tag
 		case '>':
 			// There's a choice to be made here, we could either:
@@ -270,29 +995,17 @@ func (t *Template) parseTag(runes []rune, components map[string]reflect.Type) (*
 			// If we have a matching component, we need to return a component node instead
 			// of a raw node, which includes parsing content until we find the
 			// relevant end tag so it can be lifted into a `define` block later.
-			if _, ok := components[string(tagName)]; ok {
-				children, err := t.parseUntilCloseTag(runes, tagName, components)
+			if componentType, ok := components[string(tagName)]; ok {
+				// The end tag includes the prefix, e.g. `</g:Button>`
+				children, err := t.parseUntilCloseTag(runes, runes[tagStart:tagNameStart+len(tagName)], withChildComponents(components, componentType))
 				if err != nil {
 					return nil, fmt.Errorf("error parsing children: %w", err)
 				}
 
-				return &Node{
-					Type:       NodeTypeComponent,
-					TagName:    string(tagName),
-					Attributes: attrs,
-					Children:   children,
-				}, nil
+				return t.componentNode(runes, start, string(tagName), componentType, attrs, children)
 			}
 
-			// skip the >
-			t.pos++
-
-			// If this isn't just a capitalized HTML tag, keep track of this
-			// potential component so we can recompile the template if it's
-			// registered
-			if !knownHTMLTags.IsKnown(string(tagName)) {
-				t.potentiallyReferencedComponents[string(tagName)] = true
-			}
+			t.trackReference(runes, start, string(tagName))
 
 			return &Node{
 				Type: NodeTypeRaw,
@@ -306,92 +1019,358 @@ func (t *Template) parseTag(runes []rune, components map[string]reflect.Type) (*
 	//   - Parse the attributes
 
 	// loop until we find the end of tag name
-	for runes[t.pos] != ' ' && runes[t.pos] != '>' && runes[t.pos] != '/' {
+	tagNameStart := t.pos
+	for t.pos < len(runes) && runes[t.pos] != ' ' && runes[t.pos] != '>' && runes[t.pos] != '/' {
 		t.pos++
 	}
+	tagName := runes[tagNameStart:t.pos]
 
 	// If we're here, we're in a raw tag, so we need to parse the content until
 	// we find another opening tag. We'll parse the attributes though, so we can
 	// skip them without worrying too much about quotes
-	_, err := t.parseAttributes(runes)
+	attrs, err := t.parseAttributeList(runes)
 
 	if err != nil {
 		return nil, fmt.Errorf("error parsing attributes: %w", err)
 	}
 	t.skipWhitespace(runes)
+	if t.pos >= len(runes) {
+		return nil, t.unterminatedTag(runes)
+	}
 
 	// Check if we're self-closing and skip over it
-	if runes[t.pos] == '/' {
+	selfClosing := runes[t.pos] == '/'
+	if selfClosing {
 		t.pos++
 	}
 
 	// We would expect to find a > here, so let's double check and skip it
+	if t.pos >= len(runes) {
+		return nil, t.unterminatedTag(runes)
+	}
 	if runes[t.pos] != '>' {
-		panic("unexpected character when parsing tag")
+		return nil, t.parseError(runes, "unexpected character %q when parsing tag", runes[t.pos])
 	}
 
 	// skip the >
 	t.pos++
 
+	if !selfClosing && isForeignElement(tagName) {
+		t.foreign++
+	}
+
+	raw, err := t.rawTag(runes, start, attrs)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Node{
 		Type: NodeTypeRaw,
-		Raw:  string(runes[start:t.pos]),
+		Raw:  raw,
 	}, nil
 }
 
-func (t *Template) parseAttributes(runes []rune) (map[string]string, error) {
-	attributes := make(map[string]string)
-
-	// If we have a > we can return the attributes as-is
-	if runes[t.pos] == '>' {
-		return attributes, nil
+// trackReference keeps track of a capitalized tag that isn't a registered
+// component, so the template can be recompiled if it's registered later.
+// Capitalized HTML tags, like `<DIV>`, aren't tracked unless they're allowed
+// to be shadowed, since they can be registered later.
+func (t *Template) trackReference(runes []rune, start int, tagName string) {
+	if knownHTMLTags.IsKnown(tagName) && !t.options.shadows(tagName) {
+		return
+	}
+
+	t.potentiallyReferencedComponents[tagName] = true
+
+	line, column := position(runes, start)
+	t.references = append(t.references, Reference{Name: tagName, Line: line, Column: column})
+}
+
+// isForeignElement reports whether the tag name is an <svg> or <math>
+// element, whose content is foreign to HTML.
+func isForeignElement(tagName []rune) bool {
+	name := strings.ToLower(strings.TrimSpace(string(tagName)))
+
+	return name == "svg" || name == "math"
+}
+
+// closeForeignElement leaves the foreign content of an <svg> or <math>
+// element when the end tag with the given name closes it.
+func (t *Template) closeForeignElement(tagName []rune) {
+	if t.foreign > 0 && isForeignElement(tagName) {
+		t.foreign--
+	}
+}
+
+// rawTag returns the content of the raw tag starting at start, rewriting
+// optional attributes, like `alt?="{{.Alt}}"`, so they're omitted when their
+// value is empty.
+func (t *Template) rawTag(runes []rune, start int, attrs []attribute) (string, error) {
+	var b strings.Builder
+	pos := start
+
+	for _, attr := range attrs {
+		name, optional := strings.CutSuffix(attr.rawName, "?")
+		if !optional {
+			continue
+		}
+
+		pipeline, err := t.optionalPipeline(runes, attr)
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteString(string(runes[pos:attr.start]))
+		fmt.Fprintf(&b, `{{with %s}}%s=%c{{.}}%c{{end}}`, pipeline, name, attr.quote, attr.quote)
+		pos = attr.end
+	}
+
+	b.WriteString(string(runes[pos:t.pos]))
+
+	return b.String(), nil
+}
+
+// optionalPipeline returns the pipeline of an optional attribute, which must
+// be a single action so it can be omitted when the action's value is empty.
+func (t *Template) optionalPipeline(runes []rune, attr attribute) (string, error) {
+	pipeline, ok := actionPipeline(attr.value)
+	if !ok || attr.name == "?" {
+		t.pos = attr.start
+		return "", t.parseError(runes, "optional attribute %s must be a single action, got %q", attr.rawName, attr.value)
+	}
+
+	return pipeline, nil
+}
+
+// componentTag reports whether the tag name at the current position may
+// refer to a component, returning the length of the prefix that marks it as
+// a component, if any. Without a prefix, capitalized tags are components.
+func (t *Template) componentTag(runes []rune) (int, bool) {
+	prefix := []rune(t.options.ComponentTagPrefix)
+	if t.pos+len(prefix) >= len(runes) || string(runes[t.pos:t.pos+len(prefix)]) != string(prefix) {
+		return 0, false
+	}
+
+	return len(prefix), unicode.IsUpper(runes[t.pos+len(prefix)])
+}
+
+// startsTag reports whether r can follow a < to start a tag. Like HTML, a <
+// followed by anything other than a letter, /, !, or ? is literal text. {
+// is allowed so tag names can be rendered using an action, e.g.
+// `<{{.Tag}}>`.
+func startsTag(r rune) bool {
+	return unicode.IsLetter(r) || r == '/' || r == '!' || r == '?' || r == '{'
+}
+
+// componentNode returns a component node, moving reserved attributes that are
+// handled by the compiler, like `if`, `range`, and `key`, out of the attributes
+// passed to the component. start is the position of the component's tag, used for
+// errors.
+func (t *Template) componentNode(runes []rune, start int, tagName string, componentType reflect.Type, attrs map[string]string, children []*Node) (*Node, error) {
+	if t.options.Policy != nil && !t.options.Policy.allowsComponent(tagName) {
+		t.pos = start
+		return nil, t.parseError(runes, "component %s isn't allowed by the template's policy", tagName)
+	}
+
+	node := &Node{
+		Type:       NodeTypeComponent,
+		TagName:    tagName,
+		Attributes: attrs,
+		Children:   children,
+	}
+
+	for _, reserved := range []string{"if", "range"} {
+		value, ok := attrs[reserved]
+		if !ok {
+			continue
+		}
+
+		if hasAttributeField(componentType, reserved) {
+			t.pos = start
+			return nil, t.parseError(runes, "component %s can't use the reserved %s attribute since it has a field named %s", tagName, reserved, reserved)
+		}
+
+		pipeline, ok := actionPipeline(value)
+		if !ok {
+			t.pos = start
+			return nil, t.parseError(runes, "%s attribute on component %s must be a single action, got %q", reserved, tagName, value)
+		}
+
+		if reserved == "if" {
+			node.If = pipeline
+		} else {
+			node.Range = pipeline
+		}
+		delete(attrs, reserved)
+	}
+
+	if key, ok := attrs["key"]; ok {
+		if hasAttributeField(componentType, "key") {
+			t.pos = start
+			return nil, t.parseError(runes, "component %s can't use the reserved key attribute since it has a field named key", tagName)
+		}
+
+		node.Key = key
+		delete(attrs, "key")
+	}
+
+	// Optional attributes are compiled so they're omitted when their action's
+	// value is empty, which requires the value to be a single action
+	for name, value := range attrs {
+		if !strings.HasSuffix(name, "?") {
+			continue
+		}
+
+		if _, ok := actionPipeline(value); !ok || name == "?" {
+			t.pos = start
+			return nil, t.parseError(runes, "optional attribute %s on component %s must be a single action, got %q", name, tagName, value)
+		}
+	}
+
+	// Child components collected into a field are passed to the component
+	// using the field's attribute instead of being rendered as child content
+	fields := childComponentFields(componentType)
+	if len(fields) == 0 {
+		return node, nil
+	}
+
+	node.Children = make([]*Node, 0, len(children))
+	for _, child := range children {
+		field, ok := fields[child.TagName]
+		if child.Type != NodeTypeComponent || !ok {
+			node.Children = append(node.Children, child)
+			continue
+		}
+
+		name := AttributeName(field)
+		if _, ok := attrs[name]; ok {
+			t.pos = start
+			return nil, t.parseError(runes, "component %s can't be passed the %s attribute since it's populated by its %s children", tagName, name, child.TagName)
+		}
+
+		if child.If != "" || child.Range != "" {
+			t.pos = start
+			return nil, t.parseError(runes, "child component %s of %s can't use the reserved if or range attributes", child.TagName, tagName)
+		}
+
+		if child.Key != "" {
+			t.pos = start
+			return nil, t.parseError(runes, "child component %s of %s can't use the reserved key attribute since it isn't rendered", child.TagName, tagName)
+		}
+
+		if node.ChildComponents == nil {
+			node.ChildComponents = make(map[string][]*Node)
+		}
+		node.ChildComponents[name] = append(node.ChildComponents[name], child)
+	}
+
+	return node, nil
+}
+
+// hasAttributeField returns true if the component type has a field populated
+// by the given attribute.
+func hasAttributeField(componentType reflect.Type, name string) bool {
+	if componentType.Kind() == reflect.Ptr {
+		componentType = componentType.Elem()
+	}
+
+	for i := 0; i < componentType.NumField(); i++ {
+		if strings.EqualFold(AttributeName(componentType.Field(i)), name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *Template) parseAttributes(runes []rune) (map[string]string, error) {
+	list, err := t.parseAttributeList(runes)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := make(map[string]string, len(list))
+	for _, attr := range list {
+		attributes[attr.name] = withoutComments(attr.value)
+	}
+
+	return attributes, nil
+}
+
+// parseAttributeList parses the attributes of a tag in the order they appear,
+// including the position of each attribute so the tag can be rewritten.
+func (t *Template) parseAttributeList(runes []rune) ([]attribute, error) {
+	attributes := make([]attribute, 0)
+
+	// If we have a > we can return the attributes as-is
+	if t.pos >= len(runes) || runes[t.pos] == '>' {
+		return attributes, nil
 	}
 
 	t.skipWhitespace(runes)
 
-	for runes[t.pos] != '>' && runes[t.pos] != '/' {
+	// The caller reports tags that end before the input does
+	for t.pos < len(runes) && runes[t.pos] != '>' && runes[t.pos] != '/' {
+		// An attribute must have a name before its value, e.g. `<div =foo>` is
+		// invalid and would otherwise produce an empty attribute name.
+		if runes[t.pos] == '=' {
+			return nil, t.parseError(runes, "unexpected '=' without an attribute name")
+		}
+
+		if limit := t.options.Limits.MaxAttributes; limit > 0 && len(attributes) == limit {
+			return nil, t.parseError(runes, "tag has more than %d attributes", limit)
+		}
+
 		nameStart := t.pos
 		// Loop until we find the end of the attribute which can be:
-		//   - a space (boolean attribute)
-		//   - a > (end of tag, also boolean attribute)
+		//   - whitespace (boolean attribute)
+		//   - a > or / (end of tag, also boolean attribute)
 		//   - a = (quoted attribute, but there can also be "raw" attributes with no quotes)
-		for !unicode.IsSpace(runes[t.pos]) && runes[t.pos] != '=' || runes[t.pos] == '>' {
+		for t.pos < len(runes) && !unicode.IsSpace(runes[t.pos]) && runes[t.pos] != '=' && runes[t.pos] != '>' && runes[t.pos] != '/' {
 			t.pos++
 		}
+		if t.pos >= len(runes) {
+			return nil, t.unterminatedTag(runes)
+		}
 
 		// Lowercase the attribute name so we can ignore case sensitivity when
 		// assigning attributes to struct fields
 		name := strings.ToLower(string(runes[nameStart:t.pos]))
 
-		switch runes[t.pos] {
-		// If we have a / we can consume it and subsequent whitespace and return attributes as-is
-		case '/':
-			t.pos++
-			t.skipWhitespace(runes)
-			attributes[name] = "true"
-			return attributes, nil
-		// If we have a > we can return the attributes as-is
-		case '>':
-			attributes[name] = "true"
-			return attributes, nil
-		// If we have a ' ' we can set the boolean attribute and move on
-		case ' ':
+		attr := attribute{name: name, rawName: string(runes[nameStart:t.pos]), start: nameStart}
+
+		switch r := runes[t.pos]; {
+		// If we have a / or > we're at the end of the tag, so we can return
+		// the attributes and let the caller handle the end of the tag
+		case r == '/' || r == '>':
+			attr.value = "true"
+			attr.end = t.pos
+			return append(attributes, attr), nil
+		// If we have whitespace we can set the boolean attribute and move on
+		case unicode.IsSpace(r):
+			attr.value = "true"
+			attr.end = t.pos
+			attributes = append(attributes, attr)
+
 			// TODO check if there's an equal sign after this space
 			t.skipWhitespace(runes)
-
-			attributes[name] = "true"
 			continue
 		// If we have an = we need to find the end of the attribute value
-		case '=':
+		case r == '=':
 			// Skip the =
 			t.pos++
+			if t.pos >= len(runes) {
+				return nil, t.unterminatedTag(runes)
+			}
 
+			attr.quote = runes[t.pos]
 			value, err := t.parseQuotedAttribute(runes)
 			if err != nil {
 				return nil, fmt.Errorf("error parsing quoted attribute: %w", err)
 			}
 
-			attributes[name] = string(value)
+			attr.value = string(value)
+			attr.end = t.pos
+			attributes = append(attributes, attr)
 		}
 
 		// Skip any whitespace
@@ -411,6 +1390,11 @@ func (t *Template) parseQuotedAttribute(runes []rune) ([]rune, error) {
 	valueStart := t.pos
 
 	for {
+		if t.pos >= len(runes) {
+			t.pos = valueStart - 1
+			return nil, t.parseError(runes, "unterminated attribute value")
+		}
+
 		switch runes[t.pos] {
 		// We're at the end of the tag, so we can just return
 		case quote:
@@ -423,45 +1407,120 @@ func (t *Template) parseQuotedAttribute(runes []rune) ([]rune, error) {
 		// We might have a go template tag which means we need to handle quotes
 		// inside of it
 		case '{':
-			if runes[t.pos+1] == '{' {
+			if t.pos+1 < len(runes) && runes[t.pos+1] == '{' {
 				t.skipGoTemplate(runes)
 			} else {
 				t.pos++
 			}
+		// Attribute values are text, so components can't be rendered in them
+		case '<':
+			if name, ok := t.componentInAttribute(runes); ok {
+				return nil, t.parseError(runes, "component %s can't be used in an attribute value, pass it as child content or render it in the component's template instead", name)
+			}
+
+			t.pos++
 		default:
 			t.pos++
 		}
 	}
 }
 
+// componentInAttribute returns the name of the component referenced by the
+// start or end tag at the current position of an attribute value, e.g.
+// `label="<Inner/>"`.
+func (t *Template) componentInAttribute(runes []rune) (string, bool) {
+	start := t.pos
+	defer func() { t.pos = start }()
+
+	t.pos++
+	if t.pos < len(runes) && runes[t.pos] == '/' {
+		t.pos++
+	}
+
+	prefixLength, ok := t.componentTag(runes)
+	if !ok {
+		return "", false
+	}
+
+	nameStart := t.pos + prefixLength
+	nameEnd := nameStart
+	for nameEnd < len(runes) && !unicode.IsSpace(runes[nameEnd]) && runes[nameEnd] != '>' && runes[nameEnd] != '/' && runes[nameEnd] != '"' && runes[nameEnd] != '\'' {
+		nameEnd++
+	}
+
+	name := string(runes[nameStart:nameEnd])
+	if !componentTagPattern.MatchString(name) {
+		return "", false
+	}
+
+	return name, true
+}
+
+// skipGoTemplate skips past the Go template action at the current position.
+// Quotes and }} inside of string literals, including backtick quoted raw
+// strings, are skipped, so they don't end the action or the attribute it's
+// in, e.g. label="{{ printf `"%s"` .Name }}". Comments are skipped until
+// their closing */, so quotes in them are ignored, e.g. `{{/* it's */}}`.
 func (t *Template) skipGoTemplate(runes []rune) {
 	// skip the {{
 	t.pos += 2
 
-	// This is a bit naive, but we're just going to skip until we find the end
-	// of the tag ignoring any potential }} values inside of it that may be part
-	// of string literals
-	for runes[t.pos] != '}' && runes[t.pos+1] != '}' {
-		t.pos++
+	commentStart := t.pos
+	if commentStart+1 < len(runes) && runes[commentStart] == '-' && runes[commentStart+1] == ' ' {
+		commentStart += 2
+	}
+	if commentStart+1 < len(runes) && runes[commentStart] == '/' && runes[commentStart+1] == '*' {
+		for t.pos = commentStart + 2; t.pos+1 < len(runes) && (runes[t.pos] != '*' || runes[t.pos+1] != '/'); t.pos++ {
+		}
+		// skip the */
+		t.pos = min(t.pos+2, len(runes))
 	}
 
-	// skip the }}
-	t.pos += 2
+	for t.pos < len(runes) {
+		switch runes[t.pos] {
+		case '"', '\'':
+			quote := runes[t.pos]
+			for t.pos++; t.pos < len(runes) && runes[t.pos] != quote; t.pos++ {
+				if runes[t.pos] == '\\' {
+					t.pos++
+				}
+			}
+			t.pos = min(t.pos, len(runes)-1)
+		case '`':
+			// Raw strings can't contain escapes
+			for t.pos++; t.pos < len(runes) && runes[t.pos] != '`'; t.pos++ {
+			}
+		case '}':
+			if t.pos+1 < len(runes) && runes[t.pos+1] == '}' {
+				// skip the }}
+				t.pos += 2
+				return
+			}
+		}
+
+		t.pos++
+	}
 }
 
 func (t *Template) parseUntilCloseTag(runes []rune, tagName []rune, components map[string]reflect.Type) ([]*Node, error) {
+	t.depth++
+	defer func() { t.depth-- }()
+	if limit := t.options.Limits.MaxDepth; limit > 0 && t.depth > limit {
+		return nil, t.parseError(runes, "components can't be nested more than %d levels deep", limit)
+	}
+
 	nodes := make([]*Node, 0)
 
 	start := t.pos
 	for {
 		if t.pos >= len(runes) {
-			panic("unclosed component tag")
+			return nil, t.parseError(runes, "unclosed component tag %s", string(tagName))
 		}
 
 		switch runes[t.pos] {
 		// we might be in a tag, which could be closing, could be another component, or could be an unescaped <
 		case '<':
-			if runes[t.pos+1] == '/' {
+			if t.pos+1 < len(runes) && runes[t.pos+1] == '/' {
 				// Capture end before we read the tag so we can emit the raw content
 				// if we have a matching end tag
 				end := t.pos
@@ -470,12 +1529,16 @@ func (t *Template) parseUntilCloseTag(runes []rune, tagName []rune, components m
 				t.pos += 2
 
 				endTagStart := t.pos
-				for runes[t.pos] != '>' {
+				for t.pos < len(runes) && runes[t.pos] != '>' {
 					t.pos++
 				}
+				if t.pos >= len(runes) {
+					return nil, t.unterminatedTag(runes)
+				}
 
 				// Capture the end tag name before the >
 				endTagName := runes[endTagStart:t.pos]
+				t.closeForeignElement(endTagName)
 
 				// skip the >
 				t.pos++
@@ -491,16 +1554,20 @@ func (t *Template) parseUntilCloseTag(runes []rune, tagName []rune, components m
 						})
 					}
 
+					// Elements left open in the children are closed by the
+					// component's end tag, like in HTML
+					t.foreign = 0
+
 					// TODO we need to emit the already captured nodes too
 					return nodes, nil
 				}
-			} else if unicode.IsLetter(runes[t.pos+1]) {
+			} else if t.pos+1 < len(runes) && unicode.IsLetter(runes[t.pos+1]) {
 				// We're about to run another parser, so we need to capture the raw content
-				// if we've captured any content
+				// before the < if we've captured any content
 				if t.pos != start {
 					nodes = append(nodes, &Node{
 						Type: NodeTypeRaw,
-						Raw:  string(runes[start : t.pos-1]),
+						Raw:  string(runes[start:t.pos]),
 					})
 				}
 
@@ -522,68 +1589,653 @@ func (t *Template) parseUntilCloseTag(runes []rune, tagName []rune, components m
 	}
 }
 
+// parseError returns an error prefixed with the line and column of the
+// current position in the template.
+func (t *Template) parseError(runes []rune, format string, args ...any) error {
+	line, column := position(runes, t.pos)
+
+	return fmt.Errorf("%d:%d: %s", line, column, fmt.Sprintf(format, args...))
+}
+
+// position returns the line and column of the given position in the
+// template, starting at 1.
+func position(runes []rune, pos int) (line int, column int) {
+	line, column = 1, 1
+	for _, r := range runes[:min(pos, len(runes))] {
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+
+	return line, column
+}
+
 func (t *Template) skipWhitespace(runes []rune) {
-	for unicode.IsSpace(runes[t.pos]) {
+	for t.pos < len(runes) && unicode.IsSpace(runes[t.pos]) {
 		t.pos++
 	}
 }
 
-func (t *Template) generateRenderFunc() func(string, string, map[string]any, any) htmltemplate.HTML {
-	return func(name string, identifier string, attributes map[string]any, existingData any) htmltemplate.HTML {
+// unterminatedTag returns the error for a tag that isn't closed before the
+// end of the template, e.g. `<div class="a"`.
+func (t *Template) unterminatedTag(runes []rune) error {
+	return t.parseError(runes, "unexpected end of template in tag")
+}
+
+// generateRenderFunc returns the function used to render nested components.
+// Child content is executed using the given template, which should be the
+// template currently being executed.
+func (t *Template) generateRenderFunc(tmpl executor, targets *TargetWriter, text *TextWriter) func(string, string, map[string]any, any) (htmltemplate.HTML, error) {
+	return func(name string, identifier string, attributes map[string]any, existingData any) (htmltemplate.HTML, error) {
 		componentType, ok := t.renderer.KnownComponents()[name]
 		if !ok {
-			panic(fmt.Errorf("component %s not found", name))
+			return "", &ComponentError{Component: name, Err: fmt.Errorf("component %s not found", name)}
 		}
 
-		// Get the type of the component, and if it's a pointer, get the underlying type
-		// so we can create a new instance of it
-		isPointer := componentType.Kind() == reflect.Ptr
-		if isPointer {
-			componentType = componentType.Elem()
+		attributes = withoutOmitted(attributes)
+		attributes = mapAttributes(t.attributeMapping(name), attributes)
+
+		if t.options.AttributeTransformer != nil {
+			var err error
+			attributes, err = transformAttributes(t.options.AttributeTransformer, name, componentType, attributes)
+			if err != nil {
+				return "", &ComponentError{Component: name, Err: err}
+			}
 		}
 
-		// Create a new instance of the component
-		toRender := reflect.New(componentType)
-		toCallRenderOn := toRender
-		if isPointer {
-			toRender = toRender.Elem()
+		if reporter, ok := t.renderer.(renderReporter); ok {
+			reporter.ReportRender(name, t.Name)
 		}
 
-		// Loop through the attributes and set them on the component
-		for i := 0; i < componentType.NumField(); i++ {
-			fieldType := componentType.Field(i)
-			field := toRender.Field(i)
-			if !field.CanSet() {
-				continue
+		children := t.childrenFunc(tmpl, targets, text, identifier, existingData)
+
+		var component any
+		var err error
+		if componentType == FuncComponentType {
+			component, err = newFuncComponent(name, attributes, children)
+		} else {
+			var instance any
+			if factory, ok := t.renderer.(componentFactory); ok {
+				instance = factory.NewComponent(name, componentType)
 			}
 
-			if fieldType.Name == "Children" {
-				var b bytes.Buffer
-				err := t.htmltemplate.ExecuteTemplate(&b, identifier, existingData)
-				if err != nil {
-					panic(err)
-				}
-				field.Set(reflect.ValueOf(htmltemplate.HTML(b.String())))
+			component, err = instantiateFrom(componentType, instance, attributes, children, t.unassignable, t.options.LenientTypedStrings)
+		}
+		if err != nil {
+			return "", &ComponentError{Component: name, Err: err}
+		}
+
+		var b strings.Builder
+		if hinter, ok := t.renderer.(sizeHinter); ok {
+			b.Grow(hinter.SizeHint(component))
+		}
+
+		// Nested components inherit the active target, or are rendered as
+		// text when their parent is
+		w := text.wrap(&b)
+		if targets != nil {
+			defer targets.capture(&b)()
+			w = targets
+		}
+
+		err = t.renderer.Render(w, component)
+		if err != nil {
+			return "", &ComponentError{Component: name, Err: err}
+		}
+
+		return htmltemplate.HTML(b.String()), nil
+	}
+}
+
+// childrenFunc returns the function that renders the child content defined
+// by identifier, or nil if identifier is empty since components without child
+// content have no define to execute.
+func (t *Template) childrenFunc(tmpl executor, targets *TargetWriter, text *TextWriter, identifier string, data any) func() (htmltemplate.HTML, error) {
+	if identifier == "" {
+		return nil
+	}
+
+	return func() (htmltemplate.HTML, error) {
+		var b strings.Builder
+		b.Grow(t.childrenSize.hint())
+
+		var w io.Writer = &b
+		if targets != nil {
+			defer targets.capture(&b)()
+			w = targets
+		}
+
+		err := tmpl.ExecuteTemplate(w, identifier, data)
+		if err != nil {
+			return "", err
+		}
+		t.childrenSize.record(b.Len())
+
+		if t.options.CheckBalance && targets == nil && text == nil {
+			if tag, closing, ok := checkBalance(b.String()); !ok {
+				return "", &UnbalancedError{Component: t.Name, Tag: tag, Closing: closing, ChildContent: true}
+			}
+		}
+
+		return htmltemplate.HTML(b.String()), nil
+	}
+}
+
+// childComponent is a child component collected into a field tagged with
+// `glam:"children-components=Name"`, which is instantiated along with the
+// component instead of being rendered.
+type childComponent struct {
+	attributes map[string]any
+	children   func() (htmltemplate.HTML, error)
+}
+
+// generateChildComponentFunc returns the function used to collect child
+// components, whose child content is executed using the given template.
+func (t *Template) generateChildComponentFunc(tmpl executor, targets *TargetWriter, text *TextWriter) func(string, map[string]any, any) *childComponent {
+	return func(identifier string, attributes map[string]any, existingData any) *childComponent {
+		return &childComponent{
+			attributes: withoutOmitted(attributes),
+			children:   t.childrenFunc(tmpl, targets, text, identifier, existingData),
+		}
+	}
+}
+
+// ChildComponentsTag returns the tag name of the child components collected
+// into the given field, if it's tagged with `glam:"children-components=Name"`.
+func ChildComponentsTag(field reflect.StructField) (string, bool) {
+	return strings.CutPrefix(field.Tag.Get("glam"), "children-components=")
+}
+
+// IsChildComponentsType reports whether child components can be collected
+// into a field of the given type, which must be a slice of structs or
+// pointers to structs.
+func IsChildComponentsType(fieldType reflect.Type) bool {
+	if fieldType.Kind() != reflect.Slice {
+		return false
+	}
+
+	elem := fieldType.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+
+	return elem.Kind() == reflect.Struct
+}
+
+// childComponentFields returns the component's fields that collect child
+// components, keyed by the child components' tag name.
+func childComponentFields(componentType reflect.Type) map[string]reflect.StructField {
+	if componentType.Kind() == reflect.Ptr {
+		componentType = componentType.Elem()
+	}
+
+	if componentType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields map[string]reflect.StructField
+	for i := 0; i < componentType.NumField(); i++ {
+		field := componentType.Field(i)
+		if tagName, ok := ChildComponentsTag(field); ok && IsChildComponentsType(field.Type) {
+			if fields == nil {
+				fields = make(map[string]reflect.StructField)
+			}
+			fields[tagName] = field
+		}
+	}
+
+	return fields
+}
+
+// withChildComponents returns the components that can be referenced in the
+// given component's child content, which includes the child components it
+// collects, like `<Tab>`, even if they aren't registered.
+func withChildComponents(components map[string]reflect.Type, componentType reflect.Type) map[string]reflect.Type {
+	fields := childComponentFields(componentType)
+	if len(fields) == 0 {
+		return components
+	}
+
+	withChildren := make(map[string]reflect.Type, len(components)+len(fields))
+	for name, componentType := range components {
+		withChildren[name] = componentType
+	}
+	for tagName, field := range fields {
+		withChildren[tagName] = field.Type.Elem()
+	}
+
+	return withChildren
+}
+
+// Instantiate creates a new instance of the given component type and assigns
+// the attributes to its fields. If the component has a Children field and
+// children is non-nil, it's called to render the child content. Strings are
+// only converted when assigned to fields typed as one of html/template's safe
+// strings if lenientStrings is set.
+func Instantiate(componentType reflect.Type, attributes map[string]any, children func() (htmltemplate.HTML, error), lenientStrings bool) (any, error) {
+	return instantiate(componentType, attributes, children, nil, lenientStrings)
+}
+
+// InstantiateFrom assigns the attributes to the fields of instance like
+// Instantiate, instead of creating a new instance. instance must be of the
+// given component type, or nil to create a new instance.
+func InstantiateFrom(componentType reflect.Type, instance any, attributes map[string]any, children func() (htmltemplate.HTML, error)) (any, error) {
+	return instantiateFrom(componentType, instance, attributes, children, nil, false)
+}
+
+// unassignable reports an attribute that can't be assigned to its field,
+// returning true if it should be skipped instead of failing the render.
+func (t *Template) unassignable(err *AssignmentError) bool {
+	err.Template = t.Name
+	err.Filename = t.options.Filename
+
+	if !t.options.SkipUnassignable {
+		return false
+	}
+
+	if reporter, ok := t.renderer.(unassignableReporter); ok {
+		reporter.ReportUnassignable(err)
+	}
+
+	return true
+}
+
+// instantiate creates the component like Instantiate. Attributes that can't
+// be assigned to their field are passed to unassignable, if it's non-nil,
+// and skipped if it returns true. Otherwise, an *AssignmentError is
+// returned. Strings are only converted when assigned to fields typed as one
+// of html/template's safe strings, like template.HTML, if lenientStrings is
+// set, since literal attribute text is converted when the template is
+// compiled.
+func instantiate(componentType reflect.Type, attributes map[string]any, children func() (htmltemplate.HTML, error), unassignable func(*AssignmentError) bool, lenientStrings bool) (any, error) {
+	return instantiateFrom(componentType, nil, attributes, children, unassignable, lenientStrings)
+}
+
+// instantiateFrom creates the component like instantiate, assigning the
+// attributes to instance instead of a new instance if it's non-nil.
+func instantiateFrom(componentType reflect.Type, instance any, attributes map[string]any, children func() (htmltemplate.HTML, error), unassignable func(*AssignmentError) bool, lenientStrings bool) (any, error) {
+	expected := componentType
+
+	// Get the type of the component, and if it's a pointer, get the underlying type
+	// so we can create a new instance of it
+	isPointer := componentType.Kind() == reflect.Ptr
+	if isPointer {
+		componentType = componentType.Elem()
+	}
+
+	// Create a new instance of the component
+	toRender := reflect.New(componentType)
+	if instance != nil {
+		v := reflect.ValueOf(instance)
+		switch {
+		case isPointer && v.Type() == toRender.Type() && !v.IsNil():
+			toRender = v
+		case !isPointer && v.Type() == componentType:
+			toRender.Elem().Set(v)
+		default:
+			return nil, fmt.Errorf("component %s must be constructed as a non-nil %s, got %T", componentType.Name(), expected, instance)
+		}
+	}
+	toCallRenderOn := toRender
+	if isPointer {
+		toRender = toRender.Elem()
+	} else {
+		toCallRenderOn = toRender.Elem()
+		toRender = toCallRenderOn
+	}
+
+	// Track which attributes were assigned to fields so the rest can be
+	// forwarded to the field tagged with `glam:"attrs"`, if any
+	matched := make(map[string]bool, len(attributes))
+	attrsField := -1
+
+	// Loop through the attributes and set them on the component
+	for i := 0; i < componentType.NumField(); i++ {
+		fieldType := componentType.Field(i)
+		field := toRender.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+
+		if fieldType.Tag.Get("glam") == "attrs" {
+			attrsField = i
+			continue
+		}
+
+		key := attributeKey(attributes, fieldType)
+		matched[key] = true
+
+		// Collected child components are instantiated using the slice's
+		// element type. Fields that are passed a slice are assigned as-is.
+		if collected, ok := attributes[key].([]*childComponent); ok {
+			slice, err := instantiateChildComponents(field.Type(), collected, unassignable, lenientStrings)
+			if err != nil {
+				return nil, fmt.Errorf("could not instantiate %s: %w", fieldType.Name, err)
+			}
+			field.Set(slice)
+			continue
+		}
+
+		if fieldType.Name == "Children" {
+			if children == nil {
 				continue
 			}
 
-			expectedName := strings.ToLower(fieldType.Name)
-			if name := fieldType.Tag.Get("attr"); name != "" {
-				expectedName = name
+			content, err := children()
+			if err != nil {
+				return nil, err
 			}
+			field.Set(reflect.ValueOf(content))
+			continue
+		}
 
-			if value, ok := attributes[expectedName]; ok {
-				field.Set(reflect.ValueOf(value))
+		if value, ok := attributes[key]; ok {
+			// nil values, e.g. from a nil field, leave the field unset
+			v := reflect.ValueOf(value)
+			if !v.IsValid() {
 				continue
 			}
+
+			// Strings from expressions can only populate fields like
+			// template.HTML when they're allowed, since they aren't
+			// escaped
+			if lenientStrings && v.Type() == stringType && typedStrings[field.Type()] {
+				v = v.Convert(field.Type())
+			}
+
+			if !v.Type().AssignableTo(field.Type()) {
+				err := &AssignmentError{Component: componentType.Name(), Field: fieldType, Attribute: key, ValueType: v.Type()}
+				if unassignable != nil && unassignable(err) {
+					continue
+				}
+
+				return nil, err
+			}
+
+			field.Set(v)
+			continue
 		}
+	}
 
-		var b bytes.Buffer
-		err := t.renderer.Render(&b, toCallRenderOn.Interface())
+	if attrsField != -1 {
+		attrs, err := forwardedAttributes(attributes, matched)
+		if err != nil {
+			return nil, err
+		}
+
+		field := toRender.Field(attrsField)
+		if field.Kind() != reflect.String {
+			return nil, fmt.Errorf("field %s tagged with glam:\"attrs\" must be a template.HTMLAttr", componentType.Field(attrsField).Name)
+		}
+
+		field.Set(reflect.ValueOf(attrs).Convert(field.Type()))
+	}
+
+	return toCallRenderOn.Interface(), nil
+}
+
+// instantiateChildComponents returns a slice of the given type containing an
+// instance of the slice's element type for each of the child components.
+func instantiateChildComponents(sliceType reflect.Type, children []*childComponent, unassignable func(*AssignmentError) bool, lenientStrings bool) (reflect.Value, error) {
+	slice := reflect.MakeSlice(sliceType, 0, len(children))
+	for _, child := range children {
+		value, err := instantiate(sliceType.Elem(), child.attributes, child.children, unassignable, lenientStrings)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		slice = reflect.Append(slice, reflect.ValueOf(value))
+	}
+
+	return slice, nil
+}
+
+// stringType is the type of string values, which typedStrings can be
+// converted from
+var stringType = reflect.TypeOf("")
+
+// typedLiteral converts literal attribute text passed to the given component
+// to the type of the field it populates, if the field is typed as one of
+// html/template's safe strings. Since the text is part of the template, it's
+// trusted like the rest of the template's markup.
+func (t *Template) typedLiteral(component string, attribute string, value string) (any, bool) {
+	componentType, ok := t.renderer.KnownComponents()[component]
+	if !ok {
+		return nil, false
+	}
+
+	if componentType == FuncComponentType {
+		props, ok := t.renderer.(funcComponentProps)
+		if !ok {
+			return nil, false
+		}
+
+		componentType = props.FuncComponentProps(component)
+		if componentType == nil {
+			return nil, false
+		}
+	}
+
+	field, ok := attributeField(derefType(componentType), t.attributeMapping(component), attribute)
+	if !ok || !typedStrings[field.Type] {
+		return nil, false
+	}
+
+	return reflect.ValueOf(value).Convert(field.Type).Interface(), true
+}
+
+// typedStrings are html/template's types for strings that are safe to render
+// in a specific context
+var typedStrings = map[reflect.Type]bool{
+	reflect.TypeOf(htmltemplate.CSS("")):      true,
+	reflect.TypeOf(htmltemplate.HTML("")):     true,
+	reflect.TypeOf(htmltemplate.HTMLAttr("")): true,
+	reflect.TypeOf(htmltemplate.JS("")):       true,
+	reflect.TypeOf(htmltemplate.JSStr("")):    true,
+	reflect.TypeOf(htmltemplate.Srcset("")):   true,
+	reflect.TypeOf(htmltemplate.URL("")):      true,
+}
+
+// omittedAttribute is the value of an optional attribute, like
+// `alt?="{{.Alt}}"`, whose value is empty. It's removed from the attributes
+// before the component is instantiated.
+type omittedAttribute struct{}
+
+// optionalAttribute returns the value of an optional attribute, or
+// omittedAttribute if the value is empty, following the same rules as `if`.
+func optionalAttribute(value any) any {
+	if truth, _ := htmltemplate.IsTrue(value); !truth {
+		return omittedAttribute{}
+	}
+
+	return value
+}
+
+// withoutOmitted returns the attributes without the optional attributes that
+// were omitted. The attributes are only copied if an attribute was omitted.
+func withoutOmitted(attributes map[string]any) map[string]any {
+	for _, value := range attributes {
+		if _, ok := value.(omittedAttribute); !ok {
+			continue
+		}
+
+		filtered := make(map[string]any, len(attributes))
+		for k, v := range attributes {
+			if _, omitted := v.(omittedAttribute); !omitted {
+				filtered[k] = v
+			}
+		}
+
+		return filtered
+	}
+
+	return attributes
+}
+
+// attributeMapping returns the mapping of attributes to fields of the
+// component with the given name, if the renderer has one.
+func (t *Template) attributeMapping(name string) map[string]string {
+	if mapper, ok := t.renderer.(attributeMapper); ok {
+		return mapper.AttributeMapping(name)
+	}
+
+	return nil
+}
+
+// mapAttributes returns the attributes with the attributes in mapping keyed
+// by the name of the field they populate instead. The attributes are only
+// copied if the component has a mapping.
+func mapAttributes(mapping map[string]string, attributes map[string]any) map[string]any {
+	if len(mapping) == 0 {
+		return attributes
+	}
+
+	mapped := make(map[string]any, len(attributes))
+	for name, value := range attributes {
+		if field, ok := mapping[strings.ToLower(name)]; ok {
+			name = field
+		}
+		mapped[name] = value
+	}
+
+	return mapped
+}
+
+// newFuncComponent returns the FuncComponent rendered for the function
+// component with the given name.
+func newFuncComponent(name string, attributes map[string]any, children func() (htmltemplate.HTML, error)) (*FuncComponent, error) {
+	component := &FuncComponent{Name: name, Attributes: attributes}
+	if children != nil {
+		content, err := children()
 		if err != nil {
-			panic(err)
+			return nil, err
+		}
+
+		component.Children = content
+	}
+
+	return component, nil
+}
+
+// componentTagPattern matches capitalized tag names that may refer to
+// components
+var componentTagPattern = regexp.MustCompile(`^\p{Lu}[\p{L}\p{N}_.:-]*$`)
+
+// attributeNamePattern matches attribute names that can be safely forwarded
+var attributeNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_:.-]*$`)
+
+// forwardedAttributes renders the attributes that weren't matched to a field
+// as escaped `name="value"` pairs, sorted by name. Attributes with a value of
+// true are rendered as boolean attributes and false or nil values are omitted.
+// Like html/template, unsafe URLs in URL attributes are replaced with
+// `#ZgotmplZ`, and event handler and style attributes return an error.
+func forwardedAttributes(attributes map[string]any, matched map[string]bool) (htmltemplate.HTMLAttr, error) {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		if !matched[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		if !attributeNamePattern.MatchString(name) {
+			return "", fmt.Errorf("invalid attribute name %q", name)
+		}
+
+		// Forwarded values are only HTML escaped, so attributes whose values
+		// are scripts or styles can't be forwarded
+		local := strings.ToLower(name[strings.LastIndex(name, ":")+1:])
+		if strings.HasPrefix(local, "on") || local == "style" || local == "srcdoc" {
+			return "", fmt.Errorf("attribute %s can't be forwarded since its value would run as a script or style", name)
+		}
+
+		value := attributes[name]
+		if value == nil || value == false {
+			continue
 		}
-		return htmltemplate.HTML(b.String())
+
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+
+		b.WriteString(name)
+		if value == true {
+			continue
+		}
+
+		text := fmt.Sprint(value)
+		if _, trusted := value.(htmltemplate.URL); !trusted && IsURLAttribute(name) {
+			text = FilterURL(text)
+		}
+
+		b.WriteString(`="`)
+		b.WriteString(html.EscapeString(text))
+		b.WriteByte('"')
+	}
+
+	return htmltemplate.HTMLAttr(b.String()), nil
+}
+
+// renderValue renders the given registered component value, allowing
+// templates to render components from data via `{{render .}}`.
+func (t *Template) renderValue(value any, targets *TargetWriter, text *TextWriter) (htmltemplate.HTML, error) {
+	var b bytes.Buffer
+	w := text.wrap(&b)
+	if targets != nil {
+		defer targets.capture(&b)()
+		w = targets
+	}
+
+	err := t.renderer.Render(w, value)
+	if err != nil {
+		return "", fmt.Errorf("could not render %T in component %s: %w", value, t.Name, err)
+	}
+
+	return htmltemplate.HTML(b.String()), nil
+}
+
+// AttributeName returns the attribute name used to populate the given struct
+// field. It's the value of the `attr` tag if present, otherwise the lowercased
+// field name.
+func AttributeName(field reflect.StructField) string {
+	if name := field.Tag.Get("attr"); name != "" {
+		return name
+	}
+
+	return strings.ToLower(field.Name)
+}
+
+// attributeKey returns the key of the attribute in attributes that populates
+// the given field. The field's attribute name is matched exactly first, then
+// the field's name, then either of them case-insensitively, so `name`,
+// `Name`, and `NAME` all populate a Name field. Since templates lowercase
+// attribute names, `attr:"userName"` is populated by `username`.
+func attributeKey(attributes map[string]any, field reflect.StructField) string {
+	name := AttributeName(field)
+	if _, ok := attributes[name]; ok {
+		return name
+	}
+
+	if _, ok := attributes[field.Name]; ok {
+		return field.Name
+	}
+
+	// Pick the first match in sorted order, so attributes that only differ
+	// in case are assigned consistently
+	key := ""
+	for k := range attributes {
+		if (strings.EqualFold(k, name) || strings.EqualFold(k, field.Name)) && (key == "" || k < key) {
+			key = k
+		}
+	}
+	if key != "" {
+		return key
 	}
 
+	return name
 }
@@ -2,11 +2,14 @@ package template
 
 import (
 	"bytes"
+	"fmt"
 	htmltemplate "html/template"
 	"io"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -182,9 +185,833 @@ func TestTextOnlyTemplate(t *testing.T) {
 	require.Equal(t, "Hello world!", b.String())
 }
 
+func TestCapitalizedHTMLTags(t *testing.T) {
+	testCases := map[string][]string{
+		`<DIV class="x"><SPAN>Hi</SPAN></DIV>`:              nil,
+		`<p>a<BR/>b<IMG src="a.png" />c</p>`:                nil,
+		`<Unknown label="x" />b<p>Hi</p>`:                   {"Unknown"},
+		`<DIV class="x"><Unknown>Hi</Unknown></DIV><SPAN/>`: {"Unknown"},
+	}
+
+	for template, references := range testCases {
+		t.Run(template, func(t *testing.T) {
+			tmpl, err := New("testing", NewFakeRenderer(), template)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = tmpl.Execute(&b, nil, nil)
+			require.NoError(t, err)
+			require.Equal(t, template, b.String())
+
+			names := make([]string, 0)
+			for name := range tmpl.ComponentsPotentiallyReferenced() {
+				names = append(names, name)
+			}
+			require.ElementsMatch(t, references, names)
+		})
+	}
+}
+
+func TestForeignContent(t *testing.T) {
+	chart := `<figure><svg viewBox="0 0 100 50" preserveAspectRatio="xMidYMid meet" xmlns="http://www.w3.org/2000/svg">` +
+		`<defs><linearGradient id="fill" gradientTransform="rotate(90)"><stop offset="0" stop-color="#f00"/><stop offset="1" stop-color="#00f" /></linearGradient>` +
+		`<clipPath id="bounds"><rect width="100" height="50"/></clipPath></defs>` +
+		`<Path d="M0 50 L100 0"/><Text>Label</Text>` +
+		`<use href="#bounds"/><polyline points="0,50 50,25 100,0" fill="url(#fill)" clipPathUnits="userSpaceOnUse"/>` +
+		`<foreignObject width="10" height="10"><div>Hi</div></foreignObject>` +
+		`<svg viewBox="0 0 1 1"><Path d="M0 0"/></svg><textPath startOffset="10">Along</textPath>` +
+		`</svg><math><mi>x</mi><mspace width="1em"/><Mi>y</Mi></math></figure>`
+
+	renderer := NewFakeRenderer()
+	renderer.knownComponents["Path"] = reflect.TypeOf(&EmptyComponent{})
+	renderer.knownComponents["Text"] = reflect.TypeOf(&EmptyComponent{})
+	renderer.knownComponents["Mi"] = reflect.TypeOf(&EmptyComponent{})
+
+	tmpl, err := New("testing", renderer, chart+`<Path d="M0 0"/>`)
+	require.NoError(t, err)
+	require.Empty(t, tmpl.PotentialReferences())
+
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, chart+"<!-- placeholder for EmptyComponent -->", b.String())
+}
+
+func TestForeignContent_ClosedByComponent(t *testing.T) {
+	renderer := NewFakeRenderer()
+	renderer.knownComponents["Wrapper"] = reflect.TypeOf(&EmptyComponent{})
+
+	tmpl, err := New("testing", renderer, `<Wrapper><svg><g></Wrapper><Wrapper>Hi</Wrapper>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "<!-- placeholder for EmptyComponent --><!-- placeholder for EmptyComponent -->", b.String())
+}
+
 // There was an infinite loop while parsing this template. Lets fix it
 func TestLoneLeftCurly(t *testing.T) {
 	renderer := &FakeRenderer{}
 	_, err := New("main.glam.html", renderer, `<h1 foo="{oops}">Hi</h1>`)
 	require.NoError(t, err)
 }
+
+func TestOrphanAttributeEquals(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		err      string
+	}{
+		{
+			desc:     "unquoted value without a name",
+			template: `<div =foo>Hi</div>`,
+			err:      "1:6: unexpected '=' without an attribute name",
+		},
+		{
+			desc:     "quoted value without a name",
+			template: `<div ="bar">Hi</div>`,
+			err:      "1:6: unexpected '=' without an attribute name",
+		},
+		{
+			desc:     "value without a name after other attributes",
+			template: "<p>\n  <div class=\"a\" =\"bar\">Hi</div>",
+			err:      "2:18: unexpected '=' without an attribute name",
+		},
+		{
+			desc:     "component tag",
+			template: `<Test ="bar"></Test>`,
+			err:      "1:7: unexpected '=' without an attribute name",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			renderer := NewFakeRenderer()
+			renderer.knownComponents["Test"] = reflect.TypeOf(&EmptyComponent{})
+
+			_, err := New("testing", renderer, tC.template)
+			require.ErrorContains(t, err, tC.err)
+		})
+	}
+}
+
+func TestComponentInAttributeValue(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		err      string
+	}{
+		{
+			desc:     "self-closing component",
+			template: `<Outer label="<Inner/>"></Outer>`,
+			err:      "1:15: component Inner can't be used in an attribute value, pass it as child content or render it in the component's template instead",
+		},
+		{
+			desc:     "component with content in an HTML tag",
+			template: "<div\n  title='<Inner>Hi</Inner>'>Hi</div>",
+			err:      "2:10: component Inner can't be used in an attribute value",
+		},
+		{
+			desc:     "end tag",
+			template: `<Outer label="Hi</Inner>"></Outer>`,
+			err:      "1:17: component Inner can't be used in an attribute value",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			renderer := NewFakeRenderer()
+			renderer.knownComponents["Outer"] = reflect.TypeOf(&EmptyComponent{})
+			renderer.knownComponents["Inner"] = reflect.TypeOf(&EmptyComponent{})
+
+			_, err := New("testing", renderer, tC.template)
+			require.ErrorContains(t, err, tC.err)
+		})
+	}
+}
+
+func TestTextInAttributeValue(t *testing.T) {
+	renderer := NewFakeRenderer()
+	tmpl, err := New("testing", renderer, `<p title="1 < 2, <b>bold</b>, {{ "<Inner/>" }}">Hi</p>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, `<p title="1 < 2, <b>bold</b>, &lt;Inner/&gt;">Hi</p>`, b.String())
+}
+
+// nestedBoxes generates `count` sibling Box components with children, nested
+// `depth` levels deep.
+func nestedBoxes(prefix string, count, depth int) string {
+	if depth == 0 {
+		return prefix
+	}
+
+	var b strings.Builder
+	for i := 0; i < count; i++ {
+		label := fmt.Sprintf("%s-%d", prefix, i)
+		fmt.Fprintf(&b, `<Box label="%s">%s</Box>`, label, nestedBoxes(label, count, depth-1))
+	}
+
+	return b.String()
+}
+
+func TestCompileManyDefines(t *testing.T) {
+	renderer := NewFakeRenderer()
+	renderer.knownComponents["Box"] = reflect.TypeOf(&EmptyComponent{})
+
+	content := nestedBoxes("box", 5, 3)
+
+	tmpl, err := New("testing", renderer, content)
+	require.NoError(t, err)
+
+	// One define per component with children, plus the template itself
+	names := make(map[string]bool)
+	for _, defined := range tmpl.executor.(htmlExecutor).Templates() {
+		require.False(t, names[defined.Name()], "duplicate define %s", defined.Name())
+		names[defined.Name()] = true
+	}
+	require.Len(t, names, 5+25+125+1)
+
+	// Compiling the same template again produces identical identifiers
+	other, err := New("testing", renderer, content)
+	require.NoError(t, err)
+	for _, defined := range other.executor.(htmlExecutor).Templates() {
+		require.True(t, names[defined.Name()], "unexpected define %s", defined.Name())
+	}
+}
+
+func TestEmptyTemplate(t *testing.T) {
+	for _, content := range []string{"", "   ", "\n\t\n"} {
+		tmpl, err := New("testing", NewFakeRenderer(), content)
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		err = tmpl.Execute(&b, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, content, b.String())
+	}
+}
+
+func TestInvalidComponentTagName(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		err      string
+	}{
+		{
+			desc:     "quote in tag name",
+			template: `<Test"}}{{.Secret}}></Test>`,
+			err:      `1:2: invalid tag name "Test\"}}{{.Secret}}"`,
+		},
+		{
+			desc:     "braces in tag name",
+			template: "<p>\n<Test{{x}} />",
+			err:      `2:2: invalid tag name "Test{{x}}"`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			renderer := NewFakeRenderer()
+			renderer.knownComponents["Test"] = reflect.TypeOf(&EmptyComponent{})
+
+			_, err := New("testing", renderer, tC.template)
+			require.ErrorContains(t, err, tC.err)
+		})
+	}
+}
+
+func TestMalformedTagErrors(t *testing.T) {
+	renderer := NewFakeRenderer()
+	renderer.knownComponents["Test"] = reflect.TypeOf(&EmptyComponent{})
+
+	_, err := New("testing", renderer, "<Test>Hi")
+	require.ErrorContains(t, err, "1:9: unclosed component tag Test")
+
+	_, err = New("testing", renderer, "<a/0")
+	require.ErrorContains(t, err, `1:4: unexpected character '0' when parsing tag`)
+}
+
+func TestComponentTagNameFollowedByNewline(t *testing.T) {
+	renderer := NewFakeRenderer()
+	renderer.knownComponents["Test"] = reflect.TypeOf(&EmptyComponent{})
+
+	tmpl, err := New("testing", renderer, "<Test\n  class=\"a\" />")
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "<!-- placeholder for EmptyComponent -->", b.String())
+}
+
+func TestLiteralLessThan(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		expected string
+	}{
+		{desc: "trailing", template: "text <", expected: "text &lt;"},
+		{desc: "space separated", template: "a < b", expected: "a &lt; b"},
+		{desc: "not followed by a letter", template: "1<2 <= 3", expected: "1&lt;2 &lt;= 3"},
+		{desc: "followed by a tag", template: "a <<div>b</div>", expected: "a &lt;<div>b</div>"},
+		{desc: "trailing in a tag", template: "<div>1 <</div>", expected: "<div>1 &lt;</div>"},
+		{desc: "in children", template: "<Test>a < b</Test>", expected: "<!-- placeholder for EmptyComponent -->"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			renderer := NewFakeRenderer()
+			renderer.knownComponents["Test"] = reflect.TypeOf(&EmptyComponent{})
+
+			tmpl, err := New("testing", renderer, tc.template)
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			err = tmpl.Execute(&b, nil, nil)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, b.String())
+		})
+	}
+
+	renderer := NewFakeRenderer()
+	renderer.knownComponents["Test"] = reflect.TypeOf(&EmptyComponent{})
+
+	_, err := New("testing", renderer, "<Test>a <")
+	require.ErrorContains(t, err, "unclosed component tag Test")
+}
+
+func TestParseUnterminatedTag(t *testing.T) {
+	testCases := []struct {
+		template string
+		err      string
+	}{
+		{template: "<div   ", err: "1:8: unexpected end of template in tag"},
+		{template: "<div class", err: "1:11: unexpected end of template in tag"},
+		{template: "<div class=", err: "1:12: unexpected end of template in tag"},
+		{template: `<div class="a`, err: "1:12: unterminated attribute value"},
+		{template: `<div class="{{.A}}`, err: "1:12: unterminated attribute value"},
+		{template: "<div /", err: "1:7: unexpected end of template in tag"},
+		{template: "</div", err: "1:6: unexpected end of template in tag"},
+		{template: "<Test  ", err: "1:8: unexpected end of template in tag"},
+		{template: "<Test /  ", err: "found invalid HTML"},
+		{template: "<Test></Te", err: "1:11: unexpected end of template in tag"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			renderer := NewFakeRenderer()
+			renderer.knownComponents["Test"] = reflect.TypeOf(&EmptyComponent{})
+
+			_, err := New("testing", renderer, tc.template)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+// FuzzParseCompile ensures that templates without Go template syntax of their
+// own either fail to parse with a glam error or compile into a template that
+// html/template can parse.
+func FuzzParseCompile(f *testing.F) {
+	seeds := []string{
+		`<Test />`,
+		`<Test a="1" b="{{.B}}">Hi</Test>`,
+		`<Test a='say "hi"'><Test /></Test>`,
+		`<Test"x></Test>`,
+		`<Test:x>Hi</Test:x>`,
+		`<div class="a">Hi</div>`,
+		`<Unknown>Hi</Unknown>`,
+		`a < b <`,
+		`{<`,
+		`<div   `,
+		`<Test a="1`,
+		`{<div>`,
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, content string) {
+		if strings.Contains(content, "{{") || strings.Contains(content, "}}") {
+			t.Skip("content contains Go template syntax")
+		}
+
+		renderer := NewFakeRenderer()
+		renderer.knownComponents["Test"] = reflect.TypeOf(&EmptyComponent{})
+
+		_, err := New("testing", renderer, content)
+		if err != nil {
+			require.NotContains(t, err.Error(), "error parsing template")
+		}
+	})
+}
+
+func TestParseAttributes(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		expected map[string]string
+	}{
+		{
+			desc:     "value followed by >",
+			template: `<Test a="1"></Test>`,
+			expected: map[string]string{"a": "1"},
+		},
+		{
+			desc:     "values without separating whitespace",
+			template: `<Test a="1"b="2"c='3'></Test>`,
+			expected: map[string]string{"a": "1", "b": "2", "c": "3"},
+		},
+		{
+			desc:     "packed values when self closing",
+			template: `<Test a="1"b="2"/>`,
+			expected: map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			desc:     "boolean attribute followed by >",
+			template: `<Test a="1" disabled></Test>`,
+			expected: map[string]string{"a": "1", "disabled": "true"},
+		},
+		{
+			desc:     "boolean attribute followed by />",
+			template: `<Test disabled/>`,
+			expected: map[string]string{"disabled": "true"},
+		},
+		{
+			desc:     "boolean attribute followed by a newline",
+			template: "<Test disabled\n\ta=\"1\" />",
+			expected: map[string]string{"a": "1", "disabled": "true"},
+		},
+		{
+			desc:     "raw string containing quotes",
+			template: "<Test a=\"{{ printf `\"%s\"` .X }}\" b='{{ printf `'%s'` .X }}' />",
+			expected: map[string]string{"a": "{{ printf `\"%s\"` .X }}", "b": "{{ printf `'%s'` .X }}"},
+		},
+		{
+			desc:     "raw string containing braces",
+			template: "<Test a=\"{{ printf `}}%s{{` .X }}\" b=\"2\"></Test>",
+			expected: map[string]string{"a": "{{ printf `}}%s{{` .X }}", "b": "2"},
+		},
+		{
+			desc:     "interpreted string containing escaped quotes and braces",
+			template: `<Test a="{{ printf "\"}}%s" .X }}" b="2" />`,
+			expected: map[string]string{"a": `{{ printf "\"}}%s" .X }}`, "b": "2"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			components := map[string]reflect.Type{"Test": reflect.TypeOf(&EmptyComponent{})}
+			tmpl := &Template{potentiallyReferencedComponents: make(map[string]bool)}
+
+			nodes, err := tmpl.parseRoot([]rune(tC.template), components)
+			require.NoError(t, err)
+			require.Len(t, nodes, 1)
+			require.True(t, nodes[0].Type == NodeTypeComponent)
+			require.Equal(t, tC.expected, nodes[0].Attributes)
+		})
+	}
+}
+
+func TestParseRawTagAttributes(t *testing.T) {
+	for _, content := range []string{`<dialog open>Hi</dialog>`, `<input a="1"b="2">`, `<input disabled/>`} {
+		tmpl, err := New("testing", NewFakeRenderer(), content)
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		err = tmpl.Execute(&b, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, content, b.String())
+	}
+}
+
+func TestActionPipeline(t *testing.T) {
+	testCases := map[string]struct {
+		pipeline string
+		ok       bool
+	}{
+		"{{ .Name }}":                {".Name", true},
+		"{{- .Name -}}":              {".Name", true},
+		"{{ printf `}}%s` .Name }}":  {"printf `}}%s` .Name", true},
+		`{{ printf "}}%s" .Name }}`:  {`printf "}}%s" .Name`, true},
+		"{{ .A }}{{ .B }}":           {"", false},
+		"a {{ .B }}":                 {"", false},
+		"{{ printf `}}` }} {{ .B }}": {"", false},
+		"{{ }}":                      {"", false},
+	}
+
+	for value, expected := range testCases {
+		t.Run(value, func(t *testing.T) {
+			pipeline, ok := actionPipeline(value)
+			require.Equal(t, expected.ok, ok)
+			require.Equal(t, expected.pipeline, pipeline)
+		})
+	}
+}
+
+func TestWithoutComments(t *testing.T) {
+	testCases := map[string]string{
+		"{{/* TODO */}}real":              "real",
+		"{{/* TODO */}}{{ .Name }}":       "{{ .Name }}",
+		`{{/* it's "}}" */}}real`:         "real",
+		"a {{- /* TODO */ -}} b":          "ab",
+		"a {{/* TODO */}} b":              "a  b",
+		"{{ .A }}{{/* TODO */}}{{ .B }}":  "{{ .A }}{{ .B }}",
+		"{{ printf `/*` }}{{/* TODO */}}": "{{ printf `/*` }}",
+		"{{/* unclosed":                   "{{/* unclosed",
+		"no comments":                     "no comments",
+	}
+
+	for value, expected := range testCases {
+		t.Run(value, func(t *testing.T) {
+			require.Equal(t, expected, withoutComments(value))
+		})
+	}
+}
+
+func TestCommentsInAttributeValue(t *testing.T) {
+	renderer := NewFakeRenderer()
+	tmpl, err := New("testing", renderer, `<p title="{{/* it's "}}" */}}real {{.}}" class="{{- /* TODO */ -}} a">Hi</p>`)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, "Fox", nil)
+	require.NoError(t, err)
+	require.Equal(t, `<p title="real Fox" class="a">Hi</p>`, b.String())
+}
+
+func TestCompileStaticAttributes(t *testing.T) {
+	components := map[string]reflect.Type{"Test": reflect.TypeOf(&EmptyComponent{})}
+	tmpl := &Template{potentiallyReferencedComponents: make(map[string]bool)}
+
+	nodes, err := tmpl.parseRoot([]rune(`<Test a="1" b="2" /><Test a="{{.A}}" b="2" /><Test a="3">Hi</Test>`), components)
+	require.NoError(t, err)
+
+	content, static := compile(nodes)
+	require.Contains(t, content, `{{__glamRenderComponent "Test" "" (__glamStaticAttributes 0) .}}`)
+	require.Contains(t, content, `{{__glamRenderComponent "Test" "" (__glamDict "a" (.A) "b" "2") .}}`)
+	require.Contains(t, content, `{{__glamRenderComponent "Test" "glam__Test__1" (__glamStaticAttributes 1) .}}`)
+	require.Equal(t, []map[string]any{{"a": "1", "b": "2"}, {"a": "3"}}, static)
+}
+
+func TestCompileConstantAttributes(t *testing.T) {
+	renderer := NewFakeRenderer()
+	renderer.funcMap["upper"] = strings.ToUpper
+	renderer.funcMap["token"] = func() string { return "secret" }
+	renderer.funcMap["len"] = func(s string) int { return 0 }
+
+	components := map[string]reflect.Type{"Test": reflect.TypeOf(&EmptyComponent{})}
+	tmpl := &Template{
+		renderer:                        renderer,
+		options:                         Options{PureFuncs: map[string]bool{"upper": true}},
+		potentiallyReferencedComponents: make(map[string]bool),
+	}
+
+	nodes, err := tmpl.parseRoot([]rune(
+		`<Test a="{{32}}" b="{{ printf "%d items" 5 | upper }}" c="{{1.0}}" />`+
+			`<Test a="{{.A}}" b="{{ upper "x" }}" c="{{ (eq 1 1) }}" d="{{2.5}}" />`+
+			`<Test a="{{ token }}" b="{{ upper .B }}" c="{{ len "abc" }}" d="{{ call .F }}" e="{{ $x := 1 }}" />`,
+	), components)
+	require.NoError(t, err)
+
+	c := &compiler{constant: tmpl.constantValue}
+	content, static := c.compile(nodes)
+	require.Contains(t, content, `{{__glamRenderComponent "Test" "" (__glamStaticAttributes 0) .}}`)
+	require.Equal(t, []map[string]any{{"a": 32, "b": "5 ITEMS", "c": 1.0}}, static)
+
+	require.Contains(t, content, `(__glamDict "a" (.A) "b" "X" "c" true "d" 2.5)`)
+
+	// Impure funcs, replaced builtins, data, and variables are never folded
+	require.Contains(t, content, `(__glamDict "a" (token) "b" (upper .B) "c" (len "abc") "d" (call .F) "e" ($x := 1))`)
+}
+
+func TestCompileConstantAttributes_Policy(t *testing.T) {
+	calls := 0
+	renderer := NewFakeRenderer()
+	renderer.funcMap["upper"] = strings.ToUpper
+	renderer.funcMap["count"] = func() int { calls++; return calls }
+
+	tmpl := &Template{
+		renderer: renderer,
+		options: Options{
+			PureFuncs: map[string]bool{"upper": true, "count": true},
+			Policy:    &Policy{Funcs: []string{"upper"}},
+		},
+	}
+
+	// Pure funcs outside of the policy aren't called while compiling
+	value, ok := tmpl.constantValue(`upper "x"`)
+	require.True(t, ok)
+	require.Equal(t, "X", value)
+
+	_, ok = tmpl.constantValue(`count`)
+	require.False(t, ok)
+	require.Equal(t, 0, calls)
+}
+
+type TabProps struct {
+	Title    string
+	Children htmltemplate.HTML
+}
+
+type TabsComponent struct {
+	Tabs []TabProps `glam:"children-components=Tab"`
+}
+
+func TestCompileChildComponents(t *testing.T) {
+	components := map[string]reflect.Type{"Tabs": reflect.TypeOf(&TabsComponent{})}
+	tmpl := &Template{potentiallyReferencedComponents: make(map[string]bool)}
+
+	nodes, err := tmpl.parseRoot([]rune(`<Tabs><Tab title="One">First</Tab><Tab title="{{.Two}}" /><Tab title="Three" /> </Tabs>`), components)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	require.Len(t, nodes[0].ChildComponents["tabs"], 3)
+	require.Equal(t, "One", nodes[0].ChildComponents["tabs"][0].Attributes["title"])
+
+	// Content between the child components remains child content
+	require.Len(t, nodes[0].Children, 1)
+	require.Equal(t, " ", nodes[0].Children[0].Raw)
+
+	content, _ := compile(nodes)
+	require.Contains(t, content, `{{define "glam__Tab__2"}}First{{end}}`)
+	require.Contains(t, content, `{{__glamRenderComponent "Tabs" "glam__Tabs__1" (__glamDict "tabs" (__glamChildComponents `+
+		`(__glamChildComponent "glam__Tab__2" (__glamStaticAttributes 0) .) `+
+		`(__glamChildComponent "" (__glamDict "title" (.Two)) .) `+
+		`(__glamChildComponent "" (__glamStaticAttributes 1) .))) .}}`)
+}
+
+func TestChildComponentErrors(t *testing.T) {
+	components := map[string]reflect.Type{"Tabs": reflect.TypeOf(&TabsComponent{})}
+
+	testCases := map[string]string{
+		`<Tabs tabs="{{.Tabs}}"><Tab title="One" /></Tabs>`:      "1:1: component Tabs can't be passed the tabs attribute since it's populated by its Tab children",
+		`<Tabs><Tab if="{{.Show}}" title="One" /></Tabs>`:        "1:1: child component Tab of Tabs can't use the reserved if or range attributes",
+		`<Tabs><Tab range="{{.Titles}}" title="{{.}}" /></Tabs>`: "1:1: child component Tab of Tabs can't use the reserved if or range attributes",
+	}
+
+	for template, expected := range testCases {
+		t.Run(template, func(t *testing.T) {
+			tmpl := &Template{potentiallyReferencedComponents: make(map[string]bool)}
+			_, err := tmpl.parseRoot([]rune(template), components)
+			require.EqualError(t, err, expected)
+		})
+	}
+}
+
+func TestComponentTagPrefix(t *testing.T) {
+	components := map[string]reflect.Type{"Test": reflect.TypeOf(&EmptyComponent{})}
+	tmpl := &Template{
+		options:                         Options{ComponentTagPrefix: "g:"},
+		potentiallyReferencedComponents: make(map[string]bool),
+	}
+
+	nodes, err := tmpl.parseRoot([]rune(`<g:Test a="1"><Test>raw</Test></g:Test><g:Div></g:Div><g:test />`), components)
+	require.NoError(t, err)
+	require.Equal(t, NodeType(NodeTypeComponent), nodes[0].Type)
+	require.Equal(t, "Test", nodes[0].TagName)
+	require.Equal(t, map[string]string{"a": "1"}, nodes[0].Attributes)
+	for _, child := range nodes[0].Children {
+		require.Equal(t, NodeType(NodeTypeRaw), child.Type)
+	}
+
+	// Prefixed tags are tracked even if they share a name with an HTML tag
+	require.Equal(t, map[string]bool{"Div": true}, tmpl.potentiallyReferencedComponents)
+}
+
+func TestCompileTargets(t *testing.T) {
+	content := compileTargets(`{{target "text"}}{{if .A}}a{{else}}b{{end}}{{/* {{end}} */}}{{"}}"}}{{end}}{{- target "x" -}}{{with .B}}{{.}}{{end}}{{- end }}{{end}}`)
+
+	require.Equal(
+		t,
+		`{{__glamTarget "text"}}{{if .A}}a{{else}}b{{end}}{{/* {{end}} */}}{{"}}"}}{{__glamEndTarget}}{{- __glamTarget "x" -}}{{with .B}}{{.}}{{end}}{{- __glamEndTarget}}{{end}}`,
+		content,
+	)
+}
+
+type CaseComponent struct {
+	Name     string
+	UserName string `attr:"userName"`
+}
+
+func TestInstantiateAttributeCase(t *testing.T) {
+	for _, key := range []string{"name", "Name", "NAME"} {
+		t.Run(key, func(t *testing.T) {
+			component, err := Instantiate(reflect.TypeOf(CaseComponent{}), map[string]any{key: "Fox"}, nil, false)
+			require.NoError(t, err)
+			require.Equal(t, CaseComponent{Name: "Fox"}, component)
+		})
+	}
+
+	// Exact matches take precedence over case-insensitive matches
+	component, err := Instantiate(reflect.TypeOf(CaseComponent{}), map[string]any{"NAME": "Dana", "name": "Fox", "username": "fmulder", "UserName": "dscully"}, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, CaseComponent{Name: "Fox", UserName: "dscully"}, component)
+
+	// Templates lowercase attribute names, so tags are matched regardless of case
+	component, err = Instantiate(reflect.TypeOf(CaseComponent{}), map[string]any{"username": "fmulder"}, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, CaseComponent{UserName: "fmulder"}, component)
+}
+
+func TestWithKey(t *testing.T) {
+	testCases := map[string]htmltemplate.HTML{
+		`<li>One</li>`:       `<li data-glam-key="k&lt;1&gt;">One</li>`,
+		`<img src="a.png"/>`: `<img data-glam-key="k&lt;1&gt;" src="a.png"/>`,
+		`<br/>`:              `<br data-glam-key="k&lt;1&gt;"/>`,
+		"<!-- note -->\n<div\nclass=\"a\"></div>": "<!-- note -->\n<div data-glam-key=\"k&lt;1&gt;\"\nclass=\"a\"></div>",
+		`<!-- <b> --><div>x</div>`:                `<!-- <b> --><div data-glam-key="k&lt;1&gt;">x</div>`,
+		`<!DOCTYPE html><html></html>`:            `<!DOCTYPE html><html data-glam-key="k&lt;1&gt;"></html>`,
+		`</p>Text <em>x</em>`:                     `</p>Text <em data-glam-key="k&lt;1&gt;">x</em>`,
+		`<p title="a > b">x</p>`:                  `<p data-glam-key="k&lt;1&gt;" title="a > b">x</p>`,
+		`<!-- <b> unclosed`:                       `<!-- <b> unclosed`,
+		`1 < 2`:                                   `1 < 2`,
+		`a <b`:                                    `a <b`,
+		``:                                        ``,
+	}
+
+	for content, expected := range testCases {
+		t.Run(string(content), func(t *testing.T) {
+			require.Equal(t, expected, withKey("k<1>", htmltemplate.HTML(content)))
+		})
+	}
+}
+
+func TestSpliceIncludes_Depth(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 0; i < maxIncludeDepth+1; i++ {
+		fsys[fmt.Sprintf("%d.html", i)] = &fstest.MapFile{Data: []byte(fmt.Sprintf(`%d{{include "%d.html"}}`, i, i+1))}
+	}
+	fsys[fmt.Sprintf("%d.html", maxIncludeDepth)] = &fstest.MapFile{Data: []byte("end")}
+
+	content, err := spliceIncludes(fsys, `{{include "1.html"}}`, nil)
+	require.NoError(t, err)
+	require.Equal(t, "1234567end", content)
+
+	_, err = spliceIncludes(fsys, `{{include "0.html"}}`, nil)
+	require.ErrorContains(t, err, "includes can't be nested more than 8 levels deep")
+}
+
+func TestParseTextBeforeNestedComponent(t *testing.T) {
+	components := map[string]reflect.Type{
+		"Wrapper": reflect.TypeOf(&EmptyComponent{}),
+		"Inner":   reflect.TypeOf(&EmptyComponent{}),
+	}
+	tmpl := &Template{potentiallyReferencedComponents: make(map[string]bool)}
+
+	nodes, err := tmpl.parseRoot([]rune(`<Wrapper>X<Inner/></Wrapper>`), components)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	require.Len(t, nodes[0].Children, 2)
+	require.Equal(t, "X", nodes[0].Children[0].Raw)
+	require.Equal(t, "Inner", nodes[0].Children[1].TagName)
+}
+
+func TestParseInlineComponentWhitespace(t *testing.T) {
+	components := map[string]reflect.Type{
+		"Wrapper": reflect.TypeOf(&EmptyComponent{}),
+		"Count":   reflect.TypeOf(&EmptyComponent{}),
+	}
+	tmpl := &Template{potentiallyReferencedComponents: make(map[string]bool)}
+
+	nodes, err := tmpl.parseRoot([]rune(`You have <Count n="{{.N}}"/> messages`), components)
+	require.NoError(t, err)
+	require.Len(t, nodes, 3)
+	require.Equal(t, "You have ", nodes[0].Raw)
+	require.Equal(t, "Count", nodes[1].TagName)
+	require.Equal(t, " messages", nodes[2].Raw)
+
+	tmpl.pos = 0
+	nodes, err = tmpl.parseRoot([]rune(`<Wrapper>You have <Count>new</Count> messages</Wrapper>`), components)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	require.Len(t, nodes[0].Children, 3)
+	require.Equal(t, "You have ", nodes[0].Children[0].Raw)
+	require.Equal(t, "Count", nodes[0].Children[1].TagName)
+	require.Equal(t, " messages", nodes[0].Children[2].Raw)
+}
+
+func TestCheckBalance(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		html    string
+		tag     string
+		closing bool
+	}{
+		{desc: "balanced", html: `<div><p>Hi <b>there</b></p></div>`},
+		{desc: "void elements", html: `<div><br><img src="a.png"><input type="text"></div>`},
+		{desc: "self-closing tags", html: `<svg><path d="M0 0" /><circle/></svg>`},
+		{desc: "self-closing foreign element", html: `<svg><path/></svg><math/>`},
+		{desc: "void elements with a slash", html: `<br/><img src="a.png" />`},
+		{desc: "self-closing HTML element", html: `<div/>`, tag: "div"},
+		{desc: "self-closing HTML element inside an element", html: `<p><span/></p>`, tag: "span"},
+		{desc: "optional end tags", html: `<ul><li>One<li>Two</ul><p>Text`},
+		{desc: "comments and doctypes", html: `<!DOCTYPE html><!-- <div> --><div></div>`},
+		{desc: "quoted attributes", html: `<div title="a > b" data-x='<span>'></div>`},
+		{desc: "raw text elements", html: `<script>if (a < b) { document.write("<div>") }</script><STYLE>a > b {}</style>`},
+		{desc: "case-insensitive", html: `<DIV></div>`},
+		{desc: "foreign content", html: `<svg><title><b>Chart</b></title><style>a {}</style></svg>`},
+		{desc: "unclosed tag in foreign content", html: `<svg><title><b>Chart</title></svg>`, tag: "b"},
+		{desc: "unclosed tag", html: `<div><span>Hi</span>`, tag: "div"},
+		{desc: "closed out of order", html: `<div><span>Hi</div>`, tag: "span"},
+		{desc: "end tag without start tag", html: `<div></div></section>`, tag: "section", closing: true},
+		{desc: "unclosed raw text element", html: `<script>alert(1)`, tag: "script"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			tag, closing, ok := checkBalance(tC.html)
+			require.Equal(t, tC.tag == "", ok)
+			require.Equal(t, tC.tag, tag)
+			require.Equal(t, tC.closing, closing)
+		})
+	}
+}
+
+func TestParserLimits(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		template string
+		limits   ParserLimits
+		err      string
+	}{
+		{
+			desc:     "nested components",
+			template: strings.Repeat("<Test>", 100000) + strings.Repeat("</Test>", 100000),
+			limits:   ParserLimits{MaxDepth: 10},
+			err:      "1:67: components can't be nested more than 10 levels deep",
+		},
+		{
+			desc:     "attributes",
+			template: "<div" + strings.Repeat(` a="b"`, 100000) + "></div>",
+			limits:   ParserLimits{MaxAttributes: 5},
+			err:      "1:36: tag has more than 5 attributes",
+		},
+		{
+			desc:     "boolean attributes",
+			template: "<Test" + strings.Repeat(" a", 100000) + " />",
+			limits:   ParserLimits{MaxAttributes: 5},
+			err:      "1:17: tag has more than 5 attributes",
+		},
+		{
+			desc:     "tags",
+			template: strings.Repeat("<p>a</p>\n", 100000),
+			limits:   ParserLimits{MaxTags: 3},
+			err:      "4:1: template has more than 3 tags",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			renderer := NewFakeRenderer()
+			renderer.knownComponents["Test"] = reflect.TypeOf(&EmptyComponent{})
+
+			_, err := NewWithOptions("testing", renderer, tC.template, Options{Limits: tC.limits})
+			require.ErrorContains(t, err, tC.err)
+		})
+	}
+
+	// Templates within the limits are parsed
+	renderer := NewFakeRenderer()
+	renderer.knownComponents["Test"] = reflect.TypeOf(&EmptyComponent{})
+	_, err := NewWithOptions("testing", renderer, `<Test a="b"><Test><p>Hi</p></Test></Test>`, Options{
+		Limits: ParserLimits{MaxDepth: 2, MaxAttributes: 1, MaxTags: 3},
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,110 @@
+package template
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+// TextWriter is a writer that components are rendered into as text instead of
+// HTML. Templates are executed using text/template, and each value they
+// interpolate is escaped using Escape, if it's non-nil. Literal text in the
+// template and values of type template.HTML, like child content and nested
+// components, are written as-is.
+type TextWriter struct {
+	io.Writer
+	Escape func(string) string
+}
+
+// wrap returns a TextWriter with the same escaper that writes to w, or w if
+// the receiver is nil, so nested components are rendered as text too.
+func (tw *TextWriter) wrap(w io.Writer) io.Writer {
+	if tw == nil {
+		return w
+	}
+
+	return &TextWriter{Writer: w, Escape: tw.Escape}
+}
+
+// escapeValue returns the text for a value interpolated by a template rendered
+// as text, escaped using escape.
+func escapeValue(escape func(string) string) func(any) string {
+	return func(value any) string {
+		if html, ok := value.(htmltemplate.HTML); ok {
+			return string(html)
+		}
+
+		text := fmt.Sprint(value)
+		if escape == nil {
+			return text
+		}
+
+		return escape(text)
+	}
+}
+
+// newTextExecutor returns a text/template executor for the template's parse
+// trees, which pipes the value of each action that writes output through
+// `__glamEscape`.
+func (t *Template) newTextExecutor(tw *TextWriter) (executor, error) {
+	text := texttemplate.New(t.Name)
+	text.Funcs(t.allowedFuncs(t.renderer.FuncMap()))
+	text.Funcs(t.allowedFuncs(t.parseFuncs()))
+	text.Funcs(map[string]any{"__glamEscape": escapeValue(tw.Escape)})
+
+	executor := textExecutor{text}
+	err := applyTemplateOptions(executor, t.options.TemplateOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tree := range t.base.trees() {
+		tree = tree.Copy()
+		escapeActions(tree.Root)
+
+		_, err := text.AddParseTree(tree.Name, tree)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t.bindFuncs(executor, nil, tw)
+
+	return executor, nil
+}
+
+// escapeActions appends `__glamEscape` to the pipeline of each action in node
+// that writes output, like html/template does with its escaping funcs.
+func escapeActions(node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+
+		for _, child := range node.Nodes {
+			escapeActions(child)
+		}
+	case *parse.ActionNode:
+		if len(node.Pipe.Decl) > 0 {
+			return
+		}
+
+		node.Pipe.Cmds = append(node.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      node.Pos,
+			Args:     []parse.Node{parse.NewIdentifier("__glamEscape").SetPos(node.Pos)},
+		})
+	case *parse.IfNode:
+		escapeActions(node.List)
+		escapeActions(node.ElseList)
+	case *parse.RangeNode:
+		escapeActions(node.List)
+		escapeActions(node.ElseList)
+	case *parse.WithNode:
+		escapeActions(node.List)
+		escapeActions(node.ElseList)
+	}
+}
@@ -0,0 +1,76 @@
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// AttributeTransformer transforms the value of an attribute before it's
+// assigned to a component. field is the name of the struct field the
+// attribute is assigned to, or the attribute's name when it isn't assigned to
+// a field, like forwarded attributes and attributes of function components.
+type AttributeTransformer func(component string, field string, value any) (any, error)
+
+// attributeFieldCache maps component types to the names of the fields their
+// attributes are assigned to, so they aren't resolved on every render
+var attributeFieldCache sync.Map
+
+// attributeFields returns the names of the fields of the given component
+// type, keyed by the lowercased attribute that's assigned to them.
+func attributeFields(componentType reflect.Type) map[string]string {
+	if fields, ok := attributeFieldCache.Load(componentType); ok {
+		return fields.(map[string]string)
+	}
+
+	fields := make(map[string]string)
+	structType := derefType(componentType)
+	if structType.Kind() == reflect.Struct {
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			if !field.IsExported() || field.Tag.Get("glam") == "attrs" {
+				continue
+			}
+
+			fields[strings.ToLower(AttributeName(field))] = field.Name
+		}
+	}
+
+	actual, _ := attributeFieldCache.LoadOrStore(componentType, fields)
+
+	return actual.(map[string]string)
+}
+
+// transformAttributes returns a copy of attributes with each value replaced
+// by the result of transform. Collected child components aren't attributes
+// written in the template, so they aren't transformed.
+func transformAttributes(transform AttributeTransformer, component string, componentType reflect.Type, attributes map[string]any) (map[string]any, error) {
+	// Function components aren't backed by a struct the renderer knows of
+	var fields map[string]string
+	if componentType != FuncComponentType {
+		fields = attributeFields(componentType)
+	}
+
+	transformed := make(map[string]any, len(attributes))
+	for name, value := range attributes {
+		if _, ok := value.([]*childComponent); ok {
+			transformed[name] = value
+			continue
+		}
+
+		field, ok := fields[strings.ToLower(name)]
+		if !ok {
+			field = name
+		}
+
+		value, err := transform(component, field, value)
+		if err != nil {
+			return nil, fmt.Errorf("could not transform attribute %s: %w", name, err)
+		}
+
+		transformed[name] = value
+	}
+
+	return transformed, nil
+}
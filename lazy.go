@@ -0,0 +1,128 @@
+package glam
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/blakewilliams/glam/internal/template"
+)
+
+// lazyTemplate is the template of a component registered using
+// RegisterComponentLazy, which is loaded and parsed the first time the
+// component is rendered.
+type lazyTemplate struct {
+	load func() (string, error)
+	// mu ensures the template is only loaded once when the component's first
+	// renders are concurrent
+	mu       sync.Mutex
+	template atomic.Pointer[template.Template]
+}
+
+// RegisterComponentLazy registers a component whose template is loaded using
+// load and parsed the first time the component is rendered, instead of when
+// it's registered, so engines with many components start faster. The parsed
+// template is cached, so load is called once. If load or parsing fails, the
+// render returns the error and the next render tries again.
+//
+// Lazy templates are parsed with the same options as other templates, using
+// the components registered when the component is first rendered. Registering
+// a component the parsed template references discards it, so it's parsed
+// again the next time the component is rendered.
+func (e *Engine) RegisterComponentLazy(value any, load func() (string, error)) error {
+	name, err := componentName(value)
+	if err != nil {
+		return err
+	}
+
+	err = e.templateOptions.CheckTagConflict(name)
+	if err != nil {
+		return fmt.Errorf("could not register template: %w", err)
+	}
+
+	e.setComponent(name, reflect.TypeOf(value))
+	e.resetRegistration(name)
+	delete(e.templateMap, name)
+	delete(e.warnings, name)
+	e.lazyTemplates[name] = &lazyTemplate{load: load}
+
+	// Templates parsed before the component was registered render its tag
+	// as raw HTML
+	err = e.recompileReferences(name)
+	if err != nil {
+		return err
+	}
+
+	return e.recompilePartials(name)
+}
+
+// componentTemplate returns the template of the component with the given
+// name, loading it first if it was registered using RegisterComponentLazy.
+func (e *Engine) componentTemplate(name string) (*template.Template, bool, error) {
+	if t, ok := e.templateMap[name]; ok {
+		return t, true, nil
+	}
+
+	lazy, ok := e.lazyTemplates[name]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if t := lazy.template.Load(); t != nil {
+		return t, true, nil
+	}
+
+	lazy.mu.Lock()
+	defer lazy.mu.Unlock()
+
+	if t := lazy.template.Load(); t != nil {
+		return t, true, nil
+	}
+
+	content, err := lazy.load()
+	if err != nil {
+		return nil, true, fmt.Errorf("could not load template of component %s: %w", name, err)
+	}
+
+	t, err := template.NewWithOptions(name, e, content, e.componentOptions(name))
+	if err != nil {
+		return nil, true, err
+	}
+	lazy.template.Store(t)
+
+	// Warnings are computed when they're requested instead of recorded, since
+	// the template is parsed while rendering
+	if e.warningHandler != nil {
+		for _, warning := range e.typoWarnings(t) {
+			e.warningHandler(warning)
+		}
+	}
+
+	return t, true, nil
+}
+
+// recompileLazyReferences discards the parsed templates of lazily registered
+// components that potentially reference the component with the given name, so
+// they're parsed again using it the next time they're rendered.
+func (e *Engine) recompileLazyReferences(name string) error {
+	names := make([]string, 0)
+	for lazyName, lazy := range e.lazyTemplates {
+		if t := lazy.template.Load(); t != nil && t.ComponentsPotentiallyReferenced()[name] {
+			names = append(names, lazyName)
+		}
+	}
+	slices.Sort(names)
+
+	for _, lazyName := range names {
+		err := e.recompiling(lazyName, name)
+		if err != nil {
+			return err
+		}
+
+		e.lazyTemplates[lazyName].template.Store(nil)
+	}
+
+	return nil
+}
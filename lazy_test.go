@@ -0,0 +1,176 @@
+package glam
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type LazyCard struct {
+	Title string
+}
+
+type LazyPage struct {
+	Title string
+}
+
+func TestRegisterComponentLazy(t *testing.T) {
+	var loads atomic.Int32
+	engine := New()
+	err := engine.RegisterComponent(&LazyPage{}, `<main><LazyCard title="{{.Title}}" /></main>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponentLazy(&LazyCard{}, func() (string, error) {
+		loads.Add(1)
+		return `<h2>{{.Title}}</h2>`, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(0), loads.Load())
+
+	const goroutines = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var b strings.Builder
+			err := engine.Render(&b, &LazyPage{Title: "Hi"})
+			if err == nil && b.String() != "<main><h2>Hi</h2></main>" {
+				err = fmt.Errorf("unexpected output %q", b.String())
+			}
+			if err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), loads.Load())
+
+	var b strings.Builder
+	err = engine.RenderByName(&b, "LazyCard", &LazyCard{Title: "Direct"})
+	require.NoError(t, err)
+	require.Equal(t, "<h2>Direct</h2>", b.String())
+	require.Equal(t, int32(1), loads.Load())
+
+	// Clones render components using their own templates
+	clone, err := engine.Clone()
+	require.NoError(t, err)
+	b.Reset()
+	err = clone.Render(&b, &LazyCard{Title: "Clone"})
+	require.NoError(t, err)
+	require.Equal(t, "<h2>Clone</h2>", b.String())
+	require.Equal(t, int32(2), loads.Load())
+}
+
+func TestRegisterComponentLazy_Errors(t *testing.T) {
+	fail := true
+	engine := New()
+	err := engine.RegisterComponentLazy(&LazyCard{}, func() (string, error) {
+		if fail {
+			return "", errors.New("file not found")
+		}
+
+		return `<h2>{{.Title}}</h2>`, nil
+	})
+	require.NoError(t, err)
+	err = engine.RegisterComponent(&LazyPage{}, `<LazyCard title="{{.Title}}" />`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = engine.Render(&b, &LazyPage{Title: "Hi"})
+	require.ErrorContains(t, err, "error rendering component LazyCard: could not load template of component LazyCard: file not found")
+
+	// Failed loads are tried again
+	fail = false
+	b.Reset()
+	err = engine.Render(&b, &LazyPage{Title: "Hi"})
+	require.NoError(t, err)
+	require.Equal(t, "<h2>Hi</h2>", b.String())
+
+	err = engine.RegisterComponentLazy(&LazyPage{}, func() (string, error) {
+		return `<LazyCard>`, nil
+	})
+	require.NoError(t, err)
+	err = engine.Render(&b, &LazyPage{})
+	require.ErrorContains(t, err, "could not parse template LazyPage: error parsing children: 1:11: unclosed component tag LazyCard")
+}
+
+func TestRegisterComponentLazy_Recompile(t *testing.T) {
+	var loads atomic.Int32
+	load := func() (string, error) {
+		loads.Add(1)
+		return `<main><LazyCard title="{{.Title}}"></LazyCard></main>`, nil
+	}
+
+	engine := New()
+	err := engine.RegisterComponentLazy(&LazyPage{}, load)
+	require.NoError(t, err)
+
+	// LazyCard isn't registered yet, so it's rendered as raw HTML
+	var b strings.Builder
+	err = engine.Render(&b, &LazyPage{Title: "Hi"})
+	require.NoError(t, err)
+	require.Equal(t, `<main><LazyCard title="Hi"></LazyCard></main>`, b.String())
+
+	err = engine.RegisterComponent(&LazyCard{}, `<h2>{{.Title}}</h2>`)
+	require.NoError(t, err)
+
+	b.Reset()
+	err = engine.Render(&b, &LazyPage{Title: "Hi"})
+	require.NoError(t, err)
+	require.Equal(t, "<main><h2>Hi</h2></main>", b.String())
+	require.Equal(t, int32(2), loads.Load())
+
+	// Discarding lazy templates counts toward the recompile limit
+	engine = New(WithRecompileLimit(1))
+	err = engine.RegisterComponent(&TestFSComponent{}, `<LazyCard title="{{.Value}}"></LazyCard>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponentLazy(&LazyPage{}, load)
+	require.NoError(t, err)
+	err = engine.Render(&b, &LazyPage{Title: "Hi"})
+	require.NoError(t, err)
+
+	err = engine.RegisterComponent(&LazyCard{}, `<h2>{{.Title}}</h2>`)
+	require.ErrorContains(t, err, "could not recompile LazyPage after registering LazyCard: exceeded the limit of 1 recompiles")
+}
+
+func TestRegisterComponentLazy_Warnings(t *testing.T) {
+	var handled []Warning
+	engine := New(WithWarningHandler(func(w Warning) {
+		handled = append(handled, w)
+	}))
+	err := engine.RegisterComponent(&LazyCard{}, `<h2>{{.Title}}</h2>`)
+	require.NoError(t, err)
+	err = engine.RegisterComponentLazy(&LazyPage{}, func() (string, error) {
+		return `<main><LazyCrad></LazyCrad></main>`, nil
+	})
+	require.NoError(t, err)
+
+	// Lazy templates aren't checked until they're parsed
+	require.Empty(t, engine.Warnings())
+
+	var b strings.Builder
+	err = engine.Render(&b, &LazyPage{})
+	require.NoError(t, err)
+
+	expected := Warning{
+		Component: "LazyPage",
+		Line:      1,
+		Column:    7,
+		Message:   "<LazyCrad> isn't a registered component, did you mean LazyCard?",
+	}
+	require.Equal(t, []Warning{expected}, engine.Warnings())
+	require.Equal(t, []Warning{expected}, handled)
+}
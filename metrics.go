@@ -0,0 +1,157 @@
+package glam
+
+import (
+	"expvar"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/blakewilliams/glam/internal/template"
+)
+
+// MetricsSink receives the duration of each component render, including
+// nested components, e.g. to record them using Prometheus. The duration of a
+// component includes the components it renders. ObserveRender must be safe
+// to call concurrently.
+type MetricsSink interface {
+	ObserveRender(component string, d time.Duration, err bool)
+}
+
+// RecompileObserver can be implemented by a MetricsSink to observe templates
+// being recompiled because the component they render, registered, was
+// registered after them. See WithRecompileLimit.
+type RecompileObserver interface {
+	ObserveRecompile(component string, registered string)
+}
+
+// WithMetrics reports the duration of every component render to the sink,
+// including the components rendered by other components. See ExpvarMetrics
+// for a sink that publishes metrics using expvar.
+func WithMetrics(sink MetricsSink) Option {
+	return optionFunc(func(e *Engine) {
+		e.metrics = sink
+	})
+}
+
+// executeTemplate executes the template, reporting the duration of the
+// render to the metrics sink, if any.
+func (e *Engine) executeTemplate(w io.Writer, t *template.Template, data any, funcMap map[string]any) error {
+	if e.metrics == nil {
+		return t.Execute(w, data, funcMap)
+	}
+
+	start := time.Now()
+	err := t.Execute(w, data, funcMap)
+	e.metrics.ObserveRender(t.Name, time.Since(start), err != nil)
+
+	return err
+}
+
+// renderBuckets are the upper bounds of the render duration histogram
+// recorded by ExpvarMetrics
+var renderBuckets = []struct {
+	name  string
+	bound time.Duration
+}{
+	{"100us", 100 * time.Microsecond},
+	{"1ms", time.Millisecond},
+	{"10ms", 10 * time.Millisecond},
+	{"100ms", 100 * time.Millisecond},
+	{"1s", time.Second},
+	{"+Inf", 1<<63 - 1},
+}
+
+// ExpvarMetrics is a MetricsSink that records the number of renders, errors,
+// total duration, and a histogram of durations for each component, along with
+// the number of times its template was recompiled. It
+// implements expvar.Var, so it can be published using expvar.Publish:
+//
+//	metrics := glam.NewExpvarMetrics()
+//	expvar.Publish("glam", metrics)
+//	engine := glam.New(glam.WithMetrics(metrics))
+//
+// Metrics are keyed by component name, e.g.
+// `{"Button": {"renders": 2, "errors": 0, "duration_ns": 1200, "recompiles": 0, "histogram": {"100us": 2, ...}}}`.
+// Histogram buckets are cumulative, so each bucket counts the renders that
+// took at most its duration.
+type ExpvarMetrics struct {
+	components expvar.Map
+	// mu guards adding new components, so their metrics are only
+	// initialized once
+	mu sync.Mutex
+}
+
+var (
+	_ MetricsSink       = (*ExpvarMetrics)(nil)
+	_ RecompileObserver = (*ExpvarMetrics)(nil)
+)
+
+// NewExpvarMetrics returns an ExpvarMetrics without any recorded renders.
+func NewExpvarMetrics() *ExpvarMetrics {
+	return &ExpvarMetrics{}
+}
+
+func (m *ExpvarMetrics) ObserveRender(component string, d time.Duration, err bool) {
+	metrics := m.component(component)
+
+	metrics.Add("renders", 1)
+	if err {
+		metrics.Add("errors", 1)
+	}
+	metrics.Add("duration_ns", int64(d))
+
+	histogram := metrics.Get("histogram").(*expvar.Map)
+	for _, bucket := range renderBuckets {
+		if d <= bucket.bound {
+			histogram.Add(bucket.name, 1)
+		}
+	}
+}
+
+func (m *ExpvarMetrics) ObserveRecompile(component string, _ string) {
+	m.component(component).Add("recompiles", 1)
+}
+
+// component returns the metrics of the given component, initializing them
+// the first time it's rendered.
+func (m *ExpvarMetrics) component(name string) *expvar.Map {
+	if metrics, ok := m.components.Get(name).(*expvar.Map); ok {
+		return metrics
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if metrics, ok := m.components.Get(name).(*expvar.Map); ok {
+		return metrics
+	}
+
+	metrics := new(expvar.Map)
+	metrics.Add("renders", 0)
+	metrics.Add("errors", 0)
+	metrics.Add("duration_ns", 0)
+	metrics.Add("recompiles", 0)
+
+	histogram := new(expvar.Map)
+	for _, bucket := range renderBuckets {
+		histogram.Add(bucket.name, 0)
+	}
+	metrics.Set("histogram", histogram)
+
+	m.components.Set(name, metrics)
+
+	return metrics
+}
+
+// Get returns the metrics of the given component, or nil if it hasn't been
+// rendered.
+func (m *ExpvarMetrics) Get(component string) *expvar.Map {
+	metrics, _ := m.components.Get(component).(*expvar.Map)
+
+	return metrics
+}
+
+// String returns the metrics as JSON, implementing expvar.Var.
+func (m *ExpvarMetrics) String() string {
+	return m.components.String()
+}
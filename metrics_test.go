@@ -0,0 +1,154 @@
+package glam
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	mu      sync.Mutex
+	renders []string
+}
+
+func (s *recordingSink) ObserveRender(component string, d time.Duration, err bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err {
+		component += " (error)"
+	}
+	s.renders = append(s.renders, component)
+}
+
+type MetricsPage struct {
+	Fail bool
+}
+
+type MetricsCard struct {
+	Fail bool
+}
+
+func (c MetricsCard) Title() (string, error) {
+	if c.Fail {
+		return "", errors.New("no title")
+	}
+
+	return "Title", nil
+}
+
+func newMetricsEngine(t testing.TB, sink MetricsSink) *Engine {
+	engine := New(WithMetrics(sink))
+	require.NoError(t, engine.RegisterComponent(&MetricsCard{}, `<h2>{{.Title}}</h2>`))
+	require.NoError(t, engine.RegisterFunc("MetricsIcon", func(_ map[string]any, _ template.HTML) (template.HTML, error) {
+		return "<i></i>", nil
+	}))
+	require.NoError(t, engine.RegisterComponent(&MetricsPage{}, `<MetricsIcon /><MetricsCard fail="{{.Fail}}" />`))
+
+	return engine
+}
+
+func TestWithMetrics(t *testing.T) {
+	sink := &recordingSink{}
+	engine := newMetricsEngine(t, sink)
+
+	var b bytes.Buffer
+	err := engine.Render(&b, &MetricsPage{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"MetricsIcon", "MetricsCard", "MetricsPage"}, sink.renders)
+
+	sink.renders = nil
+	err = engine.Render(&b, &MetricsPage{Fail: true})
+	require.Error(t, err)
+	require.Equal(t, []string{"MetricsIcon", "MetricsCard (error)", "MetricsPage (error)"}, sink.renders)
+
+	sink.renders = nil
+	err = engine.RenderByName(&b, "MetricsCard", &MetricsCard{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"MetricsCard"}, sink.renders)
+}
+
+func TestExpvarMetrics(t *testing.T) {
+	metrics := NewExpvarMetrics()
+	metrics.ObserveRender("Button", 50*time.Microsecond, false)
+	metrics.ObserveRender("Button", 5*time.Millisecond, true)
+	metrics.ObserveRender("Card", 2*time.Second, false)
+
+	var values map[string]struct {
+		Renders    int64            `json:"renders"`
+		Errors     int64            `json:"errors"`
+		DurationNS int64            `json:"duration_ns"`
+		Histogram  map[string]int64 `json:"histogram"`
+	}
+	err := json.Unmarshal([]byte(metrics.String()), &values)
+	require.NoError(t, err)
+
+	button := values["Button"]
+	require.Equal(t, int64(2), button.Renders)
+	require.Equal(t, int64(1), button.Errors)
+	require.Equal(t, int64(5050*time.Microsecond), button.DurationNS)
+	require.Equal(t, map[string]int64{"100us": 1, "1ms": 1, "10ms": 2, "100ms": 2, "1s": 2, "+Inf": 2}, button.Histogram)
+
+	card := values["Card"]
+	require.Equal(t, map[string]int64{"100us": 0, "1ms": 0, "10ms": 0, "100ms": 0, "1s": 0, "+Inf": 1}, card.Histogram)
+
+	require.Equal(t, "2", metrics.Get("Button").Get("renders").String())
+	require.Nil(t, metrics.Get("Unknown"))
+}
+
+func TestExpvarMetrics_Concurrent(t *testing.T) {
+	metrics := NewExpvarMetrics()
+	engine := newMetricsEngine(t, metrics)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 10; j++ {
+				var b bytes.Buffer
+				_ = engine.Render(&b, &MetricsPage{})
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, "100", metrics.Get("MetricsPage").Get("renders").String())
+	require.Equal(t, "100", metrics.Get("MetricsCard").Get("renders").String())
+}
+
+// BenchmarkMetrics compares the overhead of rendering with and without a
+// metrics sink.
+func BenchmarkMetrics(b *testing.B) {
+	table := benchmarkTable()
+
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"disabled", nil},
+		{"enabled", []Option{WithMetrics(NewExpvarMetrics())}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			engine := New(bc.opts...)
+			require.NoError(b, engine.RegisterComponent(&WrapperComponent{}, wrapperTemplate))
+			require.NoError(b, engine.RegisterComponent(&BenchmarkTable{}, `<WrapperComponent name="Users" /><table>{{range .Rows}}<tr><td>{{.Name}}</td></tr>{{end}}</table>`))
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var buf bytes.Buffer
+				err := engine.Render(&buf, table)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
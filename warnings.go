@@ -0,0 +1,199 @@
+package glam
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/blakewilliams/glam/internal/template"
+)
+
+// Warning describes a likely mistake in a component's template that doesn't
+// prevent it from being registered, like a tag that's probably a typo of a
+// registered component's name.
+type Warning struct {
+	// Component is the name of the component whose template contains the
+	// mistake
+	Component string
+	// Filename is the file the component's template was read from, if any
+	Filename string
+	// Line and Column are the position of the mistake in the template,
+	// starting at 1. They're 0 for mistakes found while rendering.
+	Line   int
+	Column int
+	// Message describes the mistake
+	Message string
+}
+
+func (w Warning) String() string {
+	source := w.Component
+	if w.Filename != "" {
+		source = w.Filename
+	}
+
+	// Warnings found while rendering don't have a position
+	if w.Line == 0 {
+		return fmt.Sprintf("%s: %s", source, w.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", source, w.Line, w.Column, w.Message)
+}
+
+// maxTypoDistance is the largest edit distance between an unregistered tag and
+// a registered component's name for the tag to be considered a typo
+const maxTypoDistance = 2
+
+// Warnings returns the warnings for the templates of the registered
+// components, sorted by component name and position. Components registered
+// using RegisterComponentLazy only have warnings once they've been rendered.
+func (e *Engine) Warnings() []Warning {
+	warnings := make([]Warning, 0)
+	for _, componentWarnings := range e.warnings {
+		warnings = append(warnings, componentWarnings...)
+	}
+	for _, lazy := range e.lazyTemplates {
+		if t := lazy.template.Load(); t != nil {
+			warnings = append(warnings, e.typoWarnings(t)...)
+		}
+	}
+
+	sort.Slice(warnings, func(i, j int) bool {
+		if warnings[i].Component != warnings[j].Component {
+			return warnings[i].Component < warnings[j].Component
+		}
+		if warnings[i].Line != warnings[j].Line {
+			return warnings[i].Line < warnings[j].Line
+		}
+
+		return warnings[i].Column < warnings[j].Column
+	})
+
+	return warnings
+}
+
+// checkTypos records warnings for tags in the given template that aren't
+// registered, but are close to the name of a registered component, e.g.
+// `<WraperComponent>` when WrapperComponent is registered. Warnings that
+// weren't previously recorded for the template are passed to the warning
+// handler.
+func (e *Engine) checkTypos(t *template.Template) {
+	warnings := e.typoWarnings(t)
+
+	previous := e.warnings[t.Name]
+	if len(warnings) == 0 {
+		delete(e.warnings, t.Name)
+	} else {
+		e.warnings[t.Name] = warnings
+	}
+
+	if e.warningHandler == nil {
+		return
+	}
+
+	for _, warning := range warnings {
+		if !slices.Contains(previous, warning) {
+			e.warningHandler(warning)
+		}
+	}
+}
+
+// typoWarnings returns warnings for tags in the given template that aren't
+// registered, but are close to the name of a registered component.
+func (e *Engine) typoWarnings(t *template.Template) []Warning {
+	warnings := make([]Warning, 0)
+	for _, reference := range t.PotentialReferences() {
+		if _, ok := e.components[reference.Name]; ok {
+			continue
+		}
+
+		suggestion, ok := e.closestComponent(reference.Name)
+		if !ok {
+			continue
+		}
+
+		warnings = append(warnings, Warning{
+			Component: t.Name,
+			Filename:  e.filenames[t.Name],
+			Line:      reference.Line,
+			Column:    reference.Column,
+			Message:   fmt.Sprintf("<%s> isn't a registered component, did you mean %s?", reference.Name, suggestion),
+		})
+	}
+
+	return warnings
+}
+
+// checkPendingTypos checks the templates that reference unregistered tags
+// close to the given component's name, since they may have been registered
+// before the component was.
+func (e *Engine) checkPendingTypos(name string) {
+	// Check the references in order so warnings are reported
+	// deterministically
+	references := make([]string, 0, len(e.recompileMap))
+	for reference := range e.recompileMap {
+		references = append(references, reference)
+	}
+	slices.Sort(references)
+
+	checked := make(map[*template.Template]bool)
+	for _, reference := range references {
+		templates := e.recompileMap[reference]
+		distance := editDistance(reference, name)
+		if distance == 0 || distance > maxTypoDistance {
+			continue
+		}
+
+		for _, t := range templates {
+			// Templates in recompileMap may have been replaced since
+			if e.templateMap[t.Name] != t || checked[t] {
+				continue
+			}
+
+			checked[t] = true
+			e.checkTypos(t)
+		}
+	}
+}
+
+// closestComponent returns the registered component with the name closest to
+// the given name, if it's close enough to be a typo.
+func (e *Engine) closestComponent(name string) (string, bool) {
+	closest := ""
+	closestDistance := maxTypoDistance + 1
+	for _, component := range e.componentNames {
+		distance := editDistance(name, component)
+		if distance < closestDistance || (distance == closestDistance && component < closest) {
+			closest = component
+			closestDistance = distance
+		}
+	}
+
+	return closest, closestDistance <= maxTypoDistance
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(br)]
+}
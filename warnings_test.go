@@ -0,0 +1,149 @@
+package glam
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type TypoWrapper struct {
+	Children template.HTML
+}
+
+type TypoPage struct{}
+
+func TestTypoWarnings(t *testing.T) {
+	var reported []Warning
+	engine := New(WithWarningHandler(func(w Warning) {
+		reported = append(reported, w)
+	}))
+
+	err := engine.RegisterComponent(&TypoWrapper{}, `<div>{{.Children}}</div>`)
+	require.NoError(t, err)
+
+	err = engine.RegisterComponent(&TypoPage{}, "<h1>Title</h1>\n  <TypoWraper>Hi</TypoWraper><UnknownThing></UnknownThing>")
+	require.NoError(t, err)
+
+	expected := []Warning{{
+		Component: "TypoPage",
+		Line:      2,
+		Column:    3,
+		Message:   "<TypoWraper> isn't a registered component, did you mean TypoWrapper?",
+	}}
+	require.Equal(t, expected, engine.Warnings())
+	require.Equal(t, expected, reported)
+	require.Equal(t, "TypoPage:2:3: <TypoWraper> isn't a registered component, did you mean TypoWrapper?", expected[0].String())
+
+	// Recompiling the template doesn't report the warning again
+	err = engine.RegisterComponent(&TypoWrapper{}, `<section>{{.Children}}</section>`)
+	require.NoError(t, err)
+	require.Len(t, reported, 1)
+
+	// Fixing the typo removes the warning
+	err = engine.RegisterComponent(&TypoPage{}, `<TypoWrapper>Hi</TypoWrapper>`)
+	require.NoError(t, err)
+	require.Empty(t, engine.Warnings())
+}
+
+func TestTypoWarnings_ComponentRegisteredLater(t *testing.T) {
+	var reported []Warning
+	engine := New(WithWarningHandler(func(w Warning) {
+		reported = append(reported, w)
+	}))
+
+	err := engine.registerComponent(&TypoPage{}, `<TypoWraper>Hi</TypoWraper>`, "page.glam.html")
+	require.NoError(t, err)
+	require.Empty(t, reported)
+
+	err = engine.RegisterComponent(&TypoWrapper{}, `<div>{{.Children}}</div>`)
+	require.NoError(t, err)
+	require.Equal(t, []Warning{{
+		Component: "TypoPage",
+		Filename:  "page.glam.html",
+		Line:      1,
+		Column:    1,
+		Message:   "<TypoWraper> isn't a registered component, did you mean TypoWrapper?",
+	}}, reported)
+	require.Equal(t, "page.glam.html:1:1: <TypoWraper> isn't a registered component, did you mean TypoWrapper?", reported[0].String())
+
+	// Registering the referenced component removes the warning
+	err = engine.RegisterFunc("TypoWraper", func(_ map[string]any, children template.HTML) (template.HTML, error) {
+		return children, nil
+	})
+	require.NoError(t, err)
+	require.Empty(t, engine.Warnings())
+}
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"Button", "Button", 0},
+		{"Buton", "Button", 1},
+		{"Btuton", "Button", 2},
+		{"Card", "Button", 6},
+		{"", "Tab", 3},
+		{"Äbc", "Abc", 1},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.distance, editDistance(tc.a, tc.b), "%s -> %s", tc.a, tc.b)
+		require.Equal(t, tc.distance, editDistance(tc.b, tc.a), "%s -> %s", tc.b, tc.a)
+	}
+}
+
+type OrderCard struct {
+	Children template.HTML
+}
+
+type OrderFirstPage struct{}
+
+type OrderSecondPage struct{}
+
+type OrderThirdPage struct{}
+
+func TestRegistrationOrderIsDeterministic(t *testing.T) {
+	register := func() ([]Warning, []byte, string) {
+		var reported []Warning
+		engine := New(WithWarningHandler(func(w Warning) {
+			reported = append(reported, w)
+		}))
+
+		err := engine.RegisterComponent(&OrderFirstPage{}, `<OrderCardd>1</OrderCardd>`)
+		require.NoError(t, err)
+		err = engine.RegisterComponent(&OrderSecondPage{}, `<OrderCar>2</OrderCar>`)
+		require.NoError(t, err)
+		err = engine.RegisterComponent(&OrderThirdPage{}, `<OrderCrad>3</OrderCrad><OrderFirstPage></OrderFirstPage><OrderCard>3</OrderCard>`)
+		require.NoError(t, err)
+		err = engine.RegisterComponent(&OrderCard{}, `<p>{{.Children}}</p>`)
+		require.NoError(t, err)
+
+		manifest, err := engine.Manifest()
+		require.NoError(t, err)
+
+		var b strings.Builder
+		err = engine.Render(&b, &OrderThirdPage{})
+		require.NoError(t, err)
+
+		return reported, manifest, b.String()
+	}
+
+	warnings, manifest, output := register()
+	components := make([]string, 0, len(warnings))
+	for _, warning := range warnings {
+		components = append(components, warning.Component)
+	}
+	require.Equal(t, []string{"OrderThirdPage", "OrderSecondPage", "OrderFirstPage"}, components)
+	require.Equal(t, `<OrderCrad>3</OrderCrad><OrderCardd>1</OrderCardd><p>3</p>`, output)
+
+	for i := 0; i < 20; i++ {
+		repeatedWarnings, repeatedManifest, repeatedOutput := register()
+		require.Equal(t, warnings, repeatedWarnings)
+		require.Equal(t, manifest, repeatedManifest)
+		require.Equal(t, output, repeatedOutput)
+	}
+}